@@ -0,0 +1,107 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ezgliding/goigc/pkg/airspace"
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func init() {
+	airspaceCmd.Flags().String("airspace", "", "airspace file in OpenAir format")
+	airspaceCmd.Flags().String("altitude", "auto", "altitude compared with MSL and AGL limits (gnss, pressure, qnh, auto)")
+	airspaceCmd.Flags().Float64("qnh", 0, "qnh in hPa for qnh altitudes - header or estimated from gnss by default")
+	airspaceCmd.Flags().Float64("ground-elevation", 0, "terrain elevation in meters for AGL and SFC limits")
+	airspaceCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	airspaceCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	airspaceCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv)")
+	airspaceCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	airspaceCmd.MarkFlagRequired("airspace")
+	rootCmd.AddCommand(airspaceCmd)
+}
+
+var airspaceCmd = &cobra.Command{
+	Use:   "airspace FILE",
+	Short: "reports airspace infringements for the given flight",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+		location, err := cmd.Flags().GetString("airspace")
+		if err != nil {
+			return err
+		}
+		opts, err := airspaceOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		airspaces, err := airspace.ParseLocation(location)
+		if err != nil {
+			return fmt.Errorf("%v :: %v", location, err)
+		}
+		trk, err := parseLocation(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		infringements, err := airspace.Infringements(&trk, airspaces, opts)
+		if err != nil {
+			return err
+		}
+		result, err := airspace.EncodeInfringements(infringements, outputFormat)
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func airspaceOptions(cmd *cobra.Command) (airspace.Options, error) {
+	var opts airspace.Options
+	altitude, err := cmd.Flags().GetString("altitude")
+	if err != nil {
+		return opts, err
+	}
+	if opts.Altitude, err = igc.ParseAltitudeSource(altitude); err != nil {
+		return opts, err
+	}
+	if opts.QNH, err = cmd.Flags().GetFloat64("qnh"); err != nil {
+		return opts, err
+	}
+	opts.GroundElevation, err = cmd.Flags().GetFloat64("ground-elevation")
+	return opts, err
+}
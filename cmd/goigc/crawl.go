@@ -16,20 +16,30 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/ezgliding/goigc/pkg/igc"
-	"github.com/ezgliding/goigc/pkg/netcoupe"
+	"github.com/ezgliding/goigc/pkg/crawl"
+	// sources registered in the crawl package
+	_ "github.com/ezgliding/goigc/pkg/jsonapi"
+	_ "github.com/ezgliding/goigc/pkg/netcoupe"
 )
 
 func init() {
-	crawlCmd.Flags().String("source", "netcoupe", "online web source to crawl")
+	defaults := crawl.DefaultOptions()
+	crawlCmd.Flags().String("source", "netcoupe",
+		fmt.Sprintf("online web source to crawl (%v)", strings.Join(crawl.Sources(), ", ")))
+	crawlCmd.Flags().String("base-url", "", "base url of the source, required for jsonapi")
+	crawlCmd.Flags().Int("workers", defaults.Workers, "number of concurrent requests")
+	crawlCmd.Flags().Float64("rate", defaults.Rate, "max http requests per second to the source, shared by all workers, 0 for no limit")
+	crawlCmd.Flags().Int("burst", defaults.Burst, "max burst of http requests above the rate")
+	crawlCmd.Flags().Int("retries", defaults.Backoff.Attempts-1, "max retries of failed requests")
+	crawlCmd.Flags().Duration("backoff", defaults.Backoff.Initial, "initial delay between retries, doubling on each one")
+	crawlCmd.Flags().String("record", "", "directory to save all responses as test fixtures (netcoupe only)")
+	crawlCmd.Flags().Bool("resume", false, "retry only failed or missing downloads of the days already listed")
 	rootCmd.AddCommand(crawlCmd)
 }
 
@@ -44,8 +54,16 @@ Results are stored under PATH with the following structure.
 
 PATH/YEAR
   /DD-MM-YYYY.json ( one json file per day with flight metadata )
+  /manifest.json ( download status, attempts and checksum per track )
   /flights
     /TRACKID ( one file with the flight track in the original format )
+
+Days with an existing day file are not crawled again, remove the file to list
+the day again. They are skipped unless --resume is given, which retries only
+their failed or missing downloads.
+
+With --record DIR all responses are also saved under DIR, to be used as test
+fixtures when the layout of the source changes.
 `,
 	Args: cobra.ExactArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -61,56 +79,53 @@ PATH/YEAR
 		if start.Year() != end.Year() {
 			return fmt.Errorf("Start and end year must be the same")
 		}
-		basePath := args[2]
-		err = os.MkdirAll(fmt.Sprintf("%v/%v/flights", basePath, start.Year()), os.ModePerm)
+		opts, err := crawlOptions(cmd)
 		if err != nil {
 			return err
 		}
-
-		var n netcoupe.Netcoupe = netcoupe.NewNetcoupeYear(start.Year())
-		current := start
-		for ; end.After(current.AddDate(0, 0, -1)); current = current.AddDate(0, 0, 1) {
-			var flights []igc.Flight
-			dbFile := fmt.Sprintf("%v/%v/%v.json", basePath, current.Year(), current.Format("02-01-2006"))
-			if _, err := os.Stat(dbFile); os.IsNotExist(err) {
-				flights, err = n.Crawl(current, current)
-				if err != nil {
-					return err
-				}
-				jsonFlights, err := json.MarshalIndent(flights, "", "   ")
-				if err != nil {
-					return err
-				}
-				err = ioutil.WriteFile(dbFile, jsonFlights, 0644)
-				if err != nil {
-					return err
-				}
-			} else {
-				b, err := ioutil.ReadFile(dbFile)
-				if err != nil {
-					return err
-				}
-				err = json.Unmarshal(b, &flights)
-				if err != nil {
-					return err
-				}
-			}
-
-			for _, f := range flights {
-				flightFile := fmt.Sprintf("%v/%v/flights/%v", basePath, current.Year(), f.TrackID)
-				if _, err := os.Stat(flightFile); os.IsNotExist(err) {
-					url := fmt.Sprintf("%v%v", n.TrackBaseUrl(), f.TrackID)
-					data, err := n.Get(url)
-					if err != nil {
-						return err
-					}
-					err = ioutil.WriteFile(flightFile, data, 0644)
-					if err != nil {
-						return err
-					}
-				}
-			}
+		name, err := cmd.Flags().GetString("source")
+		if err != nil {
+			return err
+		}
+		baseURL, err := cmd.Flags().GetString("base-url")
+		if err != nil {
+			return err
 		}
-		return nil
+		record, err := cmd.Flags().GetString("record")
+		if err != nil {
+			return err
+		}
+		source, err := crawl.NewSource(name, crawl.SourceOptions{Year: start.Year(),
+			BaseURL: baseURL, RecordDir: record, Limiter: opts.Limiter})
+		if err != nil {
+			return err
+		}
+		return crawl.Run(source, start, end, args[2], opts)
 	},
 }
+
+func crawlOptions(cmd *cobra.Command) (crawl.Options, error) {
+	opts := crawl.DefaultOptions()
+	flags := cmd.Flags()
+	var err error
+	if opts.Workers, err = flags.GetInt("workers"); err != nil {
+		return opts, err
+	}
+	if opts.Rate, err = flags.GetFloat64("rate"); err != nil {
+		return opts, err
+	}
+	if opts.Burst, err = flags.GetInt("burst"); err != nil {
+		return opts, err
+	}
+	opts.Limiter = crawl.NewTokenBucket(opts.Rate, opts.Burst)
+	retries, err := flags.GetInt("retries")
+	if err != nil {
+		return opts, err
+	}
+	opts.Backoff.Attempts = retries + 1
+	if opts.Backoff.Initial, err = flags.GetDuration("backoff"); err != nil {
+		return opts, err
+	}
+	opts.Resume, err = flags.GetBool("resume")
+	return opts, err
+}
@@ -0,0 +1,178 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+	"github.com/ezgliding/goigc/pkg/scoring"
+)
+
+var optimizers = map[string]func() igc.Optimizer{
+	"bruteforce":   func() igc.Optimizer { return igc.NewBruteForceOptimizer(false) },
+	"dp":           igc.NewDPOptimizer,
+	"fai-triangle": igc.NewTriangleOptimizer,
+	"flat-triangle": func() igc.Optimizer {
+		return igc.NewTriangleOptimizerWithOptions(igc.TriangleOptions{MaxGapPercent: 20})
+	},
+}
+
+// closedOptimizers return closed courses, measured by igc.Triangle.
+var closedOptimizers = map[string]bool{"fai-triangle": true, "flat-triangle": true}
+
+func init() {
+	optimizeCmd.Flags().String("algorithm", "dp",
+		fmt.Sprintf("optimizer algorithm, one of %v", strings.Join(keys(optimizers), ", ")))
+	optimizeCmd.Flags().Int("turnpoints", 3, "number of turnpoints")
+	optimizeCmd.Flags().String("score", "distance",
+		fmt.Sprintf("score function, one of distance, triangle, %v", strings.Join(scoring.Names(), ", ")))
+	optimizeCmd.Flags().Float64("tolerance", 0.0001, "track simplification tolerance, 0 to disable")
+	optimizeCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	optimizeCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	optimizeCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv, kml, kmz)")
+	optimizeCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	rootCmd.AddCommand(optimizeCmd)
+}
+
+// optimizeResult is the yaml and json output of the optimize command.
+type optimizeResult struct {
+	Algorithm string
+	Score     string
+	Distance  float64
+	Points    float64
+	Task      igc.Task
+}
+
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize FILE",
+	Short: "computes the optimal task for the given flight",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+		algorithm, err := cmd.Flags().GetString("algorithm")
+		if err != nil {
+			return err
+		}
+		turnpoints, err := cmd.Flags().GetInt("turnpoints")
+		if err != nil {
+			return err
+		}
+		scoreName, err := cmd.Flags().GetString("score")
+		if err != nil {
+			return err
+		}
+		tolerance, err := cmd.Flags().GetFloat64("tolerance")
+		if err != nil {
+			return err
+		}
+
+		newOptimizer, ok := optimizers[algorithm]
+		if !ok {
+			return fmt.Errorf("unknown algorithm '%v', available :: %v", algorithm, keys(optimizers))
+		}
+		score, err := scoreFunc(scoreName)
+		if err != nil {
+			return err
+		}
+
+		trk, err := parseLocation(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		simplified := trk
+		if tolerance > 0 {
+			if simplified, err = trk.Simplify(tolerance); err != nil {
+				return err
+			}
+		}
+		task, err := newOptimizer().Optimize(simplified, turnpoints, score)
+		if err != nil {
+			return err
+		}
+
+		var result []byte
+		closed := closedOptimizers[algorithm]
+		distance := task.Distance()
+		if closed {
+			distance = igc.Triangle(task)
+		}
+		r := optimizeResult{Algorithm: algorithm, Score: scoreName,
+			Distance: distance, Points: score(task), Task: task}
+		switch outputFormat {
+		case "json":
+			result, err = json.MarshalIndent(r, "", "  ")
+		case "yaml":
+			result, err = yaml.Marshal(r)
+		default:
+			result, err = trk.EncodeTaskWithOptions(task, outputFormat,
+				igc.EncodeTaskOptions{Closed: closed})
+		}
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// scoreFunc returns the igc.Score with the given name, either one from the
+// igc package or the points for the scoring rules with that name.
+func scoreFunc(name string) (igc.Score, error) {
+	switch name {
+	case "distance":
+		return igc.Distance, nil
+	case "triangle":
+		return igc.Triangle, nil
+	}
+	rules, err := scoring.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown score '%v'", name)
+	}
+	return scoring.ScoreFunc(rules), nil
+}
+
+func keys(m map[string]func() igc.Optimizer) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
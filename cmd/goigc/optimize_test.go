@@ -0,0 +1,92 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func TestOptimizeCmdScore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "optimize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "result.yaml")
+
+	tests := []struct {
+		name       string
+		algorithm  string
+		score      string
+		turnpoints string
+		err        bool
+	}{
+		{name: "dp-distance", algorithm: "dp", score: "distance", turnpoints: "3"},
+		{name: "dp-triangle", algorithm: "dp", score: "triangle", turnpoints: "3", err: true},
+		{name: "dp-olc-fai", algorithm: "dp", score: "olc-fai", turnpoints: "3", err: true},
+		{name: "bruteforce-netcoupe", algorithm: "bruteforce", score: "netcoupe", turnpoints: "2"},
+		{name: "fai-triangle-triangle", algorithm: "fai-triangle", score: "triangle", turnpoints: "3"},
+	}
+
+	cmd := rootCmd
+	cmd.SetOutput(new(bytes.Buffer))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd.SetArgs([]string{"optimize", "../../testdata/optimize/optimize-short-flight-1.igc",
+				"--algorithm", tt.algorithm, "--score", tt.score, "--turnpoints", tt.turnpoints,
+				"--output-file", output})
+			err := cmd.Execute()
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), "score not supported") {
+					t.Errorf("expected unsupported score error got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result optimizeResult
+			if err := yaml.Unmarshal(b, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Points <= 0 {
+				t.Errorf("expected positive points got %v", result.Points)
+			}
+			distance := result.Task.Distance()
+			if closedOptimizers[tt.algorithm] {
+				distance = igc.Triangle(result.Task)
+			}
+			if math.Abs(result.Distance-distance) > 0.001 {
+				t.Errorf("expected distance %v got %v", distance, result.Distance)
+			}
+		})
+	}
+}
@@ -25,10 +25,10 @@ import (
 )
 
 func init() {
-	// TODO(rochaporto): not yet supported, only igc
-	parseCmd.Flags().String("format", "", "input file format - auto detection by default")
+	parseCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
 	parseCmd.Flags().Bool("no-points", false, "do not include individual points")
-	parseCmd.Flags().String("output-format", "yaml", "output format for display")
+	parseCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	parseCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv, points-csv, points-tsv, kml, kmz, igc, geojson, gpx)")
 	parseCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
 	rootCmd.AddCommand(parseCmd)
 }
@@ -48,7 +48,7 @@ var parseCmd = &cobra.Command{
 			return err
 		}
 
-		trk, err := igc.ParseLocation(args[0])
+		trk, err := parseLocation(cmd, args[0])
 		if err != nil {
 			return err
 		}
@@ -73,3 +73,26 @@ var parseCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// parseLocation parses the given location honoring the lenient and format
+// flags.
+//
+// In lenient mode invalid records are reported but do not fail the command.
+func parseLocation(cmd *cobra.Command, location string) (igc.Track, error) {
+	lenient, err := cmd.Flags().GetBool("lenient")
+	if err != nil {
+		return igc.Track{}, err
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return igc.Track{}, err
+	}
+	trk, err := igc.ParseLocationWithOptions(location, igc.ParseOptions{Lenient: lenient, Format: format})
+	if errs, ok := err.(igc.ParseErrors); ok && lenient {
+		for _, e := range errs {
+			fmt.Fprintf(cmd.ErrOrStderr(), "skipped invalid record :: %v\n", e)
+		}
+		return trk, nil
+	}
+	return trk, err
+}
@@ -19,13 +19,25 @@ import (
 	"fmt"
 	"io/ioutil"
 
-	"github.com/ezgliding/goigc/pkg/igc"
 	"github.com/spf13/cobra"
+
+	"github.com/ezgliding/goigc/pkg/igc"
 )
 
 func init() {
-	phasesCmd.Flags().String("output-format", "yaml", "output format for display")
+	phasesCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	phasesCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	phasesCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv, kml, kmz, igc, geojson, gpx)")
 	phasesCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	phasesCmd.Flags().String("preset", "glider",
+		"aircraft type setting the phase thresholds (glider, motorglider, hangglider, paraglider)")
+	phasesCmd.Flags().Float64("min-turn-rate", 0, "min turn rate in deg/s to consider circling, overrides the preset")
+	phasesCmd.Flags().Float64("max-turn-rate", 0, "max turn rate in deg/s considered valid, overrides the preset")
+	phasesCmd.Flags().Duration("min-circling-time", 0, "min time turning to switch to circling, overrides the preset")
+	phasesCmd.Flags().Duration("min-cruising-time", 0, "min time not turning to switch to cruising, overrides the preset")
+	phasesCmd.Flags().Int("smoothing-window", 0, "number of turn rates to average, overrides the preset")
+	phasesCmd.Flags().String("altitude", "gnss", "altitude used for phase stats (gnss, pressure, qnh, auto)")
+	phasesCmd.Flags().Float64("qnh", 0, "qnh in hPa for qnh altitudes - header or estimated from gnss by default")
 	rootCmd.AddCommand(phasesCmd)
 }
 
@@ -44,11 +56,15 @@ var phasesCmd = &cobra.Command{
 			return err
 		}
 
-		trk, err := igc.ParseLocation(args[0])
+		trk, err := parseLocation(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		opts, err := phaseOptions(cmd)
 		if err != nil {
 			return err
 		}
-		result, err := trk.EncodePhases(outputFormat)
+		result, err := trk.EncodePhasesWithOptions(outputFormat, opts)
 		if err != nil {
 			return err
 		}
@@ -64,3 +80,41 @@ var phasesCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// phaseOptions returns the options of the preset flag, with any other phase
+// flags overriding the preset values.
+func phaseOptions(cmd *cobra.Command) (igc.PhaseOptions, error) {
+	preset, err := cmd.Flags().GetString("preset")
+	if err != nil {
+		return igc.PhaseOptions{}, err
+	}
+	opts, err := igc.PhasePreset(preset)
+	if err != nil {
+		return opts, err
+	}
+	flags := cmd.Flags()
+	if flags.Changed("min-turn-rate") {
+		opts.MinTurnRate, _ = flags.GetFloat64("min-turn-rate")
+	}
+	if flags.Changed("max-turn-rate") {
+		opts.MaxTurnRate, _ = flags.GetFloat64("max-turn-rate")
+	}
+	if flags.Changed("min-circling-time") {
+		opts.MinCirclingTime, _ = flags.GetDuration("min-circling-time")
+	}
+	if flags.Changed("min-cruising-time") {
+		opts.MinCruisingTime, _ = flags.GetDuration("min-cruising-time")
+	}
+	if flags.Changed("smoothing-window") {
+		opts.SmoothingWindow, _ = flags.GetInt("smoothing-window")
+	}
+	altitude, err := flags.GetString("altitude")
+	if err != nil {
+		return opts, err
+	}
+	if opts.Altitude, err = igc.ParseAltitudeSource(altitude); err != nil {
+		return opts, err
+	}
+	opts.QNH, err = flags.GetFloat64("qnh")
+	return opts, err
+}
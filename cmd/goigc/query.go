@@ -0,0 +1,145 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ezgliding/goigc/pkg/archive"
+)
+
+func init() {
+	queryCmd.Flags().String("start", "", "first flight date (2006-01-02)")
+	queryCmd.Flags().String("end", "", "last flight date (2006-01-02)")
+	queryCmd.Flags().String("pilot", "", "pilot name containing the given text")
+	queryCmd.Flags().String("club", "", "club name containing the given text")
+	queryCmd.Flags().String("takeoff", "", "takeoff site containing the given text")
+	queryCmd.Flags().String("region", "", "region containing the given text")
+	queryCmd.Flags().String("glider", "", "glider type containing the given text")
+	queryCmd.Flags().Float64("min-distance", 0, "min flight distance in kms")
+	queryCmd.Flags().Float64("max-distance", 0, "max flight distance in kms")
+	queryCmd.Flags().Float64("min-speed", 0, "min flight speed in km/h")
+	queryCmd.Flags().Float64("max-speed", 0, "max flight speed in km/h")
+	queryCmd.Flags().Bool("no-update", false, "query the existing index without indexing new or changed flights")
+	queryCmd.Flags().Int("workers", archive.DefaultUpdateOptions().Workers, "number of tracks parsed concurrently when indexing")
+	queryCmd.Flags().String("preset", "glider",
+		"aircraft type setting the phase thresholds (glider, motorglider, hangglider, paraglider)")
+	queryCmd.Flags().String("altitude", "gnss", "altitude used for stats (gnss, pressure, qnh, auto)")
+	queryCmd.Flags().Float64("qnh", 0, "qnh in hPa for qnh altitudes - header or estimated from gnss by default")
+	queryCmd.Flags().String("output-format", "table", "output format for display (table, csv, json, yaml)")
+	queryCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	rootCmd.AddCommand(queryCmd)
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query PATH",
+	Short: "queries the flights crawled under the given path",
+	Long: `Queries the flights crawled under PATH, with the structure left by crawl.
+
+Flights are indexed in PATH/index.json along with the stats of their tracks,
+only new or changed ones being indexed on each query. Text filters match
+case insensitive substrings, and distance and speed are those given by the
+source, or the ones flown in the track for flights without them.
+
+Expected format for start and end dates is 2006-01-02.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+		q, err := query(cmd)
+		if err != nil {
+			return err
+		}
+
+		index, err := archive.Open(args[0])
+		if err != nil {
+			return err
+		}
+		noUpdate, err := cmd.Flags().GetBool("no-update")
+		if err != nil {
+			return err
+		}
+		if !noUpdate {
+			opts := archive.DefaultUpdateOptions()
+			if opts.Phases, err = phaseOptions(cmd); err != nil {
+				return err
+			}
+			if opts.Workers, err = cmd.Flags().GetInt("workers"); err != nil {
+				return err
+			}
+			if err := index.Update(opts); err != nil {
+				return err
+			}
+		}
+
+		result, err := archive.EncodeRecords(index.Query(q), outputFormat)
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func query(cmd *cobra.Command) (archive.Query, error) {
+	q := archive.Query{}
+	flags := cmd.Flags()
+	var err error
+	for name, date := range map[string]*time.Time{"start": &q.Start, "end": &q.End} {
+		value, err := flags.GetString(name)
+		if err != nil {
+			return q, err
+		}
+		if value == "" {
+			continue
+		}
+		if *date, err = time.Parse("2006-01-02", value); err != nil {
+			return q, err
+		}
+	}
+	for name, text := range map[string]*string{"pilot": &q.Pilot, "club": &q.Club,
+		"takeoff": &q.Takeoff, "region": &q.Region, "glider": &q.Glider} {
+		if *text, err = flags.GetString(name); err != nil {
+			return q, err
+		}
+	}
+	for name, value := range map[string]*float64{"min-distance": &q.MinDistance,
+		"max-distance": &q.MaxDistance, "min-speed": &q.MinSpeed, "max-speed": &q.MaxSpeed} {
+		if *value, err = flags.GetFloat64(name); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
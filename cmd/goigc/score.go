@@ -0,0 +1,93 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ezgliding/goigc/pkg/scoring"
+)
+
+func init() {
+	scoreCmd.Flags().String("rules", "olc-classic",
+		fmt.Sprintf("scoring rules, one of %v", strings.Join(scoring.Names(), ", ")))
+	scoreCmd.Flags().Float64("handicap", 0, "glider index to apply, none by default")
+	scoreCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	scoreCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	scoreCmd.Flags().String("output-format", "yaml", "output format for display")
+	scoreCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	rootCmd.AddCommand(scoreCmd)
+}
+
+var scoreCmd = &cobra.Command{
+	Use:   "score FILE",
+	Short: "computes the best score for the given flight",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+		name, err := cmd.Flags().GetString("rules")
+		if err != nil {
+			return err
+		}
+		handicap, err := cmd.Flags().GetFloat64("handicap")
+		if err != nil {
+			return err
+		}
+
+		rules, err := scoring.Get(name)
+		if err != nil {
+			return err
+		}
+		if handicap > 0 {
+			rules = scoring.NewHandicap(rules, handicap)
+		}
+
+		trk, err := parseLocation(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		score, err := rules.Optimize(trk)
+		if err != nil {
+			return err
+		}
+		result, err := score.Encode(outputFormat)
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,155 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func init() {
+	statsCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	statsCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	statsCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv)")
+	statsCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	statsCmd.Flags().String("preset", "glider",
+		"aircraft type setting the phase thresholds (glider, motorglider, hangglider, paraglider)")
+	statsCmd.Flags().String("altitude", "gnss", "altitude used for stats (gnss, pressure, qnh, auto)")
+	statsCmd.Flags().Float64("qnh", 0, "qnh in hPa for qnh altitudes - header or estimated from gnss by default")
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats FILE...",
+	Short: "compute summary statistics for the given flights",
+	Long:  "",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+
+		opts, err := phaseOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		results := make([]flightStats, len(args))
+		for i, f := range args {
+			trk, err := parseLocation(cmd, f)
+			if err != nil {
+				return fmt.Errorf("%v :: %v", f, err)
+			}
+			stats, err := trk.StatsWithOptions(opts)
+			if err != nil {
+				return fmt.Errorf("%v :: %v", f, err)
+			}
+			results[i] = flightStats{File: f, Date: trk.Date, Pilot: trk.Pilot,
+				GliderType: trk.GliderType, Stats: stats}
+		}
+
+		var result []byte
+		switch outputFormat {
+		case "yaml":
+			result, err = yaml.Marshal(results)
+		case "json":
+			result, err = json.MarshalIndent(results, "", "  ")
+		case "csv":
+			result, err = encodeStatsCSV(results)
+		default:
+			err = fmt.Errorf("unsupported format '%v'", outputFormat)
+		}
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// flightStats holds the stats of a single flight file.
+type flightStats struct {
+	File       string
+	Date       time.Time
+	Pilot      string
+	GliderType string
+	igc.Stats  `yaml:",inline"`
+}
+
+// phaseColumns are the phase types with a duration column in the csv output.
+var phaseColumns = []igc.PhaseType{igc.Towing, igc.EngineOn, igc.Cruising, igc.Circling}
+
+// encodeStatsCSV returns a header and one row per flight, with the phase
+// durations and height bands omitted.
+func encodeStatsCSV(results []flightStats) ([]byte, error) {
+	header := []string{"File", "Date", "Pilot", "GliderType", "Start", "End",
+		"Duration", "Distance", "MaxAltitude", "MaxAltitudeTime", "MinAltitude",
+		"MinAltitudeTime", "CirclingPercentage", "Thermals", "AvgClimb",
+		"AvgCruiseSpeed", "AvgLD"}
+	for _, t := range phaseColumns {
+		header = append(header, fmt.Sprintf("Duration-%v", t))
+	}
+	for _, b := range results[0].LDBands {
+		header = append(header, fmt.Sprintf("Distance-LD%v", b.Min))
+	}
+	records := [][]string{header}
+	for _, r := range results {
+		record := []string{r.File, r.Date.Format("2006-01-02"), r.Pilot, r.GliderType,
+			r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339),
+			fmt.Sprintf("%.0f", r.Duration.Seconds()), fmt.Sprintf("%f", r.Distance),
+			fmt.Sprintf("%d", r.MaxAltitude), r.MaxAltitudeTime.Format(time.RFC3339),
+			fmt.Sprintf("%d", r.MinAltitude), r.MinAltitudeTime.Format(time.RFC3339),
+			fmt.Sprintf("%f", r.CirclingPercentage), fmt.Sprintf("%d", r.Thermals),
+			fmt.Sprintf("%f", r.AvgClimb), fmt.Sprintf("%f", r.AvgCruiseSpeed),
+			fmt.Sprintf("%f", r.AvgLD)}
+		for _, t := range phaseColumns {
+			record = append(record, fmt.Sprintf("%.0f", r.Phases[t.String()].Duration.Seconds()))
+		}
+		for _, b := range r.LDBands {
+			record = append(record, fmt.Sprintf("%f", b.Distance))
+		}
+		records = append(records, record)
+	}
+
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(records); err != nil {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
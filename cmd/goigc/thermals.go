@@ -0,0 +1,67 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	thermalsCmd.Flags().String("format", "", "input file format (igc, gpx, kml, nmea) - auto detection by default")
+	thermalsCmd.Flags().Bool("lenient", false, "skip invalid records instead of failing")
+	thermalsCmd.Flags().String("output-format", "yaml", "output format for display (yaml, json, csv, kml, kmz)")
+	thermalsCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	rootCmd.AddCommand(thermalsCmd)
+}
+
+var thermalsCmd = &cobra.Command{
+	Use:   "thermals FILE",
+	Short: "compute thermals for the given flight",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+
+		trk, err := parseLocation(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		result, err := trk.EncodeThermals(outputFormat)
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
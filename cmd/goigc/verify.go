@@ -0,0 +1,51 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify FILE",
+	Short: "verifies the security signature of the given flight",
+	Long: `Verifies the security signature (G record) of the given flight.
+
+The verification algorithm is picked from the manufacturer in the A record.
+Fails if the signature is invalid or the manufacturer is not supported.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if err := igc.Verify(string(content)); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%v :: valid signature\n", args[0])
+		return nil
+	},
+}
@@ -0,0 +1,230 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+	"github.com/ezgliding/goigc/pkg/waypoint"
+)
+
+func init() {
+	waypointsCmd.Flags().String("name", "", "only waypoints with names containing this value")
+	waypointsCmd.Flags().String("country", "", "only waypoints in this country")
+	waypointsCmd.Flags().IntSlice("style", []int{}, "only waypoints with these styles")
+	waypointsCmd.Flags().Bool("landable", false, "only airfields and outlanding fields")
+	waypointsCmd.Flags().String("task", "", "convert the task with this name instead of listing waypoints")
+	waypointsCmd.Flags().String("output-format", "table", "output format for display (table, cup, json, yaml, geojson, kml), table, json, yaml or igc for tasks")
+	waypointsCmd.Flags().String("output-file", "/dev/stdout", "output file to write to")
+	rootCmd.AddCommand(waypointsCmd)
+}
+
+// taskResult is the output of the waypoints command for a single task.
+type taskResult struct {
+	Task  igc.Task
+	Zones []igc.Zone
+}
+
+var waypointsCmd = &cobra.Command{
+	Use:   "waypoints FILE",
+	Short: "lists, filters and converts waypoints in the given file",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, err := cmd.Flags().GetString("output-file")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output-format")
+		if err != nil {
+			return err
+		}
+		taskName, err := cmd.Flags().GetString("task")
+		if err != nil {
+			return err
+		}
+
+		f, err := waypoint.ParseLocation(args[0])
+		if err != nil {
+			return err
+		}
+
+		var result []byte
+		if taskName != "" {
+			result, err = encodeTask(f, taskName, outputFormat)
+		} else {
+			if f.Waypoints, err = filterWaypoints(cmd, f.Waypoints); err != nil {
+				return err
+			}
+			if outputFormat == "table" {
+				result, err = encodeWaypointsTable(f.Waypoints)
+			} else {
+				result, err = f.Encode(outputFormat)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if outputFile == "/dev/stdout" {
+			fmt.Printf("%v", string(result))
+		} else {
+			err = ioutil.WriteFile(outputFile, result, 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func filterWaypoints(cmd *cobra.Command, waypoints []waypoint.Waypoint) ([]waypoint.Waypoint, error) {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	country, err := cmd.Flags().GetString("country")
+	if err != nil {
+		return nil, err
+	}
+	styles, err := cmd.Flags().GetIntSlice("style")
+	if err != nil {
+		return nil, err
+	}
+	landable, err := cmd.Flags().GetBool("landable")
+	if err != nil {
+		return nil, err
+	}
+
+	result := []waypoint.Waypoint{}
+	for _, w := range waypoints {
+		if name != "" && !strings.Contains(strings.ToLower(w.Name), strings.ToLower(name)) {
+			continue
+		}
+		if country != "" && !strings.EqualFold(w.Country, country) {
+			continue
+		}
+		if landable && !w.Style.IsLandable() {
+			continue
+		}
+		if len(styles) > 0 && !containsStyle(styles, w.Style) {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+func containsStyle(styles []int, style waypoint.Style) bool {
+	for _, s := range styles {
+		if waypoint.Style(s) == style {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeWaypointsTable(waypoints []waypoint.Waypoint) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCODE\tCOUNTRY\tLAT\tLNG\tELEVATION\tSTYLE")
+	for _, v := range waypoints {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%.5f\t%.5f\t%.0fm\t%d\n",
+			v.Name, v.Code, v.Country, v.Lat, v.Lng, v.Elevation, v.Style)
+	}
+	err := w.Flush()
+	return buf.Bytes(), err
+}
+
+func encodeTask(f waypoint.File, name string, format string) ([]byte, error) {
+	for _, t := range f.Tasks {
+		if t.Name != name {
+			continue
+		}
+		task, zones, err := f.Task(t)
+		if err != nil {
+			return []byte{}, err
+		}
+		r := taskResult{Task: task, Zones: zones}
+		switch format {
+		case "table":
+			return encodeTaskTable(task, zones)
+		case "json":
+			return json.MarshalIndent(r, "", "  ")
+		case "yaml":
+			return yaml.Marshal(r)
+		case "igc":
+			trk := igc.NewTrack()
+			trk.Task = task
+			return trk.Encode("igc")
+		default:
+			return []byte{}, fmt.Errorf("unsupported format for tasks '%v'", format)
+		}
+	}
+	return []byte{}, fmt.Errorf("task not found :: %v", name)
+}
+
+// zoneNames are the observation zone types in the task table.
+var zoneNames = map[igc.ZoneType]string{
+	igc.LineZone:     "line",
+	igc.CylinderZone: "cylinder",
+	igc.SectorZone:   "sector",
+	igc.KeyholeZone:  "keyhole",
+}
+
+// encodeTaskTable returns one row per task point, with the zones of the
+// start, turnpoints and finish and the length of the leg ending in each.
+func encodeTaskTable(task igc.Task, zones []igc.Zone) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "POINT\tNAME\tLAT\tLNG\tZONE\tRADIUS\tLEG")
+	fmt.Fprintf(w, "takeoff\t%v\t%.5f\t%.5f\t\t\t\n",
+		task.Takeoff.Description, task.Takeoff.Lat.Degrees(), task.Takeoff.Lng.Degrees())
+	points := append(append([]igc.Point{task.Start}, task.Turnpoints...), task.Finish)
+	for i, p := range points {
+		name := fmt.Sprintf("tp%d", i)
+		switch i {
+		case 0:
+			name = "start"
+		case len(points) - 1:
+			name = "finish"
+		}
+		zone, radius := "", ""
+		if i < len(zones) {
+			zone, radius = zoneNames[zones[i].Type], fmt.Sprintf("%.1fkm", zones[i].Radius)
+		}
+		leg := ""
+		if i > 0 {
+			leg = fmt.Sprintf("%.1fkm", points[i-1].Distance(p))
+		}
+		fmt.Fprintf(w, "%v\t%v\t%.5f\t%.5f\t%v\t%v\t%v\n",
+			name, p.Description, p.Lat.Degrees(), p.Lng.Degrees(), zone, radius, leg)
+	}
+	fmt.Fprintf(w, "landing\t%v\t%.5f\t%.5f\t\t\t\n",
+		task.Landing.Description, task.Landing.Lat.Degrees(), task.Landing.Lng.Degrees())
+	fmt.Fprintf(w, "total\t\t\t\t\t\t%.1fkm\n", task.Distance())
+	err := w.Flush()
+	return buf.Bytes(), err
+}
@@ -0,0 +1,61 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWaypointsCmdTaskTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "waypoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "task.txt")
+
+	cmd := rootCmd
+	cmd.SetOutput(new(bytes.Buffer))
+	// table is the default output format
+	cmd.SetArgs([]string{"waypoints", "../../testdata/waypoint/waypoint-valid.1.cup",
+		"--task", "Alps 500", "--output-file", output})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	expected := []string{"POINT", "takeoff", "start", "tp1", "tp2", "tp3", "finish", "landing", "total"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v lines got\n%v", len(expected), string(b))
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(lines[i], e+" ") {
+			t.Errorf("expected line %v to start with %v got '%v'", i, e, lines[i])
+		}
+	}
+	if !strings.Contains(lines[2], "Bouchere") || !strings.Contains(lines[2], "line") {
+		t.Errorf("expected start at Bouchere with a line got '%v'", lines[2])
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+/*
+Package airspace provides means to parse airspace files and check flights
+against them.
+
+The supported format is OpenAir, including polygons, arcs and circles with
+lower and upper limits in flight levels, above mean sea level or above
+ground level:
+
+http://www.winpilot.com/UsersGuide/UserAirspace.asp
+
+Each airspace is converted to an s2.Polygon, and Infringements() reports the
+parts of an igc.Track inside any of them.
+
+*/
+package airspace
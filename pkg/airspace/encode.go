@@ -0,0 +1,61 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package airspace
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeInfringements returns the infringements in the given format.
+//
+// Supported formats are yaml, json and csv.
+func EncodeInfringements(infringements []Infringement, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(infringements, "", "  ")
+	case "yaml":
+		return yaml.Marshal(infringements)
+	case "csv":
+		return encodeInfringementsCSV(infringements)
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+func encodeInfringementsCSV(infringements []Infringement) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.Write([]string{"Name", "Class", "Entry", "Exit", "Duration",
+		"MaxVertical", "MaxLateral"}); err != nil {
+		return []byte{}, err
+	}
+	for _, i := range infringements {
+		if err := w.Write([]string{i.Name, i.Class,
+			i.Entry.Format("15:04:05"), i.Exit.Format("15:04:05"),
+			fmt.Sprintf("%.0f", i.Duration().Seconds()),
+			fmt.Sprintf("%.0f", i.MaxVertical), fmt.Sprintf("%.0f", i.MaxLateral)}); err != nil {
+			return []byte{}, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,164 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package airspace
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// Options holds the altitude settings used to check a track against the
+// airspaces.
+//
+// Altitude and QNH select the altitude compared with MSL and AGL limits (see
+// igc.Track.Altitudes()). Flight levels are compared with the raw pressure
+// altitude, except for the igc.AltitudeGNSS source or when it is missing.
+// GroundElevation is the terrain elevation in meters used for the AGL and
+// Surface limits, as there is no terrain model.
+type Options struct {
+	Altitude        igc.AltitudeSource
+	QNH             float64
+	GroundElevation float64
+}
+
+// Infringement is a part of a track inside an airspace.
+//
+// MaxVertical is the max distance in meters inside the vertical limits, and
+// MaxLateral the max distance in meters inside the boundary.
+type Infringement struct {
+	Name        string
+	Class       string
+	Entry       time.Time
+	EntryIndex  int
+	Exit        time.Time
+	ExitIndex   int
+	MaxVertical float64
+	MaxLateral  float64
+}
+
+// Duration returns the time spent inside the airspace.
+func (i *Infringement) Duration() time.Duration {
+	return i.Exit.Sub(i.Entry)
+}
+
+// Infringements returns every part of the track inside any of the given
+// airspaces, ordered by entry time.
+func Infringements(track *igc.Track, airspaces []Airspace, opts Options) ([]Infringement, error) {
+	if len(track.Points) == 0 {
+		return []Infringement{}, fmt.Errorf("track has no points")
+	}
+	alts, err := track.Altitudes(opts.Altitude, opts.QNH)
+	if err != nil {
+		return []Infringement{}, err
+	}
+
+	result := []Infringement{}
+	// open holds the index in result of the current infringement of each
+	// airspace, -1 if outside
+	open := make([]int, len(airspaces))
+	for i := range open {
+		open[i] = -1
+	}
+	for i, p := range track.Points {
+		altitude := float64(alts[i])
+		pressure := float64(p.PressureAltitude)
+		if opts.Altitude == igc.AltitudeGNSS || p.PressureAltitude == 0 {
+			pressure = altitude
+		}
+		for j := range airspaces {
+			vertical, lateral, inside := airspaces[j].penetration(p.LatLng,
+				altitude, pressure, opts.GroundElevation)
+			if !inside {
+				open[j] = -1
+				continue
+			}
+			if open[j] < 0 {
+				result = append(result, Infringement{
+					Name: airspaces[j].Name, Class: airspaces[j].Class,
+					Entry: p.Time, EntryIndex: i})
+				open[j] = len(result) - 1
+			}
+			inf := &result[open[j]]
+			inf.Exit, inf.ExitIndex = p.Time, i
+			inf.MaxVertical = math.Max(inf.MaxVertical, vertical)
+			inf.MaxLateral = math.Max(inf.MaxLateral, lateral)
+		}
+	}
+	return result, nil
+}
+
+// Contains checks if the given position is inside the airspace boundary,
+// ignoring the vertical limits.
+func (a *Airspace) Contains(ll s2.LatLng) bool {
+	if a.Polygon == nil {
+		return false
+	}
+	return a.Polygon.RectBound().ContainsLatLng(ll) &&
+		a.Polygon.ContainsPoint(s2.PointFromLatLng(ll))
+}
+
+// penetration returns the vertical and lateral distances in meters inside
+// the airspace, and false if outside. Altitude is compared with MSL and AGL
+// limits and pressure with flight levels.
+func (a *Airspace) penetration(ll s2.LatLng, altitude, pressure, ground float64) (float64, float64, bool) {
+	if !a.Contains(ll) {
+		return 0, 0, false
+	}
+	above := a.Lower.value(altitude, pressure, ground)
+	below := -a.Upper.value(altitude, pressure, ground)
+	if above < 0 || below < 0 {
+		return 0, 0, false
+	}
+	return math.Min(above, below), a.boundaryDistance(ll), true
+}
+
+// value returns the given altitude relative to the limit, in the matching
+// reference. Unlimited limits are always above.
+func (l Limit) value(altitude, pressure, ground float64) float64 {
+	switch l.Reference {
+	case Surface:
+		return altitude - ground
+	case AGL:
+		return altitude - ground - l.Altitude
+	case FlightLevel:
+		return pressure - l.Altitude
+	case Unlimited:
+		return math.Inf(-1)
+	default:
+		return altitude - l.Altitude
+	}
+}
+
+// boundaryDistance returns the distance in meters to the closest edge of
+// the airspace boundary.
+func (a *Airspace) boundaryDistance(ll s2.LatLng) float64 {
+	pt := s2.PointFromLatLng(ll)
+	best := math.Inf(1)
+	for _, loop := range a.Polygon.Loops() {
+		n := loop.NumVertices()
+		for i := 0; i < n; i++ {
+			d := s2.DistanceFromSegment(pt, loop.Vertex(i), loop.Vertex((i+1)%n))
+			best = math.Min(best, d.Radians())
+		}
+	}
+	return best * igc.EarthRadius * 1000
+}
@@ -0,0 +1,165 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package airspace
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// airspaceTrack returns a track heading north along the given longitude
+// from lat0 to lat1, at the given GNSS and pressure altitudes.
+func airspaceTrack(lng, lat0, lat1 float64, gnss, pressure int64) igc.Track {
+	track := igc.NewTrack()
+	t := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for lat := lat0; lat <= lat1+1e-9; lat += 0.01 {
+		p := igc.NewPointFromLatLng(lat, lng)
+		p.Time, p.FixValidity = t, 'A'
+		p.GNSSAltitude, p.PressureAltitude = gnss, pressure
+		track.Points = append(track.Points, p)
+		t = t.Add(10 * time.Second)
+	}
+	return track
+}
+
+func TestInfringements(t *testing.T) {
+	airspaces, err := ParseLocation("../../testdata/airspace/airspace-valid.1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		track    igc.Track
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "ctr-below-upper",
+			track:    airspaceTrack(5.2, 45.25, 45.55, 800, 800),
+			expected: []string{"CTR GRENOBLE"},
+		},
+		{
+			name:     "ctr-above-upper",
+			track:    airspaceTrack(5.2, 45.25, 45.55, 1200, 1200),
+			expected: []string{},
+		},
+		{
+			name:     "circle-gnss",
+			track:    airspaceTrack(6, 44.9, 45.1, 2500, 1900),
+			expected: []string{"R 71 CIRCLE"},
+		},
+		{
+			name:     "circle-pressure-below-fl",
+			track:    airspaceTrack(6, 44.9, 45.1, 2500, 1900),
+			opts:     Options{Altitude: igc.AltitudePressure},
+			expected: []string{},
+		},
+		{
+			name:     "arc-below-agl",
+			track:    airspaceTrack(5.05, 44.65, 44.8, 1000, 1000),
+			opts:     Options{GroundElevation: 800},
+			expected: []string{},
+		},
+		{
+			name:     "arc-above-agl",
+			track:    airspaceTrack(5.05, 44.65, 44.8, 1200, 1200),
+			opts:     Options{GroundElevation: 800},
+			expected: []string{"D 12 ARC"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Infringements(&tt.track, airspaces, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %v infringements got %+v", len(tt.expected), result)
+			}
+			for i, name := range tt.expected {
+				if result[i].Name != name {
+					t.Errorf("expected infringement of %v got %v", name, result[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestInfringementValues(t *testing.T) {
+	airspaces, err := ParseLocation("../../testdata/airspace/airspace-valid.1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// points off the boundary, from 45.335 to 45.495 inside
+	track := airspaceTrack(5.2, 45.255, 45.555, 800, 800)
+	result, err := Infringements(&track, airspaces, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected 1 infringement got %v", len(result))
+	}
+	inf := result[0]
+	if inf.Class != "D" || inf.EntryIndex != 8 || inf.ExitIndex != 24 {
+		t.Errorf("expected class D from point 8 to 24 got %+v", inf)
+	}
+	if inf.Duration() != 160*time.Second {
+		t.Errorf("expected duration 160s got %v", inf.Duration())
+	}
+	// 1066.8m upper limit
+	if math.Abs(inf.MaxVertical-266.8) > 0.1 {
+		t.Errorf("expected max vertical 266.8m got %v", inf.MaxVertical)
+	}
+	// 2' of longitude from the west edge
+	if math.Abs(inf.MaxLateral-2620) > 30 {
+		t.Errorf("expected max lateral 2620m got %v", inf.MaxLateral)
+	}
+}
+
+func TestInfringementsNoPoints(t *testing.T) {
+	track := igc.NewTrack()
+	if _, err := Infringements(&track, []Airspace{}, Options{}); err == nil {
+		t.Errorf("expected error for track with no points")
+	}
+}
+
+func TestEncodeInfringements(t *testing.T) {
+	infringements := []Infringement{{Name: "CTR GRENOBLE", Class: "D",
+		Entry: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+		Exit:  time.Date(2020, 1, 1, 12, 2, 0, 0, time.UTC), MaxVertical: 120.4, MaxLateral: 850.6}}
+	result, err := EncodeInfringements(infringements, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Name,Class,Entry,Exit,Duration,MaxVertical,MaxLateral\n" +
+		"CTR GRENOBLE,D,12:00:00,12:02:00,120,120,851\n"
+	if string(result) != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, string(result))
+	}
+	for _, format := range []string{"json", "yaml"} {
+		if _, err := EncodeInfringements(infringements, format); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := EncodeInfringements(infringements, "kml"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
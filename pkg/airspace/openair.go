@@ -0,0 +1,402 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package airspace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// Reference is the reference of an airspace vertical limit.
+type Reference int
+
+// Vertical limit references.
+const (
+	MSL         Reference = 0
+	AGL         Reference = 1
+	FlightLevel Reference = 2
+	Surface     Reference = 3
+	Unlimited   Reference = 4
+)
+
+var referenceNames = map[Reference]string{
+	MSL:         "MSL",
+	AGL:         "AGL",
+	FlightLevel: "FL",
+	Surface:     "SFC",
+	Unlimited:   "UNL",
+}
+
+// String returns the OpenAir name of the reference.
+func (r Reference) String() string {
+	if name, ok := referenceNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Reference(%d)", int(r))
+}
+
+const (
+	// ArcStep is the angle in degrees between the points generated for arcs
+	// and circles.
+	ArcStep = 5.0
+	// nauticalMile is the length of a nautical mile in meters.
+	nauticalMile = 1852.0
+	// foot is the length of a foot in meters.
+	foot = 0.3048
+)
+
+// Limit is the lower or upper vertical limit of an airspace.
+//
+// Altitude is in meters, being the pressure altitude for flight levels and
+// zero for the Surface and Unlimited references.
+type Limit struct {
+	Altitude  float64
+	Reference Reference
+}
+
+// String returns the limit as in OpenAir files, in feet.
+func (l Limit) String() string {
+	switch l.Reference {
+	case Surface, Unlimited:
+		return l.Reference.String()
+	case FlightLevel:
+		return fmt.Sprintf("FL%.0f", l.Altitude/foot/100)
+	default:
+		return fmt.Sprintf("%.0fft %v", l.Altitude/foot, l.Reference)
+	}
+}
+
+// Airspace holds a single airspace with its boundary and vertical limits.
+//
+// Boundary has the vertices of the airspace, with arcs and circles converted
+// to points every ArcStep degrees, and Polygon is built from it.
+type Airspace struct {
+	Name     string
+	Class    string
+	Lower    Limit
+	Upper    Limit
+	Boundary []s2.LatLng
+	Polygon  *s2.Polygon `json:"-" yaml:"-"`
+}
+
+// ParseLocation returns the airspaces in the given location.
+func ParseLocation(location string) ([]Airspace, error) {
+	r, err := os.Open(location)
+	if err != nil {
+		return []Airspace{}, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// Parse returns the airspaces in the given reader in OpenAir format.
+//
+// Supported records are AC, AN, AL, AH, DP, DA, DB, DC and the V X= and
+// V D= variables, with any others (like the AT label and SP/SB styles)
+// ignored.
+func Parse(r io.Reader) ([]Airspace, error) {
+	var airspaces []Airspace
+	var p *parser
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "*") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		record, value := strings.ToUpper(fields[0]), ""
+		if len(fields) > 1 {
+			value = strings.TrimSpace(fields[1])
+		}
+		if record == "AC" {
+			if p != nil {
+				a, err := p.airspace()
+				if err != nil {
+					return airspaces, fmt.Errorf("line %d :: %v", p.line, err)
+				}
+				airspaces = append(airspaces, a)
+			}
+			p = &parser{line: number, clockwise: true,
+				Airspace: Airspace{Class: value}}
+			continue
+		}
+		if p == nil {
+			return airspaces, fmt.Errorf("line %d :: record %v before any AC", number, record)
+		}
+		if err := p.parseRecord(record, value); err != nil {
+			return airspaces, fmt.Errorf("line %d :: %v", number, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return airspaces, err
+	}
+	if p != nil {
+		a, err := p.airspace()
+		if err != nil {
+			return airspaces, fmt.Errorf("line %d :: %v", p.line, err)
+		}
+		airspaces = append(airspaces, a)
+	}
+	return airspaces, nil
+}
+
+// parser holds the state while parsing a single airspace.
+type parser struct {
+	Airspace
+	line      int
+	center    s2.LatLng
+	clockwise bool
+}
+
+func (p *parser) parseRecord(record string, value string) error {
+	var err error
+	switch record {
+	case "AN":
+		p.Name = value
+	case "AL":
+		p.Lower, err = parseLimit(value)
+	case "AH":
+		p.Upper, err = parseLimit(value)
+	case "V":
+		err = p.parseVariable(value)
+	case "DP":
+		var ll s2.LatLng
+		if ll, err = parseCoordinate(value); err == nil {
+			p.Boundary = append(p.Boundary, ll)
+		}
+	case "DA":
+		err = p.parseArc(value)
+	case "DB":
+		err = p.parseArcPoints(value)
+	case "DC":
+		var r float64
+		if r, err = strconv.ParseFloat(value, 64); err == nil {
+			p.Boundary = append(p.Boundary, arc(p.center, r*nauticalMile, 0, 360, true)...)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %v record '%v' :: %v", record, value, err)
+	}
+	return nil
+}
+
+func (p *parser) parseVariable(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("missing '='")
+	}
+	v := strings.TrimSpace(kv[1])
+	switch strings.ToUpper(strings.TrimSpace(kv[0])) {
+	case "X":
+		ll, err := parseCoordinate(v)
+		if err != nil {
+			return err
+		}
+		p.center = ll
+	case "D":
+		if v != "+" && v != "-" {
+			return fmt.Errorf("invalid direction '%v'", v)
+		}
+		p.clockwise = v == "+"
+	}
+	return nil
+}
+
+// parseArc parses a DA record, with the radius in nautical miles and the
+// start and end angles in degrees.
+func (p *parser) parseArc(value string) error {
+	fields := strings.Split(value, ",")
+	if len(fields) != 3 {
+		return fmt.Errorf("expected radius, start and end angles")
+	}
+	values := make([]float64, 3)
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	p.Boundary = append(p.Boundary,
+		arc(p.center, values[0]*nauticalMile, values[1], values[2], p.clockwise)...)
+	return nil
+}
+
+// parseArcPoints parses a DB record, an arc between two points around the
+// current center.
+func (p *parser) parseArcPoints(value string) error {
+	fields := strings.Split(value, ",")
+	if len(fields) != 2 {
+		return fmt.Errorf("expected start and end points")
+	}
+	start, err := parseCoordinate(fields[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseCoordinate(fields[1])
+	if err != nil {
+		return err
+	}
+	radius := distance(p.center, start)
+	points := arc(p.center, radius, bearing(p.center, start), bearing(p.center, end), p.clockwise)
+	// use the exact start and end points instead of the generated ones
+	points[0], points[len(points)-1] = start, end
+	p.Boundary = append(p.Boundary, points...)
+	return nil
+}
+
+// airspace returns the parsed airspace, building its polygon.
+func (p *parser) airspace() (Airspace, error) {
+	a := p.Airspace
+	var points []s2.Point
+	for _, ll := range a.Boundary {
+		pt := s2.PointFromLatLng(ll)
+		if len(points) > 0 && (pt.ApproxEqual(points[len(points)-1]) || pt.ApproxEqual(points[0])) {
+			continue
+		}
+		points = append(points, pt)
+	}
+	if len(points) < 3 {
+		return a, fmt.Errorf("airspace '%v' has %v points, min 3 required", a.Name, len(points))
+	}
+	loop := s2.LoopFromPoints(points)
+	// the smaller of the two areas, whatever the direction of the boundary
+	loop.Normalize()
+	a.Polygon = s2.PolygonFromLoops([]*s2.Loop{loop})
+	return a, nil
+}
+
+// coordinateRegexp matches coordinates as DD:MM:SS N DDD:MM:SS E, with
+// optional seconds and decimals.
+var coordinateRegexp = regexp.MustCompile(
+	`^(\d+):(\d+(?:\.\d+)?)(?::(\d+(?:\.\d+)?))?\s*([NS])\s*(\d+):(\d+(?:\.\d+)?)(?::(\d+(?:\.\d+)?))?\s*([EW])$`)
+
+// parseCoordinate parses a coordinate in OpenAir format.
+func parseCoordinate(value string) (s2.LatLng, error) {
+	m := coordinateRegexp.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if m == nil {
+		return s2.LatLng{}, fmt.Errorf("invalid coordinate '%v'", value)
+	}
+	degrees := func(d, m, s, h string) float64 {
+		dv, _ := strconv.ParseFloat(d, 64)
+		mv, _ := strconv.ParseFloat(m, 64)
+		sv, _ := strconv.ParseFloat(s, 64)
+		v := dv + mv/60 + sv/3600
+		if h == "S" || h == "W" {
+			v = -v
+		}
+		return v
+	}
+	lat, lng := degrees(m[1], m[2], m[3], m[4]), degrees(m[5], m[6], m[7], m[8])
+	if math.Abs(lat) > 90 || math.Abs(lng) > 180 {
+		return s2.LatLng{}, fmt.Errorf("coordinate out of range '%v'", value)
+	}
+	return s2.LatLngFromDegrees(lat, lng), nil
+}
+
+// limitRegexp matches altitudes like 3500ft MSL, 1000 ft AGL or 1500m.
+var limitRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(FT|F|M)?\s*(MSL|AMSL|ALT|AGL|AGND|GND|SFC|ASFC)?$`)
+
+// parseLimit parses a vertical limit, with feet MSL being the default.
+func parseLimit(value string) (Limit, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	switch {
+	case v == "SFC" || v == "GND":
+		return Limit{Reference: Surface}, nil
+	case strings.HasPrefix(v, "UNL"):
+		return Limit{Reference: Unlimited}, nil
+	case strings.HasPrefix(v, "FL"):
+		fl, err := strconv.ParseFloat(strings.TrimSpace(v[2:]), 64)
+		if err != nil {
+			return Limit{}, err
+		}
+		return Limit{Altitude: fl * 100 * foot, Reference: FlightLevel}, nil
+	}
+	m := limitRegexp.FindStringSubmatch(v)
+	if m == nil {
+		return Limit{}, fmt.Errorf("invalid limit '%v'", value)
+	}
+	alt, _ := strconv.ParseFloat(m[1], 64)
+	if m[2] != "M" {
+		alt *= foot
+	}
+	l := Limit{Altitude: alt, Reference: MSL}
+	switch m[3] {
+	case "AGL", "AGND", "GND", "SFC", "ASFC":
+		l.Reference = AGL
+	}
+	return l, nil
+}
+
+// arc returns the points of the arc around center with the given radius in
+// meters, from the start to the end bearing in degrees. A full circle is
+// returned when start and end are 0 and 360.
+func arc(center s2.LatLng, radius float64, start float64, end float64, clockwise bool) []s2.LatLng {
+	sweep := math.Mod(end-start+720, 360)
+	if !clockwise {
+		sweep = math.Mod(start-end+720, 360)
+	}
+	if sweep == 0 && end != start {
+		sweep = 360
+	}
+	n := int(math.Ceil(sweep / ArcStep))
+	points := make([]s2.LatLng, 0, n+1)
+	for i := 0; i <= n; i++ {
+		step := math.Min(float64(i)*ArcStep, sweep)
+		if !clockwise {
+			step = -step
+		}
+		points = append(points, destination(center, start+step, radius))
+	}
+	return points
+}
+
+// destination returns the point at the given distance in meters from
+// origin, in the given bearing in degrees.
+func destination(origin s2.LatLng, bearing float64, distance float64) s2.LatLng {
+	d := distance / (igc.EarthRadius * 1000)
+	b := bearing * math.Pi / 180
+	lat1, lng1 := origin.Lat.Radians(), origin.Lng.Radians()
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lng2 := lng1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1),
+		math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return s2.LatLng{Lat: s1.Angle(lat2), Lng: s1.Angle(lng2)}.Normalized()
+}
+
+// bearing returns the initial bearing in degrees from a to b.
+func bearing(a, b s2.LatLng) float64 {
+	pa, pb := igc.Point{LatLng: a}, igc.Point{LatLng: b}
+	return pa.Bearing(pb).Degrees()
+}
+
+// distance returns the distance in meters between a and b.
+func distance(a, b s2.LatLng) float64 {
+	return a.Distance(b).Radians() * igc.EarthRadius * 1000
+}
@@ -0,0 +1,184 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package airspace
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+var update = flag.Bool("update", false, "update golden test data")
+
+func TestParse(t *testing.T) {
+	// testdata/airspace file name format is testname.[1|0].txt
+	match, err := filepath.Glob("../../testdata/airspace/airspace-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range match {
+		t.Run(in, func(t *testing.T) {
+			parts := strings.Split(in, ".")
+			ok, _ := strconv.ParseBool(parts[len(parts)-2])
+
+			result, err := ParseLocation(in)
+			if err != nil && !ok {
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			} else if !ok {
+				t.Fatalf("expected error parsing %v", in)
+			}
+			resultJSON, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := fmt.Sprintf("%v.golden", in)
+			// update golden if flag is passed
+			if *update {
+				if err = ioutil.WriteFile(out, resultJSON, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expectedJSON, err := ioutil.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(resultJSON) != string(expectedJSON) {
+				t.Errorf("expected\n%+v\ngot\n%+v", string(expectedJSON), string(resultJSON))
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	airspaces, err := ParseLocation("../../testdata/airspace/airspace-valid.1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(airspaces) != 3 {
+		t.Fatalf("expected 3 airspaces got %v", len(airspaces))
+	}
+
+	ctr := airspaces[0]
+	if ctr.Name != "CTR GRENOBLE" || ctr.Class != "D" || len(ctr.Boundary) != 4 {
+		t.Errorf("expected class D CTR GRENOBLE with 4 points got %+v", ctr)
+	}
+	if !ctr.Contains(s2.LatLngFromDegrees(45.4, 5.2)) || ctr.Contains(s2.LatLngFromDegrees(45.6, 5.2)) {
+		t.Errorf("expected ctr to contain 45.4,5.2 and not 45.6,5.2")
+	}
+
+	circle := airspaces[1]
+	if circle.Lower.Reference != FlightLevel || math.Abs(circle.Lower.Altitude-1981.2) > 0.1 {
+		t.Errorf("expected lower limit FL65 got %v", circle.Lower)
+	}
+	if len(circle.Boundary) != int(360/ArcStep)+1 {
+		t.Errorf("expected %v circle points got %v", int(360/ArcStep)+1, len(circle.Boundary))
+	}
+	// 5nm radius
+	center := s2.LatLngFromDegrees(45, 6)
+	for _, ll := range circle.Boundary {
+		if d := distance(center, ll); math.Abs(d-5*nauticalMile) > 1 {
+			t.Fatalf("expected circle point at 9260m got %v", d)
+		}
+	}
+	if !circle.Contains(center) || circle.Contains(s2.LatLngFromDegrees(45.1, 6)) {
+		t.Errorf("expected circle to contain its center only")
+	}
+
+	arc := airspaces[2]
+	if arc.Lower.Reference != AGL || arc.Upper.Reference != Unlimited {
+		t.Errorf("expected AGL to unlimited got %v %v", arc.Lower, arc.Upper)
+	}
+	// north east and south west quarters, without the north west one
+	if !arc.Contains(s2.LatLngFromDegrees(44.72, 5.05)) ||
+		!arc.Contains(s2.LatLngFromDegrees(44.62, 4.93)) ||
+		arc.Contains(s2.LatLngFromDegrees(44.72, 4.93)) {
+		t.Errorf("expected arc airspace in north east and south west quarters")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value     string
+		altitude  float64
+		reference Reference
+	}{
+		{value: "SFC", reference: Surface},
+		{value: "GND", reference: Surface},
+		{value: "UNLTD", reference: Unlimited},
+		{value: "FL65", altitude: 1981.2, reference: FlightLevel},
+		{value: "FL 115", altitude: 3505.2, reference: FlightLevel},
+		{value: "3500ft MSL", altitude: 1066.8, reference: MSL},
+		{value: "3500 FT AMSL", altitude: 1066.8, reference: MSL},
+		{value: "3500", altitude: 1066.8, reference: MSL},
+		{value: "1000 ft AGL", altitude: 304.8, reference: AGL},
+		{value: "1500m ASFC", altitude: 1500, reference: AGL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			l, err := parseLimit(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Reference != tt.reference || math.Abs(l.Altitude-tt.altitude) > 0.01 {
+				t.Errorf("expected %v %v got %v %v", tt.altitude, tt.reference, l.Altitude, l.Reference)
+			}
+		})
+	}
+	if _, err := parseLimit("high"); err == nil {
+		t.Errorf("expected error for invalid limit")
+	}
+}
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		value string
+		lat   float64
+		lng   float64
+	}{
+		{value: "45:30:00 N 005:15:00 E", lat: 45.5, lng: 5.25},
+		{value: "45:30.5N 005:15.5W", lat: 45.508333, lng: -5.258333},
+		{value: "33:52:12.5 S 151:12:36 E", lat: -33.870139, lng: 151.21},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ll, err := parseCoordinate(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(ll.Lat.Degrees()-tt.lat) > 1e-6 || math.Abs(ll.Lng.Degrees()-tt.lng) > 1e-6 {
+				t.Errorf("expected %v %v got %v", tt.lat, tt.lng, ll)
+			}
+		})
+	}
+}
+
+func TestParseRecordBeforeAirspace(t *testing.T) {
+	if _, err := Parse(strings.NewReader("AN NO CLASS\n")); err == nil {
+		t.Errorf("expected error for record before AC")
+	}
+}
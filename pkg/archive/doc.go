@@ -0,0 +1,34 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+/*
+Package archive provides an index and query engine for the flights stored by
+a crawl.
+
+The index keeps the metadata of each flight listed in the crawl day files,
+along with the stats computed from its track, in a single file under the
+crawl path.
+
+  PATH/index.json ( all indexed flights, sorted by date )
+  PATH/YEAR
+    /DD-MM-YYYY.json ( one json file per day with flight metadata )
+    /flights
+      /TRACKID ( one file with the flight track in the original format )
+
+Updates are incremental: only tracks which are new or changed since the last
+update are parsed again, unless the phase options used for the stats change.
+
+*/
+package archive
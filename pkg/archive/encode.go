@@ -0,0 +1,111 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// recordColumns are the columns in the table and csv formats.
+var recordColumns = []string{"Date", "Pilot", "Club", "Takeoff", "Region", "Glider",
+	"Distance", "Speed", "Points", "Duration", "MaxAltitude", "Thermals", "AvgClimb",
+	"TrackID"}
+
+// EncodeRecords returns the records in the given format.
+//
+// Supported formats are table, csv, json and yaml. Table and csv have one row
+// per flight with its metadata and main stats, which are empty for flights
+// without any.
+func EncodeRecords(records []Record, format string) ([]byte, error) {
+	switch format {
+	case "table":
+		return encodeRecordsTable(records)
+	case "csv":
+		return encodeRecordsCSV(records)
+	case "json":
+		return json.MarshalIndent(records, "", "  ")
+	case "yaml":
+		return yaml.Marshal(records)
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+func encodeRecordsTable(records []Record) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	row := func(values []string) {
+		for i, v := range values {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, v)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	row(recordColumns)
+	for _, r := range records {
+		f := r.Flight
+		values := []string{f.Date.Format("2006-01-02"), f.Pilot, f.Club, f.Takeoff,
+			f.Region, f.Glider, fmt.Sprintf("%.1f", f.Distance), fmt.Sprintf("%.1f", f.Speed),
+			fmt.Sprintf("%.1f", f.Points), "", "", "", "", f.TrackID}
+		if s := r.Stats; s != nil {
+			d := s.Duration.Round(time.Minute)
+			values[9] = fmt.Sprintf("%d:%02d", int(d.Hours()), int(d.Minutes())%60)
+			values[10] = fmt.Sprintf("%d", s.MaxAltitude)
+			values[11] = fmt.Sprintf("%d", s.Thermals)
+			values[12] = fmt.Sprintf("%.1f", s.AvgClimb)
+		}
+		row(values)
+	}
+	if err := w.Flush(); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeRecordsCSV(records []Record) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.Write(recordColumns); err != nil {
+		return []byte{}, err
+	}
+	for _, r := range records {
+		f := r.Flight
+		record := []string{f.Date.Format("2006-01-02"), f.Pilot, f.Club, f.Takeoff,
+			f.Region, f.Glider, fmt.Sprintf("%f", f.Distance), fmt.Sprintf("%f", f.Speed),
+			fmt.Sprintf("%f", f.Points), "", "", "", "", f.TrackID}
+		if s := r.Stats; s != nil {
+			record[9] = fmt.Sprintf("%.0f", s.Duration.Seconds())
+			record[10] = fmt.Sprintf("%d", s.MaxAltitude)
+			record[11] = fmt.Sprintf("%d", s.Thermals)
+			record[12] = fmt.Sprintf("%f", s.AvgClimb)
+		}
+		if err := w.Write(record); err != nil {
+			return []byte{}, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
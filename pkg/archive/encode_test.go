@@ -0,0 +1,80 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRecords(t *testing.T) {
+	dir := newTestArchive(t)
+	defer os.RemoveAll(dir)
+	index, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+	records := index.Query(Query{Pilot: "dupont"})
+
+	b, err := EncodeRecords(records, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 csv rows got %v", len(rows))
+	}
+	// stats are empty for flights without a track
+	if rows[1][0] != "2019-05-01" || rows[1][9] == "" || rows[2][9] != "" || rows[2][13] != "13" {
+		t.Errorf("unexpected csv rows %v", rows[1:])
+	}
+
+	b, err = EncodeRecords(records, "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "Date ") ||
+		!strings.HasPrefix(lines[1], "2019-05-01  Jean Dupont") {
+		t.Errorf("unexpected table\n%v", string(b))
+	}
+
+	b, err = EncodeRecords(records, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Record
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Flight.ID != "1" || decoded[0].Stats == nil {
+		t.Errorf("unexpected json records %+v", decoded)
+	}
+
+	if _, err := EncodeRecords(records, "xml"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
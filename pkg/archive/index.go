@@ -0,0 +1,249 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ezgliding/goigc/pkg/crawl"
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// IndexFile is the name of the index file under the archive path.
+const IndexFile = "index.json"
+
+// Record is an indexed flight, with the metadata given by the source and the
+// stats computed from its track.
+//
+// Track is the path of the track file relative to the archive, and Size and
+// ModTime those of the file when indexed to detect changes. Stats is nil for
+// flights without a track or with one which failed to parse, the reason
+// being kept in Error.
+type Record struct {
+	Flight  igc.Flight
+	Year    int
+	Track   string
+	Size    int64
+	ModTime time.Time
+	Stats   *igc.Stats `json:",omitempty" yaml:",omitempty"`
+	Error   string     `json:",omitempty" yaml:",omitempty"`
+}
+
+// key returns the unique identifier of the record in the index, from the
+// flight ID or the TrackID if not set.
+func (r *Record) key() string {
+	id := r.Flight.ID
+	if id == "" {
+		id = r.Flight.TrackID
+	}
+	return fmt.Sprintf("%v/%v", r.Year, id)
+}
+
+// UpdateOptions holds the settings to update an Index.
+//
+// Stats are computed with the Phases options, and recomputed for all flights
+// when they differ from the ones used in the previous update. Workers is the
+// number of tracks parsed concurrently.
+type UpdateOptions struct {
+	Phases  igc.PhaseOptions
+	Workers int
+}
+
+// DefaultUpdateOptions returns the UpdateOptions used by the query command
+// by default.
+func DefaultUpdateOptions() UpdateOptions {
+	return UpdateOptions{Phases: igc.DefaultPhaseOptions(), Workers: runtime.NumCPU()}
+}
+
+// Index holds all flights in an archive, sorted by date.
+type Index struct {
+	Options igc.PhaseOptions
+	Records []Record
+	path    string
+}
+
+// Open returns the index of the archive in the given path, or an empty one if
+// it was never indexed.
+func Open(path string) (*Index, error) {
+	index := &Index{Options: igc.DefaultPhaseOptions(), Records: []Record{}, path: path}
+	b, err := ioutil.ReadFile(filepath.Join(path, IndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, index); err != nil {
+		return nil, fmt.Errorf("%v :: %v", IndexFile, err)
+	}
+	return index, nil
+}
+
+// Update indexes all flights in the archive day files, computing the stats
+// of new or changed tracks, and saves the index.
+//
+// Tracks which fail to parse do not fail the update, with the error kept in
+// their Record instead. Flights without an ID or TrackID are not indexed.
+func (index *Index) Update(opts UpdateOptions) error {
+	flights, err := index.dayFlights()
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]Record, len(index.Records))
+	for _, r := range index.Records {
+		previous[r.key()] = r
+	}
+	changed := opts.Phases != index.Options
+
+	seen := make(map[string]int)
+	records := []Record{}
+	for _, r := range flights {
+		if r.Flight.TrackID != "" {
+			r.Track = filepath.Join(strconv.Itoa(r.Year), "flights", r.Flight.TrackID)
+			if info, err := os.Stat(filepath.Join(index.path, r.Track)); err == nil {
+				r.Size, r.ModTime = info.Size(), info.ModTime().UTC()
+			}
+		}
+		if p, ok := previous[r.key()]; ok && !changed && p.Size == r.Size && p.ModTime.Equal(r.ModTime) {
+			r.Stats, r.Error = p.Stats, p.Error
+		}
+		// flights listed more than once keep the last one
+		if i, ok := seen[r.key()]; ok {
+			records[i] = r
+			continue
+		}
+		seen[r.key()] = len(records)
+		records = append(records, r)
+	}
+	pending := []int{}
+	for i, r := range records {
+		if r.Size > 0 && r.Stats == nil && r.Error == "" {
+			pending = append(pending, i)
+		}
+	}
+
+	// errors are kept in the records
+	crawl.Parallel(len(pending), opts.Workers, func(i int) error {
+		r := &records[pending[i]]
+		stats, err := trackStats(filepath.Join(index.path, r.Track), opts.Phases)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"track": r.Track,
+				"error": err}).Error("Failed to compute track stats")
+			r.Error = err.Error()
+			return nil
+		}
+		r.Stats = &stats
+		return nil
+	})
+	log.WithFields(log.Fields{
+		"flights": len(records),
+		"updated": len(pending)}).Trace("Updated archive index")
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].Flight.Date.Equal(records[j].Flight.Date) {
+			return records[i].Flight.Date.Before(records[j].Flight.Date)
+		}
+		return records[i].key() < records[j].key()
+	})
+	index.Options, index.Records = opts.Phases, records
+	return index.Save()
+}
+
+// Save writes the index to a temporary file renamed to the final one, so that
+// a crash while writing does not corrupt it.
+func (index *Index) Save() error {
+	b, err := json.MarshalIndent(index, "", "   ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(index.path, IndexFile)
+	tmp := filepath.Join(index.path, "."+IndexFile+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// dayFlights returns the records for all flights in the day files of the
+// archive, one directory per year. Flights without an ID or TrackID are
+// skipped.
+func (index *Index) dayFlights() ([]Record, error) {
+	years, err := ioutil.ReadDir(index.path)
+	if err != nil {
+		return nil, err
+	}
+	result := []Record{}
+	for _, y := range years {
+		year, err := strconv.Atoi(y.Name())
+		if err != nil || !y.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(index.path, y.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if _, err := time.Parse("02-01-2006.json", f.Name()); err != nil {
+				continue
+			}
+			path := filepath.Join(index.path, y.Name(), f.Name())
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			var flights []igc.Flight
+			if err := json.Unmarshal(b, &flights); err != nil {
+				return nil, fmt.Errorf("%v :: %v", path, err)
+			}
+			for _, flight := range flights {
+				// without an id the flight can not be told apart from others
+				if flight.ID == "" && flight.TrackID == "" {
+					log.WithFields(log.Fields{
+						"file":  path,
+						"pilot": flight.Pilot}).Warn("Skipping flight without id")
+					continue
+				}
+				result = append(result, Record{Flight: flight, Year: year})
+			}
+		}
+	}
+	return result, nil
+}
+
+// trackStats returns the stats of the track in the given file, in any of the
+// supported formats.
+func trackStats(path string, opts igc.PhaseOptions) (igc.Stats, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return igc.Stats{}, err
+	}
+	trk, err := igc.ParseWithOptions(string(b), igc.ParseOptions{Lenient: true})
+	if _, ok := err.(igc.ParseErrors); err != nil && !ok {
+		return igc.Stats{}, err
+	}
+	return trk.StatsWithOptions(opts)
+}
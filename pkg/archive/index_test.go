@@ -0,0 +1,202 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+const trackFile = "../../testdata/phases/phases-short-flight-1.igc"
+
+// testFlights are the flights in the test archive, per day file.
+var testFlights = map[string][]igc.Flight{
+	"2019/01-05-2019.json": {
+		{ID: "1", Pilot: "Jean Dupont", Club: "AAVO", Date: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+			Takeoff: "Pontoise", Region: "Ile-de-France", Glider: "Discus 2b", Distance: 520.5,
+			Speed: 95.2, Points: 610, TrackID: "11"},
+		{ID: "2", Pilot: "Marie Martin", Club: "CVV Auvergne", Date: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+			Takeoff: "Issoire", Region: "Auvergne", Glider: "LS 4", Distance: 310,
+			Speed: 80.1, Points: 350, TrackID: "12"},
+	},
+	"2019/02-05-2019.json": {
+		{ID: "3", Pilot: "Jean Dupont", Club: "AAVO", Date: time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC),
+			Takeoff: "Pontoise", Region: "Ile-de-France", Glider: "ASW 27", Distance: 150,
+			Speed: 70, Points: 160, TrackID: "13"},
+		// skipped without an id
+		{Pilot: "Jean Dupont", Date: time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC)},
+	},
+	"2020/15-06-2020.json": {
+		{ID: "1", Pilot: "Paul Bernard", Club: "AAVO", Date: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+			Takeoff: "Pontoise", Region: "Ile-de-France", Glider: "Discus 2b", Distance: 640,
+			Speed: 102.4, Points: 700, TrackID: "21"},
+	},
+}
+
+// newTestArchive returns a crawl archive with the testFlights, a valid track
+// for flights 11 and 21, an invalid one for 12 and none for 13.
+func newTestArchive(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, flights := range testFlights {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name), "flights"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(flights)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	track, err := ioutil.ReadFile(trackFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"2019/flights/11":     track,
+		"2019/flights/12":     []byte("not a track"),
+		"2020/flights/21":     track,
+		"2019/manifest.json":  []byte("{}"),
+		"tmp/01-05-2019.json": []byte("not a day file"),
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIndexUpdate(t *testing.T) {
+	dir := newTestArchive(t)
+	defer os.RemoveAll(dir)
+
+	index, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Records) != 0 {
+		t.Fatalf("expected an empty index got %v records", len(index.Records))
+	}
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		key   string
+		stats bool
+		err   bool
+	}{
+		{"2019/1", true, false},
+		{"2019/2", false, true},
+		{"2019/3", false, false},
+		{"2020/1", true, false},
+	}
+	if len(index.Records) != len(expected) {
+		t.Fatalf("expected %v records got %v", len(expected), len(index.Records))
+	}
+	for i, e := range expected {
+		r := index.Records[i]
+		if r.key() != e.key || (r.Stats != nil) != e.stats || (r.Error != "") != e.err {
+			t.Errorf("expected %+v got %v with stats %v and error '%v'", e, r.key(), r.Stats, r.Error)
+		}
+	}
+	if s := index.Records[0].Stats; s.Duration == 0 || s.MaxAltitude == 0 {
+		t.Errorf("expected stats for the track got %+v", s)
+	}
+}
+
+func TestIndexUpdateIncremental(t *testing.T) {
+	dir := newTestArchive(t)
+	defer os.RemoveAll(dir)
+
+	index, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged tracks keep their stats, even if wrong
+	index.Records[0].Stats.Thermals = -1
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if index.Records[0].Stats.Thermals != -1 {
+		t.Errorf("expected unchanged track stats to be kept")
+	}
+
+	// new tracks are indexed
+	track, err := ioutil.ReadFile(trackFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "2019/flights/13"), track, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if index.Records[2].Stats == nil {
+		t.Errorf("expected stats for the new track")
+	}
+	if index.Records[0].Stats.Thermals != -1 {
+		t.Errorf("expected unchanged track stats to be kept")
+	}
+
+	// changed options recompute all stats
+	opts := DefaultUpdateOptions()
+	opts.Phases.Altitude = igc.AltitudePressure
+	if err := index.Update(opts); err != nil {
+		t.Fatal(err)
+	}
+	if index.Records[0].Stats.Thermals == -1 {
+		t.Errorf("expected stats recomputed with new options")
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, IndexFile), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err == nil {
+		t.Errorf("expected error opening an invalid index")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query holds the filters to select flights from an Index.
+//
+// Start and End are the first and last flight dates, inclusive. Text filters
+// match case insensitive substrings of the flight metadata. Distance (kms)
+// and Speed (km/h) are those given by the source, or the ones flown in the
+// track Stats for flights without them. Zero values disable the
+// corresponding filter.
+type Query struct {
+	Start       time.Time
+	End         time.Time
+	Pilot       string
+	Club        string
+	Takeoff     string
+	Region      string
+	Glider      string
+	MinDistance float64
+	MaxDistance float64
+	MinSpeed    float64
+	MaxSpeed    float64
+}
+
+// Query returns the records matching the given query, sorted by date.
+func (index *Index) Query(q Query) []Record {
+	from := 0
+	if !q.Start.IsZero() {
+		start := day(q.Start)
+		from = sort.Search(len(index.Records), func(i int) bool {
+			return !day(index.Records[i].Flight.Date).Before(start)
+		})
+	}
+	result := []Record{}
+	for _, r := range index.Records[from:] {
+		if !q.End.IsZero() && day(r.Flight.Date).After(day(q.End)) {
+			break
+		}
+		if q.Match(r) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Match checks if the record matches all filters in the query.
+func (q Query) Match(r Record) bool {
+	f := r.Flight
+	date := day(f.Date)
+	switch {
+	case !q.Start.IsZero() && date.Before(day(q.Start)):
+		return false
+	case !q.End.IsZero() && date.After(day(q.End)):
+		return false
+	case !contains(f.Pilot, q.Pilot), !contains(f.Club, q.Club),
+		!contains(f.Takeoff, q.Takeoff), !contains(f.Region, q.Region),
+		!contains(f.Glider, q.Glider):
+		return false
+	case q.MinDistance != 0 && r.distance() < q.MinDistance:
+		return false
+	case q.MaxDistance != 0 && r.distance() > q.MaxDistance:
+		return false
+	case q.MinSpeed != 0 && r.speed() < q.MinSpeed:
+		return false
+	case q.MaxSpeed != 0 && r.speed() > q.MaxSpeed:
+		return false
+	}
+	return true
+}
+
+// distance returns the flight distance given by the source, or the one
+// flown in the track if not set.
+func (r *Record) distance() float64 {
+	if r.Flight.Distance == 0 && r.Stats != nil {
+		return r.Stats.Distance
+	}
+	return r.Flight.Distance
+}
+
+// speed returns the flight speed given by the source, or the average one
+// flown in the track if not set.
+func (r *Record) speed() float64 {
+	if r.Flight.Speed == 0 && r.Stats != nil && r.Stats.Duration > 0 {
+		return r.Stats.Distance / r.Stats.Duration.Hours()
+	}
+	return r.Flight.Speed
+}
+
+// contains checks if substr is in s ignoring case, always true for an empty
+// substr.
+func contains(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// day returns the date of t, ignoring the time of the day.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,104 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package archive
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+var queryTests = []struct {
+	t        string
+	query    Query
+	expected []string
+}{
+	{"all", Query{}, []string{"2019/1", "2019/2", "2019/3", "2020/1"}},
+	{
+		"date-range",
+		Query{Start: time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC), End: time.Date(2020, 6, 15, 18, 0, 0, 0, time.UTC)},
+		[]string{"2019/3", "2020/1"},
+	},
+	{"start", Query{Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, []string{"2020/1"}},
+	{"end", Query{End: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)}, []string{"2019/1", "2019/2"}},
+	{"pilot", Query{Pilot: "dupont"}, []string{"2019/1", "2019/3"}},
+	{"club", Query{Club: "Auvergne"}, []string{"2019/2"}},
+	{"takeoff", Query{Takeoff: "pontoise"}, []string{"2019/1", "2019/3", "2020/1"}},
+	{"region", Query{Region: "ile-de"}, []string{"2019/1", "2019/3", "2020/1"}},
+	{"glider", Query{Glider: "discus"}, []string{"2019/1", "2020/1"}},
+	{"min-distance", Query{MinDistance: 500}, []string{"2019/1", "2020/1"}},
+	{"max-distance", Query{MaxDistance: 310}, []string{"2019/2", "2019/3"}},
+	{"speed", Query{MinSpeed: 75, MaxSpeed: 100}, []string{"2019/1", "2019/2"}},
+	{
+		"combined",
+		Query{Start: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+			Takeoff: "Pontoise", MinDistance: 500},
+		[]string{"2019/1"},
+	},
+	{"none", Query{Pilot: "nobody"}, []string{}},
+}
+
+func TestQueryMatchStats(t *testing.T) {
+	// flights without a source distance or speed use the track stats
+	r := Record{Flight: igc.Flight{ID: "1"},
+		Stats: &igc.Stats{Distance: 200, Duration: 2 * time.Hour}}
+	tests := []struct {
+		query    Query
+		expected bool
+	}{
+		{Query{MinDistance: 150}, true},
+		{Query{MaxDistance: 150}, false},
+		{Query{MinSpeed: 90, MaxSpeed: 110}, true},
+		{Query{MinSpeed: 110}, false},
+	}
+	for _, test := range tests {
+		if m := test.query.Match(r); m != test.expected {
+			t.Errorf("expected match %v for %+v got %v", test.expected, test.query, m)
+		}
+	}
+	// without stats the filters fail
+	if (Query{MinDistance: 150}).Match(Record{Flight: igc.Flight{ID: "1"}}) {
+		t.Errorf("expected no match for a flight without distance")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := newTestArchive(t)
+	defer os.RemoveAll(dir)
+	index, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Update(DefaultUpdateOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range queryTests {
+		t.Run(test.t, func(t *testing.T) {
+			result := []string{}
+			for _, r := range index.Query(test.query) {
+				result = append(result, r.key())
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError is returned for requests failing with an HTTP error status.
+//
+// RetryAfter is the delay requested by the server in the Retry-After
+// header, zero if not set.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%v :: http status %d", e.URL, e.StatusCode)
+}
+
+// NewHTTPError returns an HTTPError for the given url, status and headers.
+func NewHTTPError(url string, status int, header http.Header) *HTTPError {
+	e := &HTTPError{URL: url, StatusCode: status}
+	if header != nil {
+		e.RetryAfter = ParseRetryAfter(header.Get("Retry-After"), time.Now())
+	}
+	return e
+}
+
+// ParseRetryAfter returns the delay in a Retry-After header value, either in
+// seconds or an HTTP date relative to now. It returns zero for invalid or
+// past values.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(value); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	t, err := http.ParseTime(value)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
+
+// Retryable checks if a request failing with the given error should be
+// retried. HTTP errors are retried for status 420 (used by some servers
+// instead of 429), 429 and 5xx, and any other errors (like timeouts) always.
+func Retryable(err error) bool {
+	if e, ok := err.(*HTTPError); ok {
+		return e.StatusCode == 420 || e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode >= 500
+	}
+	return err != nil
+}
+
+// Backoff holds the settings for retrying failed requests.
+//
+// The delay starts at Initial and doubles with each attempt, unless the
+// server sends a Retry-After, and is never longer than Max. Attempts is the
+// max number of attempts, including the first one.
+type Backoff struct {
+	Initial  time.Duration
+	Max      time.Duration
+	Attempts int
+}
+
+// DefaultBackoff returns the Backoff used by DefaultOptions().
+func DefaultBackoff() Backoff {
+	return Backoff{Initial: time.Second, Max: time.Minute, Attempts: 5}
+}
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+// Retry calls fn until it succeeds, it fails with an error which is not
+// Retryable() or the max attempts are reached. It returns the number of
+// attempts and the last error.
+func (b Backoff) Retry(fn func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= b.Attempts || !Retryable(err) {
+			return attempt, err
+		}
+		sleep(b.Delay(attempt, err))
+	}
+}
+
+// Delay returns the time to wait after the given failed attempt.
+func (b Backoff) Delay(attempt int, err error) time.Duration {
+	d := b.Initial
+	if e, ok := err.(*HTTPError); ok && e.RetryAfter > 0 {
+		d = e.RetryAfter
+	} else {
+		for i := 1; i < attempt && d < b.Max; i++ {
+			d *= 2
+		}
+	}
+	if b.Max > 0 && d > b.Max {
+		return b.Max
+	}
+	return d
+}
@@ -0,0 +1,120 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package crawl
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "", expected: 0},
+		{value: "120", expected: 2 * time.Minute},
+		{value: "-1", expected: 0},
+		{value: "Wed, 01 Jan 2020 12:00:30 GMT", expected: 30 * time.Second},
+		{value: "Wed, 01 Jan 2020 11:00:00 GMT", expected: 0},
+		{value: "soon", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if d := ParseRetryAfter(tt.value, now); d != tt.expected {
+				t.Errorf("expected %v got %v", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "nil", err: nil, retryable: false},
+		{name: "network", err: errors.New("timeout"), retryable: true},
+		{name: "enhance-your-calm", err: &HTTPError{StatusCode: 420}, retryable: true},
+		{name: "too-many-requests", err: &HTTPError{StatusCode: 429}, retryable: true},
+		{name: "server", err: &HTTPError{StatusCode: 503}, retryable: true},
+		{name: "not-found", err: &HTTPError{StatusCode: 404}, retryable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r := Retryable(tt.err); r != tt.retryable {
+				t.Errorf("expected retryable %v got %v", tt.retryable, r)
+			}
+		})
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	var delays []time.Duration
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { sleep = time.Sleep }()
+
+	b := Backoff{Initial: time.Second, Max: 3 * time.Second, Attempts: 5}
+	header := http.Header{}
+	header.Set("Retry-After", "10")
+	errs := []error{
+		errors.New("timeout"),
+		errors.New("timeout"),
+		NewHTTPError("http://test", 429, header),
+		errors.New("timeout"),
+	}
+	attempts, err := b.Retry(func() error {
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	})
+	if err != nil || attempts != 5 {
+		t.Errorf("expected success after 5 attempts got %v %v", attempts, err)
+	}
+	// the retry after is capped at the max delay
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	if len(delays) != len(expected) {
+		t.Fatalf("expected delays %v got %v", expected, delays)
+	}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("expected delays %v got %v", expected, delays)
+			break
+		}
+	}
+}
+
+func TestBackoffRetryLimits(t *testing.T) {
+	sleep = func(d time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	b := Backoff{Initial: time.Second, Max: time.Minute, Attempts: 3}
+	attempts, err := b.Retry(func() error { return errors.New("timeout") })
+	if err == nil || attempts != 3 {
+		t.Errorf("expected failure after 3 attempts got %v %v", attempts, err)
+	}
+	attempts, err = b.Retry(func() error { return &HTTPError{StatusCode: 404} })
+	if err == nil || attempts != 1 {
+		t.Errorf("expected no retries for 404 got %v %v", attempts, err)
+	}
+}
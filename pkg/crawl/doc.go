@@ -0,0 +1,32 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+/*
+Package crawl provides a concurrent and resumable pipeline to download flights
+from online competitions.
+
+Days are listed with an igc.Crawler and tracks downloaded by a pool of
+workers, sharing a token bucket rate limiter. Failed requests are retried
+with exponential backoff, honouring any Retry-After from the server.
+
+The status of each track download is kept in a per year manifest, allowing
+an interrupted crawl to be resumed by retrying only failed or missing
+downloads.
+
+Sources are registered by name with Register(), usually in the init() of
+the package implementing them, and created with NewSource().
+
+*/
+package crawl
@@ -0,0 +1,185 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package crawl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the download status of a track.
+type Status string
+
+// Track download statuses.
+const (
+	Pending Status = "pending"
+	Done    Status = "done"
+	Failed  Status = "failed"
+)
+
+// ManifestFile is the name of the manifest file under each year directory.
+const ManifestFile = "manifest.json"
+
+// ManifestSaveInterval is the min time between two saves of the manifest on
+// updates.
+const ManifestSaveInterval = 10 * time.Second
+
+// Entry holds the download status of a single track.
+//
+// Checksum is the hex encoded sha256 of the downloaded track, and Error the
+// last error for failed downloads.
+type Entry struct {
+	TrackID  string
+	URL      string
+	Date     time.Time
+	Status   Status
+	Attempts int
+	Checksum string `json:",omitempty"`
+	Error    string `json:",omitempty"`
+	Updated  time.Time
+}
+
+// Manifest holds the download status of all tracks in a year, keyed by
+// TrackID.
+//
+// It is safe for concurrent use, and saved to its file at most once every
+// ManifestSaveInterval on updates so that an interrupted crawl leaves a
+// record of the pending downloads. Call Save() to write the last updates.
+type Manifest struct {
+	Year    int
+	Entries map[string]Entry
+	path    string
+	saved   time.Time
+	mu      sync.Mutex
+}
+
+// LoadManifest returns the manifest in the given file, or an empty one for
+// the given year if the file does not exist.
+func LoadManifest(path string, year int) (*Manifest, error) {
+	m := &Manifest{Year: year, Entries: make(map[string]Entry), path: path}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]Entry)
+	}
+	return m, nil
+}
+
+// Get returns the entry for the given track.
+func (m *Manifest) Get(trackID string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.Entries[trackID]
+	return e, ok
+}
+
+// Update sets the entry for its track, and saves the manifest if it was not
+// saved in the last ManifestSaveInterval.
+func (m *Manifest) Update(e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.Updated = time.Now().UTC()
+	m.Entries[e.TrackID] = e
+	if e.Updated.Sub(m.saved) < ManifestSaveInterval {
+		return nil
+	}
+	return m.save()
+}
+
+// Add sets the entries for their tracks and saves the manifest once.
+func (m *Manifest) Add(entries []Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for _, e := range entries {
+		e.Updated = now
+		m.Entries[e.TrackID] = e
+	}
+	return m.save()
+}
+
+// Save writes the manifest to its file.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
+// save writes the manifest to a temporary file renamed to the final one, so
+// that a crash while writing does not corrupt it.
+func (m *Manifest) save() error {
+	b, err := json.MarshalIndent(m, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(m.path), "."+filepath.Base(m.path)+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return err
+	}
+	m.saved = time.Now().UTC()
+	return nil
+}
+
+// Unfinished returns the entries not Done, sorted by TrackID.
+func (m *Manifest) Unfinished() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []Entry{}
+	for _, e := range m.Entries {
+		if e.Status != Done {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].TrackID < result[j].TrackID })
+	return result
+}
+
+// Verify checks if the track was downloaded to the given file, with a
+// matching checksum.
+func (e *Entry) Verify(path string) bool {
+	if e.Status != Done {
+		return false
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return checksum(b) == e.Checksum
+}
+
+// checksum returns the hex encoded sha256 of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
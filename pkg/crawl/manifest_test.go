@@ -0,0 +1,124 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package crawl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goigc-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ManifestFile)
+	m, err := LoadManifest(path, 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 0 {
+		t.Errorf("expected empty manifest got %+v", m.Entries)
+	}
+	data := []byte("track")
+	if err := ioutil.WriteFile(filepath.Join(dir, "1"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Update(Entry{TrackID: "1", Status: Done, Attempts: 1, Checksum: checksum(data)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Update(Entry{TrackID: "2", Status: Failed, Attempts: 3, Error: "timeout"}); err != nil {
+		t.Fatal(err)
+	}
+	// the second update is saved later
+	loaded, err := LoadManifest(path, 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 1 {
+		t.Fatalf("expected 1 saved entry got %+v", loaded.Entries)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err = LoadManifest(path, 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Year != 2019 || len(loaded.Entries) != 2 {
+		t.Fatalf("expected 2 entries for 2019 got %v %+v", loaded.Year, loaded.Entries)
+	}
+	e, ok := loaded.Get("1")
+	if !ok || !e.Verify(filepath.Join(dir, "1")) {
+		t.Errorf("expected track 1 verified got %+v", e)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "1"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if e.Verify(filepath.Join(dir, "1")) {
+		t.Errorf("expected track 1 with changed checksum not verified")
+	}
+	if u := loaded.Unfinished(); len(u) != 1 || u[0].TrackID != "2" || u[0].Attempts != 3 {
+		t.Errorf("expected track 2 unfinished got %+v", u)
+	}
+}
+
+func TestManifestAdd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goigc-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ManifestFile)
+	m, err := LoadManifest(path, 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add([]Entry{{TrackID: "1", Status: Pending}, {TrackID: "2", Status: Pending}}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadManifest(path, 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 2 || len(loaded.Unfinished()) != 2 {
+		t.Fatalf("expected 2 pending entries got %+v", loaded.Entries)
+	}
+	if e, _ := loaded.Get("1"); e.Updated.IsZero() {
+		t.Errorf("expected update time set got %+v", e)
+	}
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "goigc-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("{invalid"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := LoadManifest(f.Name(), 2019); err == nil {
+		t.Errorf("expected error loading invalid manifest")
+	}
+}
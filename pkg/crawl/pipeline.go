@@ -0,0 +1,316 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package crawl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// Source is an online competition to crawl flights from, able to list the
+// flights in a date range and download their tracks.
+type Source interface {
+	igc.Crawler
+	Get(url string) ([]byte, error)
+}
+
+// Options holds the settings of a crawl.
+//
+// Workers is the number of concurrent calls to the source. Limiter is the
+// rate limiter shared with the source, which waits on it before each http
+// request. Without it each call to the source is limited instead, with a
+// TokenBucket of Rate (per second) and Burst shared by all workers.
+//
+// Days already listed in a day file are never crawled again, and skipped
+// unless Resume is set, which retries only their failed or missing track
+// downloads.
+type Options struct {
+	Workers int
+	Rate    float64
+	Burst   int
+	Limiter *TokenBucket
+	Backoff Backoff
+	Resume  bool
+}
+
+// DefaultOptions returns the Options used by the crawl command by default.
+func DefaultOptions() Options {
+	return Options{Workers: 4, Rate: 1, Burst: 1, Backoff: DefaultBackoff()}
+}
+
+// Run crawls the source for flights between start and end, which must be in
+// the same year, storing the results under path with the structure below.
+//
+//   PATH/YEAR
+//     /DD-MM-YYYY.json ( one json file per day with flight metadata )
+//     /manifest.json ( the status of each track download )
+//     /flights
+//       /TRACKID ( one file with the flight track in the original format )
+//
+// Tracks already downloaded with a matching checksum are never downloaded
+// again. Failures are recorded in the manifest and do not stop the crawl,
+// with an error summarizing them returned at the end.
+func Run(source Source, start time.Time, end time.Time, path string, opts Options) error {
+	if end.Before(start) {
+		return fmt.Errorf("invalid start end date pair")
+	}
+	if start.Year() != end.Year() {
+		return fmt.Errorf("start and end year must be the same")
+	}
+	dir := filepath.Join(path, strconv.Itoa(start.Year()))
+	if err := os.MkdirAll(filepath.Join(dir, "flights"), os.ModePerm); err != nil {
+		return err
+	}
+	manifest, err := LoadManifest(filepath.Join(dir, ManifestFile), start.Year())
+	if err != nil {
+		return err
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	p := &pipeline{source: source, dir: dir, opts: opts, manifest: manifest,
+		limiter: NewTokenBucket(opts.Rate, opts.Burst)}
+	if opts.Limiter != nil {
+		// the source waits on each request
+		p.limiter = NewTokenBucket(0, 1)
+	}
+
+	var days []time.Time
+	current := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, time.UTC)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 12, 0, 0, 0, time.UTC)
+	for ; !current.After(end); current = current.AddDate(0, 0, 1) {
+		days = append(days, current)
+	}
+	flights, dayErrs := p.crawlDays(days)
+	entries, err := p.pendingTracks(flights)
+	if err != nil {
+		return err
+	}
+	trackErrs := p.downloadTracks(entries)
+	if err := manifest.Save(); err != nil {
+		return err
+	}
+
+	if len(dayErrs) > 0 || len(trackErrs) > 0 {
+		first := append(dayErrs, trackErrs...)[0]
+		return fmt.Errorf("%d of %d days and %d of %d tracks failed :: %v",
+			len(dayErrs), len(days), len(trackErrs), len(entries), first)
+	}
+	return nil
+}
+
+// pipeline holds the state shared by the crawl workers.
+type pipeline struct {
+	source   Source
+	dir      string
+	opts     Options
+	limiter  *TokenBucket
+	manifest *Manifest
+}
+
+// parallel calls fn for each index up to n using the configured number of
+// workers, returning the errors.
+func (p *pipeline) parallel(n int, fn func(i int) error) []error {
+	return Parallel(n, p.opts.Workers, fn)
+}
+
+// Parallel calls fn for each index up to n using the given number of
+// workers, at least one, returning the errors.
+func Parallel(n int, workers int, fn func(i int) error) []error {
+	if workers < 1 {
+		workers = 1
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := []error{}
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+// request calls fn with rate limiting and retries, returning the number of
+// attempts.
+func (p *pipeline) request(fn func() error) (int, error) {
+	return p.opts.Backoff.Retry(func() error {
+		p.limiter.Wait()
+		return fn()
+	})
+}
+
+// crawlDays returns the flights in the given days, listing them from the
+// source, or reading the existing day files when resuming.
+func (p *pipeline) crawlDays(days []time.Time) ([]igc.Flight, []error) {
+	var mu sync.Mutex
+	var result []igc.Flight
+	errs := p.parallel(len(days), func(i int) error {
+		day := days[i]
+		dbFile := filepath.Join(p.dir, fmt.Sprintf("%v.json", day.Format("02-01-2006")))
+		var flights []igc.Flight
+		if _, err := os.Stat(dbFile); err == nil && !p.opts.Resume {
+			return nil
+		} else if err == nil {
+			b, err := ioutil.ReadFile(dbFile)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(b, &flights); err != nil {
+				return fmt.Errorf("%v :: %v", dbFile, err)
+			}
+		} else {
+			attempts, err := p.request(func() error {
+				var err error
+				flights, err = p.source.Crawl(day, day)
+				return err
+			})
+			if err != nil {
+				log.WithFields(log.Fields{
+					"day":      day,
+					"attempts": attempts,
+					"error":    err}).Error("Failed to crawl day")
+				return fmt.Errorf("%v :: %v", day.Format("2006-01-02"), err)
+			}
+			b, err := json.MarshalIndent(flights, "", "   ")
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(dbFile, b, 0644); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		result = append(result, flights...)
+		mu.Unlock()
+		return nil
+	})
+	return result, errs
+}
+
+// pendingTracks returns the manifest entries of the tracks to download,
+// adding the new flights to the manifest as Pending.
+func (p *pipeline) pendingTracks(flights []igc.Flight) ([]Entry, error) {
+	var result []Entry
+	var added []Entry
+	seen := make(map[string]bool)
+	for _, f := range flights {
+		if f.TrackID == "" || f.TrackURL == "" {
+			continue
+		}
+		e, ok := p.manifest.Get(f.TrackID)
+		if !ok {
+			e = Entry{TrackID: f.TrackID, URL: f.TrackURL, Date: f.Date, Status: Pending}
+			added = append(added, e)
+		}
+		file, err := p.trackFile(e.TrackID)
+		if !seen[e.TrackID] && (err != nil || !e.Verify(file)) {
+			result = append(result, e)
+		}
+		seen[e.TrackID] = true
+	}
+	// saved once, not to rewrite the whole manifest for each new track
+	if err := p.manifest.Add(added); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// downloadTracks downloads the tracks of the given entries, updating their
+// status in the manifest.
+func (p *pipeline) downloadTracks(entries []Entry) []error {
+	return p.parallel(len(entries), func(i int) error {
+		e := entries[i]
+		var data []byte
+		file, err := p.trackFile(e.TrackID)
+		if err == nil {
+			var attempts int
+			attempts, err = p.request(func() error {
+				var err error
+				data, err = p.source.Get(e.URL)
+				if err == nil && len(data) == 0 {
+					err = fmt.Errorf("%v :: empty track", e.URL)
+				}
+				return err
+			})
+			e.Attempts += attempts
+		}
+		if err == nil {
+			err = ioutil.WriteFile(file, data, 0644)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"track_id": e.TrackID,
+				"attempts": e.Attempts,
+				"error":    err}).Error("Failed to download track")
+			e.Status, e.Error = Failed, err.Error()
+		} else {
+			e.Status, e.Error, e.Checksum = Done, "", checksum(data)
+		}
+		if uerr := p.manifest.Update(e); uerr != nil {
+			return uerr
+		}
+		if err != nil {
+			return fmt.Errorf("%v :: %v", e.TrackID, err)
+		}
+		return nil
+	})
+}
+
+// trackFile returns the path of the track file, failing for ids which are
+// not a plain file name under the flights directory.
+func (p *pipeline) trackFile(trackID string) (string, error) {
+	if err := CheckTrackID(trackID); err != nil {
+		return "", err
+	}
+	return filepath.Join(p.dir, "flights", trackID), nil
+}
+
+// CheckTrackID returns an error if the track id is not a valid file name,
+// like ids with path separators which would escape the crawl directory.
+func CheckTrackID(trackID string) error {
+	if trackID == "" || trackID == "." || strings.Contains(trackID, "..") ||
+		strings.ContainsAny(trackID, `/\`) {
+		return fmt.Errorf("invalid track id '%v'", trackID)
+	}
+	return nil
+}
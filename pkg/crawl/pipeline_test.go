@@ -0,0 +1,230 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// fakeSource lists two flights per day, failing the downloads of the tracks
+// in failures the given number of times.
+type fakeSource struct {
+	mu       sync.Mutex
+	days     int
+	gets     map[string]int
+	failures map[string]int
+}
+
+func (s *fakeSource) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
+	s.mu.Lock()
+	s.days++
+	s.mu.Unlock()
+	var flights []igc.Flight
+	for i := 0; i < 2; i++ {
+		id := fmt.Sprintf("%v-%d", start.Format("0102"), i)
+		flights = append(flights, igc.Flight{ID: id, Date: start, TrackID: id,
+			TrackURL: "http://test/" + id})
+	}
+	return flights, nil
+}
+
+func (s *fakeSource) Get(url string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets[url]++
+	if s.failures[url] > 0 {
+		s.failures[url]--
+		return nil, &HTTPError{URL: url, StatusCode: 503}
+	}
+	return []byte("track " + url), nil
+}
+
+func TestRun(t *testing.T) {
+	sleep = func(d time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	dir, err := ioutil.TempDir("", "goigc-crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := &fakeSource{gets: make(map[string]int), failures: map[string]int{
+		"http://test/0102-0": 1, "http://test/0103-1": 4}}
+	opts := Options{Workers: 3, Backoff: Backoff{Initial: time.Second, Attempts: 3}}
+	start := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	// one track failing more than the max attempts
+	err = Run(source, start, end, dir, opts)
+	if err == nil {
+		t.Fatalf("expected error for failed track")
+	}
+	if source.days != 3 {
+		t.Errorf("expected 3 days crawled got %v", source.days)
+	}
+	m, err := LoadManifest(filepath.Join(dir, "2019", ManifestFile), 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 6 {
+		t.Fatalf("expected 6 tracks in manifest got %v", len(m.Entries))
+	}
+	if e, _ := m.Get("0102-0"); e.Status != Done || e.Attempts != 2 {
+		t.Errorf("expected track 0102-0 done after 2 attempts got %+v", e)
+	}
+	if e, _ := m.Get("0103-1"); e.Status != Failed || e.Attempts != 3 || e.Error == "" {
+		t.Errorf("expected track 0103-1 failed after 3 attempts got %+v", e)
+	}
+	for _, f := range []string{"02-01-2019.json", "03-01-2019.json", "04-01-2019.json", "flights/0104-1"} {
+		if _, err := os.Stat(filepath.Join(dir, "2019", f)); err != nil {
+			t.Errorf("expected file %v :: %v", f, err)
+		}
+	}
+
+	// days already listed are skipped without resume
+	source.days, source.gets = 0, make(map[string]int)
+	if err := Run(source, start, end, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if source.days != 0 || len(source.gets) != 0 {
+		t.Errorf("expected no days crawled or tracks downloaded got %v %v", source.days, source.gets)
+	}
+
+	// resume retries only the failed tracks of the given days
+	opts.Resume = true
+	if err := Run(source, start, start, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.gets) != 0 {
+		t.Errorf("expected no downloads for day without failures got %v", source.gets)
+	}
+	if err := Run(source, start, end, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if source.days != 0 {
+		t.Errorf("expected no days crawled on resume got %v", source.days)
+	}
+	if len(source.gets) != 1 || source.gets["http://test/0103-1"] != 2 {
+		t.Errorf("expected only track 0103-1 downloaded got %v", source.gets)
+	}
+	m, err = LoadManifest(filepath.Join(dir, "2019", ManifestFile), 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := m.Get("0103-1"); e.Status != Done || e.Attempts != 5 || e.Checksum == "" {
+		t.Errorf("expected track 0103-1 done after 5 attempts got %+v", e)
+	}
+
+	// a missing track file is downloaded again on resume
+	if err := os.Remove(filepath.Join(dir, "2019", "flights", "0102-1")); err != nil {
+		t.Fatal(err)
+	}
+	source.gets = make(map[string]int)
+	if err := Run(source, start, end, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if source.days != 0 {
+		t.Errorf("expected no days crawled with existing day files got %v", source.days)
+	}
+	if len(source.gets) != 1 || source.gets["http://test/0102-1"] != 1 {
+		t.Errorf("expected only track 0102-1 downloaded got %v", source.gets)
+	}
+}
+
+// failingSource fails listing flights.
+type failingSource struct{}
+
+func (s failingSource) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
+	return nil, &HTTPError{URL: "http://test", StatusCode: 404}
+}
+
+func (s failingSource) Get(url string) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestRunErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goigc-crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := Run(failingSource{}, start, start, dir, DefaultOptions()); err == nil {
+		t.Errorf("expected error for failed day")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2019", "02-01-2019.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no day file for failed day")
+	}
+	if err := Run(failingSource{}, start, start.AddDate(1, 0, 0), dir, DefaultOptions()); err == nil {
+		t.Errorf("expected error for different start and end years")
+	}
+	if err := Run(failingSource{}, start, start.AddDate(0, 0, -1), dir, DefaultOptions()); err == nil {
+		t.Errorf("expected error for end before start")
+	}
+}
+
+// traversalSource lists a flight with a track id escaping the crawl directory.
+type traversalSource struct {
+	fakeSource
+}
+
+func (s *traversalSource) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
+	return []igc.Flight{{ID: "1", Date: start, TrackID: "../../x", TrackURL: "http://test/x"}}, nil
+}
+
+func TestRunInvalidTrackID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goigc-crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := &traversalSource{fakeSource{gets: make(map[string]int)}}
+	start := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	err = Run(source, start, start, filepath.Join(dir, "crawl"), DefaultOptions())
+	if err == nil || !strings.Contains(err.Error(), "invalid track id") {
+		t.Errorf("expected invalid track id error got %v", err)
+	}
+	if len(source.gets) != 0 {
+		t.Errorf("expected no download for invalid track id got %v", source.gets)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x")); !os.IsNotExist(err) {
+		t.Errorf("expected no file outside the crawl directory")
+	}
+}
+
+func TestCheckTrackID(t *testing.T) {
+	tests := map[string]bool{
+		"987": true, "track.igc": true, "": false, ".": false, "..": false,
+		"../../x": false, "a/b": false, `a\b`: false, "a..b": false,
+	}
+	for id, valid := range tests {
+		if err := CheckTrackID(id); (err == nil) != valid {
+			t.Errorf("expected valid %v for '%v' got %v", valid, id, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package crawl
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenBucket is a rate limiter allowing a given number of requests per
+// second, with bursts of up to burst requests.
+//
+// It is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// NewTokenBucket returns a new TokenBucket with the given rate in requests
+// per second and burst size, starting full. A rate of zero or less disables
+// the limit.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	b := &TokenBucket{rate: rate, burst: float64(burst), now: time.Now, sleep: time.Sleep}
+	b.tokens, b.last = b.burst, b.now()
+	return b
+}
+
+// Wait blocks until a request is allowed by the rate limit.
+func (b *TokenBucket) Wait() {
+	if b.rate <= 0 {
+		return
+	}
+	b.mu.Lock()
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// reserve the token even if not yet available, so concurrent callers
+	// queue one after the other
+	b.tokens--
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if wait > 0 {
+		b.sleep(wait)
+	}
+}
+
+// Transport is an http.RoundTripper waiting on the Limiter before each
+// request, sending it with Base or http.DefaultTransport if nil.
+//
+// Sources making several requests per call use it to apply the rate limit
+// to each of them.
+type Transport struct {
+	Limiter *TokenBucket
+	Base    http.RoundTripper
+}
+
+// RoundTrip waits for the rate limit and sends the request.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Limiter.Wait()
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,92 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package crawl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced only by sleeping.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		requests int
+		elapsed  time.Duration
+	}{
+		{name: "burst", rate: 1, burst: 5, requests: 5, elapsed: 0},
+		{name: "rate", rate: 2, burst: 1, requests: 5, elapsed: 2 * time.Second},
+		{name: "burst-then-rate", rate: 1, burst: 3, requests: 5, elapsed: 2 * time.Second},
+		{name: "unlimited", rate: 0, burst: 1, requests: 100, elapsed: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+			b := NewTokenBucket(tt.rate, tt.burst)
+			b.now, b.sleep, b.last = clock.Now, clock.Sleep, clock.Now()
+			start := clock.Now()
+			for i := 0; i < tt.requests; i++ {
+				b.Wait()
+			}
+			if elapsed := clock.Now().Sub(start); elapsed != tt.elapsed {
+				t.Errorf("expected %v elapsed got %v", tt.elapsed, elapsed)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := NewTokenBucket(1, 1)
+	b.now, b.sleep, b.last = clock.Now, clock.Sleep, clock.Now()
+	client := &http.Client{Transport: &Transport{Limiter: b}}
+	start := clock.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 2*time.Second {
+		t.Errorf("expected 2s elapsed for 3 requests got %v", elapsed)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package crawl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SourceOptions holds the settings used to create a Source.
+//
+// Year selects the archive to crawl for sources keeping one per year, zero
+// being the current one. BaseURL overrides the default location of the
+// source, and is required by sources without one. RecordDir asks the
+// source to save all responses there as test fixtures, for sources
+// supporting it. Limiter, if set, must be waited on before each http request
+// (see Transport).
+type SourceOptions struct {
+	Year      int
+	BaseURL   string
+	RecordDir string
+	Limiter   *TokenBucket
+}
+
+// SourceFactory creates a Source with the given options.
+type SourceFactory func(opts SourceOptions) (Source, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]SourceFactory)
+)
+
+// Register makes a Source available with the given name, usually called
+// from the init() of the package implementing it.
+//
+// Registering a Source with an existing name replaces the previous one.
+func Register(name string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[name] = factory
+}
+
+// Sources returns the sorted names of all registered sources.
+func Sources() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewSource returns the Source registered with the given name.
+func NewSource(name string, opts SourceOptions) (Source, error) {
+	sourcesMu.RLock()
+	factory, ok := sources[name]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source '%v', available sources are: %v",
+			name, strings.Join(Sources(), ", "))
+	}
+	return factory(opts)
+}
@@ -0,0 +1,54 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package crawl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	Register("test-fake", func(opts SourceOptions) (Source, error) {
+		if opts.BaseURL == "" {
+			return nil, fmt.Errorf("base url required")
+		}
+		return &fakeSource{}, nil
+	})
+	defer func() {
+		sourcesMu.Lock()
+		delete(sources, "test-fake")
+		sourcesMu.Unlock()
+	}()
+
+	found := false
+	for _, name := range Sources() {
+		found = found || name == "test-fake"
+	}
+	if !found {
+		t.Errorf("expected test-fake in sources got %v", Sources())
+	}
+	if _, err := NewSource("test-fake", SourceOptions{BaseURL: "http://test"}); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewSource("test-fake", SourceOptions{}); err == nil {
+		t.Errorf("expected error from source factory")
+	}
+	_, err := NewSource("unknown", SourceOptions{})
+	if err == nil || !strings.Contains(err.Error(), "test-fake") {
+		t.Errorf("expected error listing available sources got %v", err)
+	}
+}
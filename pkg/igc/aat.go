@@ -0,0 +1,266 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Area is an assigned area of an AAT (Assigned Area Task).
+//
+// The area is the part of the circle with the given Radius (in kms) around
+// Center, outside InnerRadius, and between StartRadial and EndRadial (true
+// bearings in degrees, clockwise). Equal radials mean a full circle.
+type Area struct {
+	Center      Point
+	Radius      float64
+	InnerRadius float64
+	StartRadial float64
+	EndRadial   float64
+}
+
+// Contains returns true if the given point is inside the area.
+func (a *Area) Contains(p Point) bool {
+	d := a.Center.Distance(p)
+	if d > a.Radius || d < a.InnerRadius {
+		return false
+	}
+	if a.StartRadial == a.EndRadial || d == 0 {
+		return true
+	}
+	b := math.Mod(a.Center.Bearing(p).Degrees()-a.StartRadial+720, 360)
+	return b <= math.Mod(a.EndRadial-a.StartRadial+720, 360)
+}
+
+// AAT is an Assigned Area Task, as defined in the FAI Sporting Code Section 3
+// Annex A.
+//
+// Pilots choose where to turn inside each of the Areas, and must fly for at
+// least MinTime.
+type AAT struct {
+	Start      Point
+	StartZone  Zone
+	Areas      []Area
+	Finish     Point
+	FinishZone Zone
+	MinTime    time.Duration
+}
+
+// AATAchievement holds the result of flying an AAT.
+//
+// Task has the scoring points chosen in each area as Turnpoints. Distance is
+// the marking distance from Start to Finish through the scoring points, with
+// the radius of a start or finish cylinder deducted. MarkingTime is the task
+// time, or MinTime if longer, and Speed is the marking speed in km/h (only
+// set for completed tasks).
+//
+// For tasks not completed, Task and Distance include only the areas achieved.
+type AATAchievement struct {
+	Completed   bool
+	Areas       int
+	Start       time.Time
+	Finish      time.Time
+	Task        Task
+	Distance    float64
+	Duration    time.Duration
+	MarkingTime time.Duration
+	Speed       float64
+	Legs        []LegAchievement
+}
+
+// aatFlight holds the start and finish crossings and the candidate scoring
+// points for each area achieved.
+type aatFlight struct {
+	start     Point
+	finish    Point
+	started   bool
+	completed bool
+	// points is Start, all fixes between the start and finish crossings, and
+	// Finish, with layers holding the indices in points of each area fix
+	points []Point
+	layers [][]int
+}
+
+// flown returns how the task was flown in the given track.
+//
+// The start is the last exit of the start zone before reaching the first
+// area, and the finish the first entry of the finish zone after reaching all
+// areas.
+func (task *AAT) flown(track Track) aatFlight {
+	centers := []Point{task.Start}
+	zones := []Zone{task.StartZone}
+	for _, a := range task.Areas {
+		centers = append(centers, a.Center)
+		zones = append(zones, NewCylinderZone(a.Radius))
+	}
+	centers = append(centers, task.Finish)
+	zones = append(zones, task.FinishZone)
+	checks := zoneChecks(centers, zones)
+	start, finish := checks[0], checks[len(checks)-1]
+
+	f := aatFlight{}
+	first, last, next := 0, len(track.Points), 0
+	for i := 1; i < len(track.Points); i++ {
+		a, b := track.Points[i-1], track.Points[i]
+		if next == 0 {
+			if p, ok := start.exit(a, b); ok {
+				f.start, f.started, first = p, true, i
+			}
+		}
+		if !f.started {
+			continue
+		}
+		if next < len(task.Areas) && task.Areas[next].Contains(b) {
+			next++
+			continue
+		}
+		if next == len(task.Areas) {
+			if p, ok := finish.entry(a, b); ok {
+				f.finish, f.completed, last = p, true, i
+				break
+			}
+		}
+	}
+	if !f.started {
+		return f
+	}
+
+	f.points = []Point{task.Start}
+	f.points = append(f.points, track.Points[first:last]...)
+	f.layers = [][]int{{0}}
+	for i := 0; i < next; i++ {
+		layer := []int{}
+		for j := 1; j < len(f.points); j++ {
+			if task.Areas[i].Contains(f.points[j]) {
+				layer = append(layer, j)
+			}
+		}
+		f.layers = append(f.layers, layer)
+	}
+	if f.completed {
+		f.points = append(f.points, task.Finish)
+		f.layers = append(f.layers, []int{len(f.points) - 1})
+	}
+	return f
+}
+
+// NewAATOptimizer returns an Optimizer choosing the scoring points of the
+// given AAT.
+//
+// The number of turnpoints must match the number of areas, and the task must
+// have been completed in the track. The returned Task has the AAT Start and
+// Finish, and the scoring points as Turnpoints.
+func NewAATOptimizer(task AAT) Optimizer {
+	return &aatOptimizer{task: task}
+}
+
+type aatOptimizer struct {
+	task AAT
+}
+
+func (o *aatOptimizer) Optimize(track Track, nPoints int, score Score) (Task, error) {
+	if nPoints != len(o.task.Areas) {
+		return Task{}, fmt.Errorf("%v turn points not supported for a task with %v areas",
+			nPoints, len(o.task.Areas))
+	}
+	f := o.task.flown(track)
+	if !f.completed {
+		return Task{}, fmt.Errorf("task not completed")
+	}
+	task, _ := f.task(score)
+	return task, nil
+}
+
+// task returns the Task through the best scoring points for the given score.
+func (f *aatFlight) task(score Score) (Task, []int) {
+	path, _ := dpPath(f.points, f.layers, score)
+	task := Task{Start: f.points[0], Turnpoints: []Point{}}
+	if path == nil {
+		return task, nil
+	}
+	last := len(path)
+	if f.completed {
+		last = len(path) - 1
+		task.Finish = f.points[path[last]]
+	}
+	for _, i := range path[1:last] {
+		task.Turnpoints = append(task.Turnpoints, f.points[i])
+	}
+	return task, path
+}
+
+// AchieveAAT checks if the given AAT was flown in the track, finding the
+// scoring points that maximize the distance.
+//
+// Marking time and speed follow the FAI Sporting Code Section 3 Annex A.
+func (track *Track) AchieveAAT(task AAT) (AATAchievement, error) {
+	if len(task.Areas) == 0 {
+		return AATAchievement{}, fmt.Errorf("task has no areas")
+	}
+	f := task.flown(*track)
+	result := AATAchievement{}
+	if !f.started {
+		return result, nil
+	}
+	t, path := f.task(Distance)
+	if path == nil {
+		return result, fmt.Errorf("no scoring points found")
+	}
+	result.Task = t
+	result.Start = f.start.Time
+	result.Areas = len(t.Turnpoints)
+	result.Completed = f.completed
+
+	// legs go between crossing and scoring fixes, distances between task points
+	fixes := append([]Point{f.start}, t.Turnpoints...)
+	points := append([]Point{t.Start}, t.Turnpoints...)
+	if f.completed {
+		fixes = append(fixes, f.finish)
+		points = append(points, t.Finish)
+	}
+	for i := 1; i < len(points); i++ {
+		leg := LegAchievement{Start: fixes[i-1], End: fixes[i],
+			Distance: points[i-1].Distance(points[i])}
+		leg.Duration = leg.End.Time.Sub(leg.Start.Time)
+		if leg.Duration > 0 {
+			leg.Speed = leg.Distance / leg.Duration.Hours()
+		}
+		result.Legs = append(result.Legs, leg)
+		result.Distance += leg.Distance
+	}
+	// no leg flown, nothing to deduct the start radius from
+	if task.StartZone.Type == CylinderZone && len(result.Legs) > 0 {
+		result.Distance -= task.StartZone.Radius
+	}
+	if !f.completed {
+		return result, nil
+	}
+	if task.FinishZone.Type == CylinderZone {
+		result.Distance -= task.FinishZone.Radius
+	}
+	result.Finish = f.finish.Time
+	result.Duration = result.Finish.Sub(result.Start)
+	result.MarkingTime = result.Duration
+	if result.MarkingTime < task.MinTime {
+		result.MarkingTime = task.MinTime
+	}
+	if result.MarkingTime > 0 {
+		result.Speed = result.Distance / result.MarkingTime.Hours()
+	}
+	return result, nil
+}
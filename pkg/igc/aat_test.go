@@ -0,0 +1,196 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAreaContains(t *testing.T) {
+	center := NewPointFromLatLng(45, 5)
+	tests := []struct {
+		name     string
+		area     Area
+		point    Point
+		contains bool
+	}{
+		{name: "circle-inside", area: Area{Center: center, Radius: 10}, point: NewPointFromLatLng(45.05, 5), contains: true},
+		{name: "circle-outside", area: Area{Center: center, Radius: 10}, point: NewPointFromLatLng(45.1, 5)},
+		{name: "circle-center", area: Area{Center: center, Radius: 10}, point: center, contains: true},
+		{name: "inner-radius", area: Area{Center: center, Radius: 10, InnerRadius: 2}, point: NewPointFromLatLng(45.01, 5)},
+		{name: "radials-inside", area: Area{Center: center, Radius: 10, StartRadial: 45, EndRadial: 135}, point: NewPointFromLatLng(45, 5.05), contains: true},
+		{name: "radials-outside", area: Area{Center: center, Radius: 10, StartRadial: 45, EndRadial: 135}, point: NewPointFromLatLng(45.05, 5)},
+		{name: "radials-crossing-north", area: Area{Center: center, Radius: 10, StartRadial: 315, EndRadial: 45}, point: NewPointFromLatLng(45.05, 5), contains: true},
+		{name: "radials-crossing-north-outside", area: Area{Center: center, Radius: 10, StartRadial: 315, EndRadial: 45}, point: NewPointFromLatLng(44.95, 5)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if contains := test.area.Contains(test.point); contains != test.contains {
+				t.Errorf("expected %v got %v", test.contains, contains)
+			}
+		})
+	}
+}
+
+func newAAT(areas ...Area) AAT {
+	return AAT{
+		Start:      NewPointFromLatLng(45, 5),
+		StartZone:  NewLineZone(2),
+		Areas:      areas,
+		Finish:     NewPointFromLatLng(45, 5),
+		FinishZone: NewCylinderZone(1),
+		MinTime:    time.Hour,
+	}
+}
+
+func TestAchieveAAT(t *testing.T) {
+	east := NewPointFromLatLng(45, 5.6)
+	west := NewPointFromLatLng(45, 5.5)
+	back := NewPointFromLatLng(45, 5.2)
+	start := NewPointFromLatLng(45, 5)
+
+	tests := []struct {
+		name      string
+		task      AAT
+		track     Track
+		completed bool
+		scoring   []Point
+		distance  float64
+	}{
+		{name: "full-circle", task: newAAT(Area{Center: west, Radius: 10}),
+			track: straightTrack(45, 4.9, 5.6, 4.9), completed: true,
+			scoring: []Point{east}, distance: 2*start.Distance(east) - 1},
+		{name: "radials", task: newAAT(Area{Center: west, Radius: 10, StartRadial: 180, EndRadial: 360}),
+			track: straightTrack(45, 4.9, 5.6, 4.9), completed: true,
+			scoring: []Point{west}, distance: 2*start.Distance(west) - 1},
+		{name: "two-areas", task: newAAT(Area{Center: west, Radius: 5}, Area{Center: back, Radius: 5}),
+			track: straightTrack(45, 4.9, 5.6, 4.9), completed: true,
+			// any point in the second area is optimal, as they are all in line
+			scoring:  []Point{NewPointFromLatLng(45, 5.56), {}},
+			distance: 2*start.Distance(NewPointFromLatLng(45, 5.56)) - 1},
+		{name: "finish-missed", task: newAAT(Area{Center: west, Radius: 10}),
+			track:   straightTrack(45, 4.9, 5.6, 5.3),
+			scoring: []Point{east}, distance: start.Distance(east)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := test.track.AchieveAAT(test.task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Completed != test.completed || a.Areas != len(test.scoring) {
+				t.Fatalf("expected completed %v with %v areas got %v with %v",
+					test.completed, len(test.scoring), a.Completed, a.Areas)
+			}
+			for i, p := range test.scoring {
+				if p.LatLng.Lat == 0 && p.LatLng.Lng == 0 {
+					continue
+				}
+				if d := p.Distance(a.Task.Turnpoints[i]); d > 0.8 {
+					t.Errorf("expected scoring point %v near %v got %v", i, p.LatLng, a.Task.Turnpoints[i].LatLng)
+				}
+			}
+			if math.Abs(a.Distance-test.distance) > 0.5 {
+				t.Errorf("expected distance %v got %v", test.distance, a.Distance)
+			}
+			for i := 1; i < len(a.Legs); i++ {
+				if a.Legs[i].Start.Time.Before(a.Legs[i-1].End.Time) {
+					t.Errorf("leg %v starts before the previous one ends", i)
+				}
+			}
+			if !test.completed {
+				if a.Speed != 0 {
+					t.Errorf("expected no speed got %v", a.Speed)
+				}
+				return
+			}
+			if a.Duration >= test.task.MinTime || a.MarkingTime != test.task.MinTime {
+				t.Errorf("expected marking time %v got %v", test.task.MinTime, a.MarkingTime)
+			}
+			if math.Abs(a.Speed-a.Distance) > 1e-9 {
+				t.Errorf("expected speed %v got %v", a.Distance, a.Speed)
+			}
+		})
+	}
+}
+
+func TestAchieveAATNoArea(t *testing.T) {
+	// started through a start cylinder but turning back before the area
+	task := newAAT(Area{Center: NewPointFromLatLng(45, 5.5), Radius: 10})
+	task.StartZone = NewCylinderZone(2)
+	track := straightTrack(45, 4.9, 5.1, 4.9)
+	a, err := track.AchieveAAT(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Areas != 0 || len(a.Legs) != 0 || a.Completed {
+		t.Errorf("expected no areas or legs got %v areas and %v legs", a.Areas, len(a.Legs))
+	}
+	if a.Distance != 0 {
+		t.Errorf("expected distance 0 got %v", a.Distance)
+	}
+}
+
+func TestAchieveAATMarkingTime(t *testing.T) {
+	track := straightTrack(45, 4.9, 5.6, 4.9)
+	task := newAAT(Area{Center: NewPointFromLatLng(45, 5.5), Radius: 10})
+	task.MinTime = 10 * time.Minute
+	a, err := track.AchieveAAT(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.MarkingTime != a.Duration || a.Duration < task.MinTime {
+		t.Errorf("expected marking time %v got %v", a.Duration, a.MarkingTime)
+	}
+	expected := a.Distance / a.Duration.Hours()
+	if math.Abs(a.Speed-expected) > 1e-9 {
+		t.Errorf("expected speed %v got %v", expected, a.Speed)
+	}
+}
+
+func TestAATOptimizer(t *testing.T) {
+	track := straightTrack(45, 4.9, 5.6, 4.9)
+	task := newAAT(Area{Center: NewPointFromLatLng(45, 5.5), Radius: 10})
+	opt := NewAATOptimizer(task)
+
+	result, err := opt.Optimize(track, 1, Distance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NewPointFromLatLng(45, 5.6)
+	if d := expected.Distance(result.Turnpoints[0]); d > 0.01 {
+		t.Errorf("expected scoring point %v got %v", expected.LatLng, result.Turnpoints[0].LatLng)
+	}
+	// prefer the point closest to the area center
+	closest := func(t Task) float64 { return -t.Finish.Distance(task.Areas[0].Center) }
+	result, err = opt.Optimize(track, 1, closest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := task.Areas[0].Center.Distance(result.Turnpoints[0]); d > 0.01 {
+		t.Errorf("expected scoring point at the center got %vkm away", d)
+	}
+
+	if _, err := opt.Optimize(track, 2, Distance); err == nil {
+		t.Errorf("expected error for wrong number of turnpoints")
+	}
+	incomplete := straightTrack(45, 4.9, 5.6, 5.3)
+	if _, err := opt.Optimize(incomplete, 1, Distance); err == nil {
+		t.Errorf("expected error for task not completed")
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// ZoneType is the shape of an observation zone.
+type ZoneType int
+
+const (
+	// LineZone is a line perpendicular to the first leg (start) or last leg
+	// (finish), centered on the task point.
+	LineZone ZoneType = 0
+	// CylinderZone is a circle around the task point.
+	CylinderZone ZoneType = 1
+	// SectorZone is a sector around the outer bisector of the legs meeting
+	// at the task point.
+	SectorZone ZoneType = 2
+	// KeyholeZone is a sector combined with a small cylinder.
+	KeyholeZone ZoneType = 3
+)
+
+// Observation zone defaults, as defined in the FAI Sporting Code Section 3.
+const (
+	// FAISectorAngle is the angle of an FAI sector, in degrees.
+	FAISectorAngle = 90.0
+	// KeyholeRadius is the radius in kms of the sector in a keyhole.
+	KeyholeRadius = 10.0
+	// KeyholeInnerRadius is the radius in kms of the cylinder in a keyhole.
+	KeyholeInnerRadius = 0.5
+)
+
+// Zone is an observation zone for a task point.
+//
+// Radius is in kms, and for a LineZone it is half the line length. A zero
+// Radius in a SectorZone means the sector is unlimited. InnerRadius is the
+// radius of the cylinder in a KeyholeZone, and Angle the full angle of
+// sectors and keyholes in degrees.
+type Zone struct {
+	Type        ZoneType
+	Radius      float64
+	InnerRadius float64
+	Angle       float64
+}
+
+// NewLineZone returns a start or finish line with the given length in kms.
+func NewLineZone(length float64) Zone {
+	return Zone{Type: LineZone, Radius: length / 2}
+}
+
+// NewCylinderZone returns a cylinder with the given radius in kms.
+//
+// This is also used for a finish ring.
+func NewCylinderZone(radius float64) Zone {
+	return Zone{Type: CylinderZone, Radius: radius}
+}
+
+// NewFAISectorZone returns an unlimited 90 degree FAI sector.
+func NewFAISectorZone() Zone {
+	return Zone{Type: SectorZone, Angle: FAISectorAngle}
+}
+
+// NewKeyholeZone returns the keyhole defined in the FAI Sporting Code, a
+// 90 degree sector of 10kms and a cylinder of 0.5kms.
+func NewKeyholeZone() Zone {
+	return Zone{Type: KeyholeZone, Radius: KeyholeRadius,
+		InnerRadius: KeyholeInnerRadius, Angle: FAISectorAngle}
+}
+
+// LegAchievement holds the result of flying a single task leg.
+//
+// Start and End are the interpolated points where the observation zones were
+// crossed, and Distance is the nominal leg distance (between the task points).
+type LegAchievement struct {
+	Start    Point
+	End      Point
+	Distance float64
+	Duration time.Duration
+	Speed    float64
+}
+
+// Achievement holds the result of flying a Task.
+//
+// Start and Finish are the interpolated crossing times, with Finish being
+// zero if the task was not completed. Distance is the nominal task distance
+// if completed, otherwise the distance of the legs achieved plus the best
+// progress towards the next task point. Speed is in km/h and only set for
+// completed tasks.
+type Achievement struct {
+	Completed  bool
+	Turnpoints int
+	Start      time.Time
+	Finish     time.Time
+	Duration   time.Duration
+	Distance   float64
+	Speed      float64
+	Legs       []LegAchievement
+}
+
+// zoneCheck decides if points are inside an oriented observation zone.
+type zoneCheck struct {
+	zone   Zone
+	center Point
+	// direction is the line direction for lines, the sector bisector otherwise
+	direction s1.Angle
+	// finish is true for the last task point
+	finish bool
+}
+
+// Achieve checks if the given Task was flown in the track.
+//
+// There must be one Zone for each task point, in order: Start, Turnpoints and
+// Finish. The start is the last exit of the start zone (or crossing of the
+// start line) before reaching the first turnpoint, turnpoints are achieved
+// by entering their zones and the finish by entering the finish zone (or
+// crossing the finish line).
+func (track *Track) Achieve(task Task, zones []Zone) (Achievement, error) {
+	points, _ := task.points()
+	if len(zones) != len(points) {
+		return Achievement{}, fmt.Errorf("expected %v zones got %v", len(points), len(zones))
+	}
+	checks := zoneChecks(points, zones)
+
+	crossings := make([]Point, len(points))
+	next := 0
+	for i := 1; i < len(track.Points); i++ {
+		a, b := track.Points[i-1], track.Points[i]
+		// (re)start while no turnpoint is reached
+		if next <= 1 {
+			if p, ok := checks[0].exit(a, b); ok {
+				crossings[0] = p
+				next = 1
+				continue
+			}
+		}
+		if next == 0 || next == len(points) {
+			continue
+		}
+		if p, ok := checks[next].entry(a, b); ok {
+			crossings[next] = p
+			next++
+		}
+	}
+
+	result := Achievement{}
+	if next == 0 {
+		return result, nil
+	}
+	result.Start = crossings[0].Time
+	for i := 1; i < next; i++ {
+		leg := LegAchievement{Start: crossings[i-1], End: crossings[i],
+			Distance: points[i-1].Distance(points[i])}
+		leg.Duration = leg.End.Time.Sub(leg.Start.Time)
+		if leg.Duration > 0 {
+			leg.Speed = leg.Distance / leg.Duration.Hours()
+		}
+		result.Legs = append(result.Legs, leg)
+		result.Distance += leg.Distance
+	}
+	result.Turnpoints = next - 1
+	if next == len(points) {
+		result.Completed = true
+		result.Turnpoints = len(task.Turnpoints)
+		result.Finish = crossings[next-1].Time
+		result.Duration = result.Finish.Sub(result.Start)
+		if result.Duration > 0 {
+			result.Speed = result.Distance / result.Duration.Hours()
+		}
+		return result, nil
+	}
+
+	// best progress towards the next task point
+	leg := points[next-1].Distance(points[next])
+	progress := 0.0
+	for _, p := range track.Points {
+		if !p.Time.After(crossings[next-1].Time) {
+			continue
+		}
+		progress = math.Max(progress, leg-p.Distance(points[next]))
+	}
+	result.Distance += progress
+	return result, nil
+}
+
+func zoneChecks(points []Point, zones []Zone) []zoneCheck {
+	checks := make([]zoneCheck, len(points))
+	last := len(points) - 1
+	for i, p := range points {
+		c := zoneCheck{zone: zones[i], center: p, finish: i == last}
+		switch {
+		case i == 0:
+			c.direction = p.Bearing(points[1])
+		case i == last:
+			c.direction = points[i-1].Bearing(p)
+		default:
+			c.direction = bisector(p.Bearing(points[i-1]), p.Bearing(points[i+1]))
+		}
+		checks[i] = c
+	}
+	return checks
+}
+
+// bisector returns the outer bisector of the two given bearings.
+func bisector(a s1.Angle, b s1.Angle) s1.Angle {
+	x := math.Cos(a.Radians()) + math.Cos(b.Radians())
+	y := math.Sin(a.Radians()) + math.Sin(b.Radians())
+	if math.Hypot(x, y) < 1e-9 {
+		// legs in a straight line, the sector is perpendicular
+		return a - s1.Angle(math.Pi/2)
+	}
+	return s1.Angle(math.Atan2(y, x) + math.Pi)
+}
+
+// angleDiff returns the absolute difference between two angles in degrees.
+func angleDiff(a s1.Angle, b s1.Angle) float64 {
+	d := math.Abs(math.Remainder((a - b).Degrees(), 360))
+	return d
+}
+
+// inside returns true if p is inside the zone.
+//
+// For lines, inside means behind the start line or past the finish line.
+func (c *zoneCheck) inside(p Point) bool {
+	d := c.center.Distance(p)
+	switch c.zone.Type {
+	case LineZone:
+		if d == 0 {
+			return c.finish
+		}
+		past := angleDiff(c.center.Bearing(p), c.direction) < 90
+		return past == c.finish
+	case CylinderZone:
+		return d <= c.zone.Radius
+	case SectorZone:
+		return (c.zone.Radius == 0 || d <= c.zone.Radius) && c.inSector(p)
+	case KeyholeZone:
+		return d <= c.zone.InnerRadius || (d <= c.zone.Radius && c.inSector(p))
+	}
+	return false
+}
+
+func (c *zoneCheck) inSector(p Point) bool {
+	if c.center.Distance(p) == 0 {
+		return true
+	}
+	return angleDiff(c.center.Bearing(p), c.direction) <= c.zone.Angle/2
+}
+
+// exit returns the interpolated point where the track left the zone between
+// a and b, if it did.
+func (c *zoneCheck) exit(a Point, b Point) (Point, bool) {
+	if !c.inside(a) || c.inside(b) {
+		return Point{}, false
+	}
+	return c.crossing(a, b)
+}
+
+// entry returns the interpolated point where the track entered the zone
+// between a and b, if it did.
+func (c *zoneCheck) entry(a Point, b Point) (Point, bool) {
+	if c.inside(a) || !c.inside(b) {
+		return Point{}, false
+	}
+	return c.crossing(a, b)
+}
+
+// crossing returns the point where the segment a-b crosses the zone border.
+//
+// Lines are only crossed if the crossing point is within the line length.
+func (c *zoneCheck) crossing(a Point, b Point) (Point, bool) {
+	in := c.inside(a)
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 30; i++ {
+		mid := (lo + hi) / 2
+		if c.inside(interpolate(a, b, mid)) == in {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	p := interpolate(a, b, hi)
+	if c.zone.Type == LineZone && c.center.Distance(p) > c.zone.Radius {
+		return Point{}, false
+	}
+	return p, true
+}
+
+// interpolate returns the point at fraction f of the segment a-b, with time
+// and altitudes interpolated linearly.
+func interpolate(a Point, b Point, f float64) Point {
+	pa, pb := s2.PointFromLatLng(a.LatLng), s2.PointFromLatLng(b.LatLng)
+	p := NewPointFromLatLng(0, 0)
+	p.LatLng = s2.LatLngFromPoint(s2.Interpolate(f, pa, pb))
+	p.Time = a.Time.Add(time.Duration(f * float64(b.Time.Sub(a.Time))))
+	p.GNSSAltitude = a.GNSSAltitude + int64(math.Round(f*float64(b.GNSSAltitude-a.GNSSAltitude)))
+	p.PressureAltitude = a.PressureAltitude +
+		int64(math.Round(f*float64(b.PressureAltitude-a.PressureAltitude)))
+	return p
+}
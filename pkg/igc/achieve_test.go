@@ -0,0 +1,180 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// straightTrack returns points along latitude lat through the given
+// longitudes, one every 10 seconds with a 0.01 degree step.
+func straightTrack(lat float64, lngs ...float64) Track {
+	track := NewTrack()
+	t := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < len(lngs)-1; i++ {
+		step := 0.01
+		if lngs[i+1] < lngs[i] {
+			step = -0.01
+		}
+		n := int(math.Round(math.Abs(lngs[i+1]-lngs[i]) / 0.01))
+		for j := 0; j < n; j++ {
+			p := NewPointFromLatLng(lat, lngs[i]+float64(j)*step)
+			p.Time = t
+			track.Points = append(track.Points, p)
+			t = t.Add(10 * time.Second)
+		}
+	}
+	p := NewPointFromLatLng(lat, lngs[len(lngs)-1])
+	p.Time = t
+	track.Points = append(track.Points, p)
+	return track
+}
+
+// outAndReturn is a task from (45, 5) to (45, 5.5) and back.
+var outAndReturn = Task{
+	Start:      NewPointFromLatLng(45, 5),
+	Turnpoints: []Point{NewPointFromLatLng(45, 5.5)},
+	Finish:     NewPointFromLatLng(45, 5),
+}
+
+func TestAchieve(t *testing.T) {
+	zones := []Zone{NewLineZone(2), NewCylinderZone(0.5), NewLineZone(2)}
+	leg := outAndReturn.Start.Distance(outAndReturn.Turnpoints[0])
+	missed := NewPointFromLatLng(45, 5.4)
+	back := NewPointFromLatLng(45, 5.2)
+
+	tests := []struct {
+		name       string
+		track      Track
+		completed  bool
+		turnpoints int
+		start      time.Duration
+		distance   float64
+	}{
+		{name: "completed", track: straightTrack(45, 4.9, 5.6, 4.9),
+			completed: true, turnpoints: 1, start: 100 * time.Second, distance: 2 * leg},
+		{name: "restart", track: straightTrack(45, 4.9, 5.1, 4.9, 5.6, 4.9),
+			completed: true, turnpoints: 1, start: 500 * time.Second, distance: 2 * leg},
+		{name: "turnpoint-missed", track: straightTrack(45, 4.9, 5.4, 4.9),
+			turnpoints: 0, start: 100 * time.Second, distance: leg - missed.Distance(outAndReturn.Turnpoints[0])},
+		{name: "finish-missed", track: straightTrack(45, 4.9, 5.6, 5.2),
+			turnpoints: 1, start: 100 * time.Second, distance: leg + leg - back.Distance(outAndReturn.Finish)},
+		{name: "line-missed", track: straightTrack(45.1, 4.9, 5.6, 4.9)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := test.track.Achieve(outAndReturn, zones)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Completed != test.completed || a.Turnpoints != test.turnpoints {
+				t.Errorf("expected completed %v with %v turnpoints got %v with %v",
+					test.completed, test.turnpoints, a.Completed, a.Turnpoints)
+			}
+			if math.Abs(a.Distance-test.distance) > 0.01 {
+				t.Errorf("expected distance %v got %v", test.distance, a.Distance)
+			}
+			if test.start == 0 {
+				if !a.Start.IsZero() {
+					t.Errorf("expected no start got %v", a.Start)
+				}
+				return
+			}
+			start := test.track.Points[0].Time.Add(test.start)
+			if d := a.Start.Sub(start); d < -time.Second || d > time.Second {
+				t.Errorf("expected start %v got %v", start, a.Start)
+			}
+			if !test.completed {
+				return
+			}
+			expected := a.Distance / a.Finish.Sub(a.Start).Hours()
+			if len(a.Legs) != 2 || math.Abs(a.Speed-expected) > 0.01 {
+				t.Errorf("expected speed %v with 2 legs got %v with %v", expected, a.Speed, len(a.Legs))
+			}
+			if d := a.Legs[0].Start.Distance(outAndReturn.Start); d > 0.01 {
+				t.Errorf("expected start crossing at the line got %vkm away", d)
+			}
+			if d := a.Legs[0].End.Distance(outAndReturn.Turnpoints[0]); math.Abs(d-0.5) > 0.01 {
+				t.Errorf("expected turnpoint crossing at the cylinder got %vkm away", d)
+			}
+		})
+	}
+
+	track := straightTrack(45, 4.9, 5.1)
+	if _, err := track.Achieve(outAndReturn, zones[:2]); err == nil {
+		t.Errorf("expected error for missing zones")
+	}
+}
+
+func TestAchieveDeclared(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-long-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := []Zone{NewLineZone(10), NewKeyholeZone(), NewKeyholeZone(), NewKeyholeZone(), NewCylinderZone(3)}
+	a, err := track.Achieve(track.Task, zones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Completed || a.Turnpoints != 3 || len(a.Legs) != 4 {
+		t.Fatalf("expected completed task with 3 turnpoints got %v with %v", a.Completed, a.Turnpoints)
+	}
+	if math.Abs(a.Distance-507.80108709626626) > 0.001 {
+		t.Errorf("expected distance %v got %v", 507.80108709626626, a.Distance)
+	}
+	if math.Abs(a.Speed-78.74) > 0.1 {
+		t.Errorf("expected speed %v got %v", 78.74, a.Speed)
+	}
+	var legs time.Duration
+	for _, l := range a.Legs {
+		legs += l.Duration
+	}
+	if legs != a.Duration {
+		t.Errorf("expected legs to add up to %v got %v", a.Duration, legs)
+	}
+}
+
+func TestZoneInside(t *testing.T) {
+	// turnpoint at (45, 5) with legs coming from the west and leaving north,
+	// so the sector points south east
+	points := []Point{NewPointFromLatLng(45, 4), NewPointFromLatLng(45, 5), NewPointFromLatLng(46, 5)}
+
+	tests := []struct {
+		name   string
+		zone   Zone
+		point  Point
+		inside bool
+	}{
+		{name: "cylinder-inside", zone: NewCylinderZone(1), point: NewPointFromLatLng(45.005, 5), inside: true},
+		{name: "cylinder-outside", zone: NewCylinderZone(1), point: NewPointFromLatLng(45.05, 5)},
+		{name: "sector-inside", zone: NewFAISectorZone(), point: NewPointFromLatLng(44, 6), inside: true},
+		{name: "sector-outside", zone: NewFAISectorZone(), point: NewPointFromLatLng(45, 6)},
+		{name: "sector-limited", zone: Zone{Type: SectorZone, Radius: 5, Angle: 90}, point: NewPointFromLatLng(44.9, 5.1)},
+		{name: "keyhole-cylinder", zone: NewKeyholeZone(), point: NewPointFromLatLng(45.003, 4.998), inside: true},
+		{name: "keyhole-sector", zone: NewKeyholeZone(), point: NewPointFromLatLng(44.95, 5.05), inside: true},
+		{name: "keyhole-outside", zone: NewKeyholeZone(), point: NewPointFromLatLng(45.01, 4.99)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checks := zoneChecks(points, []Zone{NewLineZone(1), test.zone, NewLineZone(1)})
+			if inside := checks[1].inside(test.point); inside != test.inside {
+				t.Errorf("expected inside %v got %v", test.inside, inside)
+			}
+		})
+	}
+}
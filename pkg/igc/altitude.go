@@ -0,0 +1,260 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"fmt"
+	"math"
+)
+
+// AltitudeSource selects the altitude used when calculating phases and
+// stats.
+//
+// Possible values include AltitudeGNSS, AltitudePressure, AltitudeQNH and
+// AltitudeAuto.
+type AltitudeSource int
+
+const (
+	// AltitudeGNSS uses the GNSS altitude of each point.
+	AltitudeGNSS AltitudeSource = 0
+	// AltitudePressure uses the raw pressure altitude of each point,
+	// relative to the StandardPressure.
+	AltitudePressure AltitudeSource = 1
+	// AltitudeQNH uses the pressure altitude converted to the QNH.
+	AltitudeQNH AltitudeSource = 2
+	// AltitudeAuto uses the GNSS altitude, with the QNH altitude for the
+	// points where the GNSS altitude is missing (zero).
+	AltitudeAuto AltitudeSource = 3
+)
+
+var altitudeSourceNames = map[AltitudeSource]string{
+	AltitudeGNSS:     "gnss",
+	AltitudePressure: "pressure",
+	AltitudeQNH:      "qnh",
+	AltitudeAuto:     "auto",
+}
+
+// String returns the lower case name of the altitude source.
+func (s AltitudeSource) String() string {
+	if name, ok := altitudeSourceNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("AltitudeSource(%d)", int(s))
+}
+
+// ParseAltitudeSource returns the AltitudeSource with the given name, one
+// of gnss, pressure, qnh or auto.
+func ParseAltitudeSource(name string) (AltitudeSource, error) {
+	for s, n := range altitudeSourceNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return AltitudeGNSS, fmt.Errorf("unknown altitude source '%v'", name)
+}
+
+const (
+	// StandardPressure is the sea level pressure in hPa of the International
+	// Standard Atmosphere, to which pressure altitudes are relative.
+	StandardPressure = 1013.25
+	// QNHSamples is the number of points before takeoff used to estimate
+	// the QNH.
+	QNHSamples = 10
+	// StuckSensorRatio is the ratio of zero or unchanged readings above
+	// which a sensor is considered failed.
+	StuckSensorRatio = 0.95
+
+	// barometric formula constants for the ISA troposphere
+	isaLapse    = 2.25577e-5
+	isaExponent = 5.25588
+)
+
+// PressureToQNHAltitude converts a pressure altitude (relative to the
+// StandardPressure) to the altitude relative to the given QNH, in meters.
+func PressureToQNHAltitude(pressureAltitude float64, qnh float64) float64 {
+	p := isaPressure(pressureAltitude, StandardPressure)
+	return isaAltitude(p, qnh)
+}
+
+// QNHToPressureAltitude converts an altitude relative to the given QNH to a
+// pressure altitude (relative to the StandardPressure), in meters.
+func QNHToPressureAltitude(altitude float64, qnh float64) float64 {
+	p := isaPressure(altitude, qnh)
+	return isaAltitude(p, StandardPressure)
+}
+
+// isaPressure returns the pressure in hPa at the given altitude, for the
+// given sea level pressure.
+func isaPressure(altitude float64, seaLevel float64) float64 {
+	return seaLevel * math.Pow(1-isaLapse*altitude, isaExponent)
+}
+
+// isaAltitude returns the altitude at the given pressure in hPa, for the
+// given sea level pressure.
+func isaAltitude(pressure float64, seaLevel float64) float64 {
+	return (1 - math.Pow(pressure/seaLevel, 1/isaExponent)) / isaLapse
+}
+
+// QNH returns the sea level pressure in hPa estimated from the difference
+// between the GNSS and pressure altitudes, averaged over the QNHSamples
+// points up to the takeoff (or the first points for tracks starting in
+// flight).
+//
+// Points without a valid GNSS fix or with zero altitudes are ignored.
+func (track *Track) QNH() (float64, error) {
+	if sensorFailed(track.Points, gnssAltitude) {
+		return 0, fmt.Errorf("gnss altitude sensor failed, cannot estimate qnh")
+	}
+	if sensorFailed(track.Points, pressureAltitude) {
+		return 0, fmt.Errorf("pressure altitude sensor failed, cannot estimate qnh")
+	}
+	end := track.takeoffIndex()
+	if end == 0 {
+		end = QNHSamples - 1
+	}
+	var total float64
+	var n int
+	for i := end; i >= 0 && n < QNHSamples; i-- {
+		if i >= len(track.Points) {
+			continue
+		}
+		p := track.Points[i]
+		if p.FixValidity == 'V' || p.GNSSAltitude == 0 || p.PressureAltitude == 0 {
+			continue
+		}
+		pressure := isaPressure(float64(p.PressureAltitude), StandardPressure)
+		total += pressure / math.Pow(1-isaLapse*float64(p.GNSSAltitude), isaExponent)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("no valid points to estimate qnh")
+	}
+	return total / float64(n), nil
+}
+
+// Altitudes returns the altitude of each point in the Track from the given
+// source, in meters.
+//
+// The qnh (hPa) is used for the AltitudeQNH source. If zero it is taken
+// from the header AltimeterPressure if set, and estimated from the GNSS
+// altitude at takeoff otherwise (see Track.QNH()).
+//
+// If the sensor for the given source has failed (all zeros or stuck values)
+// the other sensor is used instead, with the pressure altitude converted to
+// the QNH if available.
+func (track *Track) Altitudes(source AltitudeSource, qnh float64) ([]int64, error) {
+	gnssFailed := sensorFailed(track.Points, gnssAltitude)
+	pressureFailed := sensorFailed(track.Points, pressureAltitude)
+
+	qnhAltitudes := func(strict bool) ([]int64, error) {
+		q, err := track.qnh(qnh)
+		if err != nil {
+			if strict {
+				return []int64{}, err
+			}
+			q = StandardPressure
+		}
+		alts := make([]int64, len(track.Points))
+		for i, p := range track.Points {
+			alts[i] = int64(math.Round(PressureToQNHAltitude(float64(p.PressureAltitude), q)))
+		}
+		return alts, nil
+	}
+
+	switch source {
+	case AltitudeGNSS, AltitudeAuto:
+		if gnssFailed && !pressureFailed {
+			return qnhAltitudes(false)
+		}
+		alts := altitudes(track.Points, gnssAltitude)
+		if source == AltitudeGNSS || pressureFailed {
+			return alts, nil
+		}
+		var fallback []int64
+		for i, p := range track.Points {
+			if p.GNSSAltitude != 0 || p.PressureAltitude == 0 {
+				continue
+			}
+			if fallback == nil {
+				fallback, _ = qnhAltitudes(false)
+			}
+			alts[i] = fallback[i]
+		}
+		return alts, nil
+	case AltitudePressure:
+		if pressureFailed && !gnssFailed {
+			return altitudes(track.Points, gnssAltitude), nil
+		}
+		return altitudes(track.Points, pressureAltitude), nil
+	case AltitudeQNH:
+		if pressureFailed && !gnssFailed {
+			return altitudes(track.Points, gnssAltitude), nil
+		}
+		return qnhAltitudes(true)
+	default:
+		return []int64{}, fmt.Errorf("unknown altitude source %v", source)
+	}
+}
+
+// qnh returns the given qnh if set, or the one in the header, or the one
+// estimated from the GNSS altitude.
+func (track *Track) qnh(qnh float64) (float64, error) {
+	if qnh > 0 {
+		return qnh, nil
+	}
+	if track.AltimeterPressure > 0 {
+		return track.AltimeterPressure, nil
+	}
+	return track.QNH()
+}
+
+// altitude returns the altitude of point i from the source of the last phases
+// calculation, defaulting to the GNSS altitude.
+func (track *Track) altitude(i int) int64 {
+	if len(track.altitudes) == len(track.Points) {
+		return track.altitudes[i]
+	}
+	return track.Points[i].GNSSAltitude
+}
+
+func gnssAltitude(p Point) int64     { return p.GNSSAltitude }
+func pressureAltitude(p Point) int64 { return p.PressureAltitude }
+
+// altitudes returns the values of the given sensor for each point.
+func altitudes(points []Point, sensor func(Point) int64) []int64 {
+	alts := make([]int64, len(points))
+	for i, p := range points {
+		alts[i] = sensor(p)
+	}
+	return alts
+}
+
+// sensorFailed checks if the readings of the given sensor are all zeros or
+// stuck, with the ratio of zero or unchanged readings above
+// StuckSensorRatio.
+func sensorFailed(points []Point, sensor func(Point) int64) bool {
+	if len(points) == 0 {
+		return true
+	}
+	stuck := 0
+	for i, p := range points {
+		v := sensor(p)
+		if v == 0 || (i > 0 && v == sensor(points[i-1])) {
+			stuck++
+		}
+	}
+	return float64(stuck)/float64(len(points)) >= StuckSensorRatio
+}
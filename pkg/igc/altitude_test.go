@@ -0,0 +1,173 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPressureToQNHAltitude(t *testing.T) {
+	tests := []struct {
+		name     string
+		pressure float64
+		qnh      float64
+		altitude float64
+	}{
+		{name: "standard", pressure: 1000, qnh: StandardPressure, altitude: 1000},
+		{name: "high", pressure: 0, qnh: 1023.25, altitude: 83},
+		{name: "low", pressure: 500, qnh: 1003.25, altitude: 417},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alt := PressureToQNHAltitude(tt.pressure, tt.qnh)
+			if math.Abs(alt-tt.altitude) > 1 {
+				t.Errorf("expected %vm got %v", tt.altitude, alt)
+			}
+			if p := QNHToPressureAltitude(alt, tt.qnh); math.Abs(p-tt.pressure) > 0.01 {
+				t.Errorf("expected pressure altitude %v got %v", tt.pressure, p)
+			}
+		})
+	}
+}
+
+// altitudeTrack returns a track with a GNSS altitude increasing 10m per
+// point, and the matching pressure altitude for the given qnh.
+func altitudeTrack(qnh float64) Track {
+	track := straightTrack(45, 5, 5.2)
+	for i := range track.Points {
+		alt := float64(100 + 10*i)
+		track.Points[i].FixValidity = 'A'
+		track.Points[i].GNSSAltitude = int64(alt)
+		track.Points[i].PressureAltitude = int64(math.Round(QNHToPressureAltitude(alt, qnh)))
+	}
+	return track
+}
+
+func TestQNH(t *testing.T) {
+	track := altitudeTrack(1025)
+	qnh, err := track.QNH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(qnh-1025) > 0.2 {
+		t.Errorf("expected qnh 1025 got %v", qnh)
+	}
+
+	for i := range track.Points {
+		track.Points[i].GNSSAltitude = 0
+	}
+	if _, err := track.QNH(); err == nil {
+		t.Errorf("expected error with failed gnss sensor")
+	}
+}
+
+func TestAltitudes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source AltitudeSource
+		qnh    float64
+		modify func(track *Track)
+		// first is the expected altitude of the first point, or its pressure
+		// altitude when zero
+		first int64
+	}{
+		{name: "gnss", source: AltitudeGNSS, first: 100},
+		{name: "pressure", source: AltitudePressure},
+		{name: "qnh-estimated", source: AltitudeQNH, first: 100},
+		{name: "qnh-given", source: AltitudeQNH, qnh: 1013.25},
+		{name: "qnh-header", source: AltitudeQNH, modify: func(track *Track) {
+			track.AltimeterPressure = 1013.25
+		}},
+		{name: "gnss-failed", source: AltitudeGNSS, first: 100, modify: func(track *Track) {
+			for i := range track.Points {
+				track.Points[i].GNSSAltitude = 0
+			}
+			track.AltimeterPressure = 1025
+		}},
+		{name: "pressure-stuck", source: AltitudeQNH, first: 100, modify: func(track *Track) {
+			for i := range track.Points {
+				track.Points[i].PressureAltitude = 1500
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := altitudeTrack(1025)
+			if tt.modify != nil {
+				tt.modify(&track)
+			}
+			alts, err := track.Altitudes(tt.source, tt.qnh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(alts) != len(track.Points) {
+				t.Fatalf("expected %v altitudes got %v", len(track.Points), len(alts))
+			}
+			first := tt.first
+			if first == 0 {
+				first = track.Points[0].PressureAltitude
+			}
+			if d := alts[0] - first; d < -1 || d > 1 {
+				t.Errorf("expected first altitude %v got %v", first, alts[0])
+			}
+		})
+	}
+}
+
+func TestAltitudesAuto(t *testing.T) {
+	track := altitudeTrack(1025)
+	track.Points[5].GNSSAltitude = 0
+
+	alts, err := track.Altitudes(AltitudeGNSS, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alts[5] != 0 {
+		t.Errorf("expected gnss dropout kept got %v", alts[5])
+	}
+	alts, err = track.Altitudes(AltitudeAuto, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := alts[5] - 150; d < -1 || d > 1 {
+		t.Errorf("expected gnss dropout filled with 150m got %v", alts[5])
+	}
+}
+
+func TestAltitudesNoQNH(t *testing.T) {
+	track := altitudeTrack(1025)
+	for i := range track.Points {
+		track.Points[i].GNSSAltitude, track.Points[i].FixValidity = 0, 'V'
+	}
+	if _, err := track.Altitudes(AltitudeQNH, 0); err == nil {
+		t.Errorf("expected error without qnh")
+	}
+	if _, err := track.Altitudes(AltitudeAuto, 0); err != nil {
+		t.Errorf("expected fallback to pressure altitude got %v", err)
+	}
+}
+
+func TestParseAltitudeSource(t *testing.T) {
+	for s := range altitudeSourceNames {
+		if parsed, err := ParseAltitudeSource(s.String()); err != nil || parsed != s {
+			t.Errorf("expected %v got %v %v", s, parsed, err)
+		}
+	}
+	if _, err := ParseAltitudeSource("radar"); err == nil {
+		t.Errorf("expected error for unknown source")
+	}
+}
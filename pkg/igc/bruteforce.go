@@ -18,11 +18,13 @@ package igc
 
 import (
 	"fmt"
-	"time"
 )
 
 // NewBruteForceOptimizer returns a BruteForceOptimizer with the given characteristics.
 //
+// The cache option is kept for compatibility and has no effect: each
+// candidate task is scored once as a whole, so there are no partial results
+// to reuse.
 func NewBruteForceOptimizer(cache bool) Optimizer {
 	return &bruteForceOptimizer{cache: cache}
 }
@@ -32,7 +34,6 @@ type bruteForceOptimizer struct {
 }
 
 func (b *bruteForceOptimizer) Optimize(track Track, nPoints int, score Score) (Task, error) {
-	time.Sleep(5 * time.Second)
 	switch nPoints {
 
 	case 1:
@@ -59,7 +60,7 @@ func (b *bruteForceOptimizer) optimize1(track Track, score Score) (Task, error)
 					Turnpoints: []Point{track.Points[j]},
 					Finish:     track.Points[z],
 				}
-				distance = task.Distance()
+				distance = score(task)
 				if distance > optimalDistance {
 					optimalDistance = distance
 					optimalTask = Task(task)
@@ -85,7 +86,7 @@ func (b *bruteForceOptimizer) optimize2(track Track, score Score) (Task, error)
 						Turnpoints: []Point{track.Points[j], track.Points[w]},
 						Finish:     track.Points[z],
 					}
-					distance = task.Distance()
+					distance = score(task)
 					if distance > optimalDistance {
 						optimalDistance = distance
 						optimalTask = task
@@ -0,0 +1,276 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Visitor is called by a Decoder for each record as it is decoded.
+//
+// VisitHeader is called once, before the first Point, Event, K or Satellite,
+// or at the end of the input if the track has none of those. Returning an
+// error from any of the methods stops the decoding.
+//
+// Embed NopVisitor to implement only the methods of interest.
+type Visitor interface {
+	VisitHeader(header Header) error
+	VisitPoint(point Point) error
+	VisitEvent(event Event) error
+	VisitK(k K) error
+	VisitSatellite(satellite Satellite) error
+}
+
+// NopVisitor is a Visitor ignoring all records.
+type NopVisitor struct{}
+
+// VisitHeader does nothing.
+func (NopVisitor) VisitHeader(header Header) error { return nil }
+
+// VisitPoint does nothing.
+func (NopVisitor) VisitPoint(point Point) error { return nil }
+
+// VisitEvent does nothing.
+func (NopVisitor) VisitEvent(event Event) error { return nil }
+
+// VisitK does nothing.
+func (NopVisitor) VisitK(k K) error { return nil }
+
+// VisitSatellite does nothing.
+func (NopVisitor) VisitSatellite(satellite Satellite) error { return nil }
+
+// Decoder reads and decodes IGC records from an input stream.
+//
+// Records are parsed one line at a time, so it is not required to have the
+// whole file content in memory.
+type Decoder struct {
+	r       *bufio.Reader
+	p       parser
+	opts    ParseOptions
+	pending []string
+	eof     bool
+	line    int
+}
+
+// NewDecoder returns a new Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderWithOptions(r, ParseOptions{})
+}
+
+// NewDecoderWithOptions returns a new Decoder reading from r with the given
+// options.
+func NewDecoderWithOptions(r io.Reader, opts ParseOptions) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), opts: opts}
+}
+
+// Decode reads all records from the input and returns the full Track.
+//
+// Any invalid record results in a ParseError, unless the Decoder is in
+// lenient mode in which case all errors are returned as ParseErrors.
+func (d *Decoder) Decode() (Track, error) {
+	f := NewTrack()
+	err := d.decode(&f, &trackVisitor{track: &f})
+	return f, err
+}
+
+// Walk reads all records from the input passing each to the given Visitor.
+//
+// The returned Track includes the header, task, logbook and signature, but
+// not the Points, Events, K and Satellites which are only passed to v.
+func (d *Decoder) Walk(v Visitor) (Track, error) {
+	f := NewTrack()
+	err := d.decode(&f, v)
+	return f, err
+}
+
+// trackVisitor appends all records to the given Track.
+type trackVisitor struct {
+	NopVisitor
+	track *Track
+}
+
+func (t *trackVisitor) VisitPoint(point Point) error {
+	t.track.Points = append(t.track.Points, point)
+	return nil
+}
+
+func (t *trackVisitor) VisitEvent(event Event) error {
+	t.track.Events = append(t.track.Events, event)
+	return nil
+}
+
+func (t *trackVisitor) VisitK(k K) error {
+	t.track.K = append(t.track.K, k)
+	return nil
+}
+
+func (t *trackVisitor) VisitSatellite(satellite Satellite) error {
+	t.track.Satellites = append(t.track.Satellites, satellite)
+	return nil
+}
+
+func (d *Decoder) decode(f *Track, v Visitor) error {
+	headerDone := false
+	header := func() error {
+		if headerDone {
+			return nil
+		}
+		headerDone = true
+		return v.VisitHeader(f.Header)
+	}
+	var errs ParseErrors
+
+	for {
+		raw, ok, err := d.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		line := strings.TrimSpace(raw)
+		// ignore empty lines
+		if len(line) < 1 {
+			continue
+		}
+		lines := []string{raw}
+		var record interface{}
+		switch line[0] {
+		case 'A':
+			err = d.p.parseA(line, f)
+		case 'B':
+			record, err = d.p.parseB(line, f)
+		case 'C':
+			if !d.p.taskDone {
+				if lines, err = d.task(raw); err != nil {
+					return err
+				}
+				if err = d.p.parseC(lines, f); err != nil && d.opts.Lenient {
+					// skip the whole declaration, not each of its lines
+					f.Task = Task{}
+					d.p.taskDone = true
+				}
+			}
+		case 'D':
+			err = d.p.parseD(line, f)
+		case 'E':
+			record, err = d.p.parseE(line)
+		case 'F':
+			record, err = d.p.parseF(line)
+		case 'G':
+			err = d.p.parseG(line, f)
+		case 'H':
+			err = d.p.parseH(line, f)
+		case 'I':
+			err = d.p.parseI(line)
+		case 'J':
+			err = d.p.parseJ(line)
+		case 'K':
+			record, err = d.p.parseK(line)
+		case 'L':
+			err = d.p.parseL(line, f)
+		default:
+			err = errorAt(1, 1, "invalid record")
+		}
+		if err != nil {
+			perr := newParseError(err, lines, d.line)
+			if !d.opts.Lenient {
+				return perr
+			}
+			errs = append(errs, perr)
+			continue
+		}
+
+		if record == nil {
+			continue
+		}
+		if err = header(); err != nil {
+			return err
+		}
+		switch r := record.(type) {
+		case Point:
+			err = v.VisitPoint(r)
+		case Event:
+			err = v.VisitEvent(r)
+		case Satellite:
+			err = v.VisitSatellite(r)
+		case K:
+			err = v.VisitK(r)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := header(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// next returns the next raw line, or false if there are no more lines.
+func (d *Decoder) next() (string, bool, error) {
+	d.line++
+	if len(d.pending) > 0 {
+		line := d.pending[0]
+		d.pending = d.pending[1:]
+		return line, true, nil
+	}
+	if d.eof {
+		return "", false, nil
+	}
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF {
+		d.eof = true
+		return line, true, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(line, "\n"), true, nil
+}
+
+// task returns the given C record line followed by all the lines needed to
+// parse the task declaration.
+//
+// The extra lines are read ahead and kept to be processed as usual.
+func (d *Decoder) task(first string) ([]string, error) {
+	lines := []string{first}
+	line := strings.TrimSpace(first)
+	if len(line) < 25 {
+		return lines, nil
+	}
+	nTP, err := strconv.Atoi(line[23:25])
+	if err != nil {
+		return lines, nil
+	}
+	for len(d.pending) < 4+nTP && !d.eof {
+		l, err := d.r.ReadString('\n')
+		if err == io.EOF {
+			d.eof = true
+		} else if err != nil {
+			return lines, err
+		}
+		d.pending = append(d.pending, strings.TrimSuffix(l, "\n"))
+	}
+	return append(lines, d.pending...), nil
+}
@@ -0,0 +1,146 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type countVisitor struct {
+	headers    int
+	points     int
+	events     int
+	k          int
+	satellites int
+	order      []string
+}
+
+func (c *countVisitor) VisitHeader(header Header) error {
+	c.headers++
+	c.order = append(c.order, "H")
+	return nil
+}
+
+func (c *countVisitor) VisitPoint(point Point) error {
+	c.points++
+	c.order = append(c.order, "B")
+	return nil
+}
+
+func (c *countVisitor) VisitEvent(event Event) error {
+	c.events++
+	c.order = append(c.order, "E")
+	return nil
+}
+
+func (c *countVisitor) VisitK(k K) error {
+	c.k++
+	c.order = append(c.order, "K")
+	return nil
+}
+
+func (c *countVisitor) VisitSatellite(satellite Satellite) error {
+	c.satellites++
+	c.order = append(c.order, "F")
+	return nil
+}
+
+func TestDecoderWalk(t *testing.T) {
+	f, err := os.Open("../../testdata/parse/parse-0-basic-flight.1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	v := &countVisitor{}
+	track, err := NewDecoder(f).Walk(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(v.order, "") != "HFEBKB" {
+		t.Errorf("expected HFEBKB got %v", strings.Join(v.order, ""))
+	}
+	if len(track.Points) != 0 {
+		t.Errorf("expected no points in track got %v", len(track.Points))
+	}
+	if len(track.Task.Turnpoints) != 2 {
+		t.Errorf("expected 2 task turnpoints got %v", len(track.Task.Turnpoints))
+	}
+	if track.Signature == "" {
+		t.Errorf("expected a signature")
+	}
+}
+
+func TestDecoderWalkHeaderOnly(t *testing.T) {
+	v := &countVisitor{}
+	_, err := NewDecoder(strings.NewReader("AFLA001\nHFDTE010203\n")).Walk(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.headers != 1 {
+		t.Errorf("expected 1 header got %v", v.headers)
+	}
+}
+
+type failVisitor struct {
+	NopVisitor
+	points int
+}
+
+func (f *failVisitor) VisitPoint(point Point) error {
+	f.points++
+	return errors.New("stop")
+}
+
+func TestDecoderWalkVisitorError(t *testing.T) {
+	f, err := os.Open("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	v := &failVisitor{}
+	_, err = NewDecoder(f).Walk(v)
+	if err == nil || err.Error() != "stop" {
+		t.Errorf("expected visitor error got %v", err)
+	}
+	if v.points != 1 {
+		t.Errorf("expected decoding to stop after 1 point got %v", v.points)
+	}
+}
+
+func TestDecoderTaskAtEOF(t *testing.T) {
+	_, err := NewDecoder(strings.NewReader(
+		"C150701213841160701000102500KTri\nC5111359N00101899WEZ TAKEOFF")).Decode()
+	if err == nil {
+		t.Errorf("expected error for incomplete task declaration")
+	}
+}
+
+func BenchmarkDecoderWalk(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		f, err := os.Open("../../testdata/parse/benchmark-0.igc")
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _ = NewDecoder(f).Walk(NopVisitor{})
+		f.Close()
+	}
+}
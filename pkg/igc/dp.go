@@ -0,0 +1,132 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxDPTurnpoints is the max number of turnpoints supported by the DPOptimizer.
+const MaxDPTurnpoints = 6
+
+// NewDPOptimizer returns an Optimizer based on dynamic programming.
+//
+// It finds the exact optimal Task for Score functions which are a sum over
+// the task legs (such as Distance), considering up to MaxDPTurnpoints. Each
+// leg is scored by passing the Score function a Task with only a Start and
+// a Finish, so other Scores are refused (see Additive()).
+//
+// The complexity is O(n²·k) for n points and k turnpoints, so it is best to
+// pass it a simplified Track (see Track.Simplify()).
+func NewDPOptimizer() Optimizer {
+	return &dpOptimizer{}
+}
+
+type dpOptimizer struct{}
+
+func (o *dpOptimizer) Optimize(track Track, nPoints int, score Score) (Task, error) {
+	if nPoints < 1 || nPoints > MaxDPTurnpoints {
+		return Task{}, fmt.Errorf("%v turn points not supported by this optimizer", nPoints)
+	}
+	if !IsAdditive(score) {
+		return Task{}, fmt.Errorf("score not supported by this optimizer, it must be a sum over the legs (see Additive)")
+	}
+	points := track.Points
+	if len(points) < nPoints+2 {
+		return Task{}, fmt.Errorf("track has %v points, min %v required",
+			len(points), nPoints+2)
+	}
+
+	all := make([]int, len(points))
+	for i := range all {
+		all[i] = i
+	}
+	layers := make([][]int, nPoints+2)
+	for l := range layers {
+		layers[l] = all
+	}
+	path, _ := dpPath(points, layers, score)
+
+	legs := nPoints + 1
+	task := Task{
+		Start:      points[path[0]],
+		Turnpoints: make([]Point, nPoints),
+		Finish:     points[path[legs]],
+	}
+	for i := 1; i < legs; i++ {
+		task.Turnpoints[i-1] = points[path[i]]
+	}
+	return task, nil
+}
+
+// dpPath returns the path with the best score going through one point of
+// each layer, along with its score.
+//
+// Each layer holds indices into points, sorted, and the path indices must be
+// strictly increasing. Each leg is scored separately, by passing the Score
+// function a Task with only a Start and a Finish. The returned path is nil if
+// no such path exists.
+func dpPath(points []Point, layers [][]int, score Score) ([]int, float64) {
+	if len(layers) == 0 {
+		return nil, math.Inf(-1)
+	}
+	// best[l][i] is the best score finishing at layers[l][i], with prev[l][i]
+	// being the position of the previous point in layers[l-1]
+	best := make([][]float64, len(layers))
+	prev := make([][]int, len(layers))
+	best[0] = make([]float64, len(layers[0]))
+	for l := 1; l < len(layers); l++ {
+		best[l] = make([]float64, len(layers[l]))
+		prev[l] = make([]int, len(layers[l]))
+		for i, p := range layers[l] {
+			best[l][i] = math.Inf(-1)
+			for j, q := range layers[l-1] {
+				if q >= p {
+					break
+				}
+				if best[l-1][j] == math.Inf(-1) {
+					continue
+				}
+				leg := score(Task{Start: points[q], Finish: points[p]})
+				if v := best[l-1][j] + leg; v > best[l][i] {
+					best[l][i] = v
+					prev[l][i] = j
+				}
+			}
+		}
+	}
+
+	last := len(layers) - 1
+	finish := -1
+	for i := range layers[last] {
+		if best[last][i] > math.Inf(-1) && (finish < 0 || best[last][i] > best[last][finish]) {
+			finish = i
+		}
+	}
+	if finish < 0 {
+		return nil, math.Inf(-1)
+	}
+	path := make([]int, len(layers))
+	for l, i := last, finish; l >= 0; l-- {
+		path[l] = layers[l][i]
+		if l > 0 {
+			i = prev[l][i]
+		}
+	}
+	return path, best[last][finish]
+}
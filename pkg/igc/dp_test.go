@@ -0,0 +1,161 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestDPOptimize(t *testing.T) {
+	opt := NewDPOptimizer()
+
+	for _, test := range optimizeTests {
+		for tp, expected := range test.result {
+			t.Run(fmt.Sprintf("%v/%v", test.name, tp), func(t *testing.T) {
+				track, err := ParseLocation(filepath.Join("../../testdata/optimize", fmt.Sprintf("%v.igc", test.name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				task, err := opt.Optimize(track, tp, Distance)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result := task.Distance()
+				if !test.valid(result, tp) {
+					t.Errorf("expected %v got %v", expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestDPOptimizeDeclared(t *testing.T) {
+	opt := NewDPOptimizer()
+
+	for _, test := range declaredTests {
+		for tp, expected := range test.result {
+			t.Run(fmt.Sprintf("%v/%v", test.name, tp), func(t *testing.T) {
+				track, err := ParseLocation(filepath.Join("../../testdata/optimize", fmt.Sprintf("%v.igc", test.name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				track, err = track.Simplify(optimizeTolerance)
+				if err != nil {
+					t.Fatal(err)
+				}
+				task, err := opt.Optimize(track, tp, Distance)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result := task.Distance()
+				if result < expected*(1-errorMargin) {
+					t.Errorf("expected at least %v got %v", expected, result)
+				}
+			})
+		}
+	}
+}
+
+// northing scores each leg by the latitude gained, ignoring legs to the south.
+func northing(task Task) float64 {
+	points := append([]Point{task.Start}, task.Turnpoints...)
+	points = append(points, task.Finish)
+	s := 0.0
+	for i := 0; i < len(points)-1; i++ {
+		s += math.Max(0, (points[i+1].Lat - points[i].Lat).Degrees())
+	}
+	return s
+}
+
+func TestDPOptimizeBruteForce(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err = track.Simplify(optimizeTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scores := map[string]Score{"distance": Distance, "northing": Additive(northing)}
+	for name, score := range scores {
+		for tp := 1; tp <= 2; tp++ {
+			t.Run(fmt.Sprintf("%v/%v", name, tp), func(t *testing.T) {
+				expected, err := NewBruteForceOptimizer(false).Optimize(track, tp, score)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err := NewDPOptimizer().Optimize(track, tp, score)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if math.Abs(score(expected)-score(result)) > 1e-9 {
+					t.Errorf("expected %v got %v", score(expected), score(result))
+				}
+			})
+		}
+	}
+}
+
+func TestDPOptimizeNotAdditive(t *testing.T) {
+	track := Track{Points: []Point{NewPointFromLatLng(45, 5), NewPointFromLatLng(45.5, 5),
+		NewPointFromLatLng(45.25, 5.5), NewPointFromLatLng(45, 5.01)}}
+	for name, score := range map[string]Score{"triangle": Triangle, "northing": northing} {
+		if _, err := NewDPOptimizer().Optimize(track, 2, score); err == nil {
+			t.Errorf("%v :: expected error for score not additive", name)
+		}
+	}
+}
+
+func TestDPOptimizeTurnpoints(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := NewDPOptimizer()
+	for _, tp := range []int{0, MaxDPTurnpoints + 1} {
+		if _, err := opt.Optimize(track, tp, Distance); err == nil {
+			t.Errorf("expected error for %v turnpoints", tp)
+		}
+	}
+	track.Points = track.Points[:3]
+	if _, err := opt.Optimize(track, 2, Distance); err == nil {
+		t.Errorf("expected error for track with too few points")
+	}
+}
+
+func BenchmarkDPOptimize(b *testing.B) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-long-flight-1.igc")
+	if err != nil {
+		b.Fatal(err)
+	}
+	track, err = track.Simplify(optimizeTolerance)
+	if err != nil {
+		b.Fatal(err)
+	}
+	opt := NewDPOptimizer()
+	for tp := 1; tp <= MaxDPTurnpoints; tp++ {
+		b.Run(fmt.Sprintf("%v", tp), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = opt.Optimize(track, tp, Distance)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordSeparator is the line terminator defined in the IGC specification.
+const recordSeparator = "\r\n"
+
+// encodeIGC returns the track in the IGC format.
+//
+// Records are written in the order suggested by the IGC specification, with
+// the B, E, F and K records interleaved by time. The I and J records are
+// generated from the keys in Point.IData and K.Fields, respectively.
+//
+// The G record is written as is, which means the signature will no longer
+// validate if any of the track data was modified.
+func (track *Track) encodeIGC() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := func(format string, a ...interface{}) {
+		fmt.Fprintf(buf, format, a...)
+		buf.WriteString(recordSeparator)
+	}
+
+	if track.Manufacturer != "" {
+		w("A%v%v%v", track.Manufacturer, track.UniqueID, track.AdditionalData)
+	}
+	for _, h := range track.headerRecords() {
+		w("%v", h)
+	}
+
+	iFields, err := extensionFields(35, pointExtensions(track.Points))
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(iFields) > 0 {
+		w("%v", extensionRecord('I', iFields))
+	}
+	jFields, err := extensionFields(7, kExtensions(track.K))
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(jFields) > 0 {
+		w("%v", extensionRecord('J', jFields))
+	}
+
+	if !reflect.DeepEqual(track.Task, Task{}) {
+		for _, c := range track.Task.records() {
+			w("%v", c)
+		}
+	}
+	if track.DGPSStationID != "" {
+		w("D2%v", track.DGPSStationID)
+	}
+
+	for _, r := range track.bodyRecords(iFields, jFields) {
+		w("%v", r)
+	}
+
+	for _, l := range track.Logbook {
+		w("L%v", l)
+	}
+	if track.Signature != "" {
+		w("G%v", track.Signature)
+	}
+	return buf.Bytes(), nil
+}
+
+func (track *Track) headerRecords() []string {
+	h := []string{}
+	add := func(code string, value string) {
+		if value != "" {
+			h = append(h, fmt.Sprintf("HF%v%v", code, value))
+		}
+	}
+
+	if !track.Date.IsZero() {
+		add("DTE", "DATE:"+track.Date.Format(DateFormat))
+	}
+	if track.FixAccuracy != 0 {
+		add("FXA", fmt.Sprintf("%03d", track.FixAccuracy))
+	}
+	add("PLT", prefixed("PILOTINCHARGE", track.Pilot))
+	add("CM2", prefixed("CREW2", track.Crew))
+	add("GTY", prefixed("GLIDERTYPE", track.GliderType))
+	add("GID", prefixed("GLIDERID", track.GliderID))
+	add("DTM", prefixed("GPSDATUM", track.GPSDatum))
+	add("RFW", prefixed("FIRMWAREVERSION", track.FirmwareVersion))
+	add("RHW", prefixed("HARDWAREVERSION", track.HardwareVersion))
+	add("FTY", prefixed("FRTYPE", track.FlightRecorder))
+	add("GPS", track.GPS)
+	add("PRS", prefixed("PRESSALTSENSOR", track.PressureSensor))
+	add("CID", prefixed("COMPETITIONID", track.CompetitionID))
+	add("CCL", prefixed("COMPETITIONCLASS", track.CompetitionClass))
+	if track.Timezone != 0 {
+		add("TZN", fmt.Sprintf("TIMEZONE:%d", track.Timezone))
+	}
+	if track.AltimeterPressure != 0 {
+		// stored in hPa, recorded in hundredths of hPa
+		ats := math.Round(track.AltimeterPressure*10000) / 100
+		add("ATS", "ATS:"+strconv.FormatFloat(ats, 'f', -1, 64))
+	}
+	if !track.PilotBirth.IsZero() {
+		add("DB1", "PILOTBIRTHDATE:"+track.PilotBirth.Format(DateFormat))
+	}
+	add("MOP", prefixed("MOPSENSOR", track.MOPSensor))
+	add("SIT", prefixed("SITE", track.Site))
+	add("OOI", prefixed("OOID", track.Observation))
+	add("SOF", prefixed("SOFTWARE", track.SoftwareVersion))
+	add("FSP", prefixed("SPECIFICATION", track.Specification))
+	add("ALG", prefixed("GNSSMODEL", track.GNSSModel))
+	add("ALP", prefixed("PRESSUREMODEL", track.PressureModel))
+	return h
+}
+
+// records returns the C records for the task declaration.
+func (task *Task) records() []string {
+	c := []string{}
+
+	declaration := strings.Repeat("0", 12)
+	if !task.DeclarationDate.IsZero() {
+		declaration = task.DeclarationDate.Format(DateFormat + TimeFormat)
+	}
+	date := strings.Repeat("0", 6)
+	if !task.Date.IsZero() {
+		date = task.Date.Format(DateFormat)
+	}
+	c = append(c, fmt.Sprintf("C%v%v%04d%02d%v", declaration, date,
+		task.Number, len(task.Turnpoints), task.Description))
+
+	points := []Point{task.Takeoff, task.Start}
+	points = append(points, task.Turnpoints...)
+	points = append(points, task.Finish, task.Landing)
+	for _, p := range points {
+		c = append(c, fmt.Sprintf("C%v%v%v", latDMD(p.Lat.Degrees()),
+			lngDMD(p.Lng.Degrees()), p.Description))
+	}
+	return c
+}
+
+// timedRecord is a B, E, F or K record with its position in the track.
+type timedRecord struct {
+	seconds int
+	order   int
+	line    string
+}
+
+// bodyRecords returns the B, E, F and K records sorted by time.
+//
+// When multiple records share the same time, the F record comes first so
+// that the number of satellites is set before the B record is parsed.
+func (track *Track) bodyRecords(iFields []field, jFields []field) []string {
+	records := []timedRecord{}
+
+	times := make([]time.Time, len(track.Satellites))
+	for i, s := range track.Satellites {
+		times[i] = s.Time
+	}
+	for i, s := range daySeconds(times) {
+		records = append(records, timedRecord{s, 0,
+			fmt.Sprintf("F%v%v", track.Satellites[i].Time.Format(TimeFormat),
+				strings.Join(track.Satellites[i].Ids, ""))})
+	}
+
+	times = make([]time.Time, len(track.Events))
+	for i, e := range track.Events {
+		times[i] = e.Time
+	}
+	for i, s := range daySeconds(times) {
+		e := track.Events[i]
+		records = append(records, timedRecord{s, 1,
+			fmt.Sprintf("E%v%v%v", e.Time.Format(TimeFormat), e.Type, e.Data)})
+	}
+
+	times = make([]time.Time, len(track.Points))
+	for i, p := range track.Points {
+		times[i] = p.Time
+	}
+	for i, s := range daySeconds(times) {
+		p := track.Points[i]
+		validity := p.FixValidity
+		if validity != 'A' && validity != 'V' {
+			validity = 'A'
+		}
+		records = append(records, timedRecord{s, 2,
+			fmt.Sprintf("B%v%v%v%c%05d%05d%v", p.Time.Format(TimeFormat),
+				latDMD(p.Lat.Degrees()), lngDMD(p.Lng.Degrees()), validity,
+				p.PressureAltitude, p.GNSSAltitude, extensionValues(iFields, p.IData))})
+	}
+
+	times = make([]time.Time, len(track.K))
+	for i, k := range track.K {
+		times[i] = k.Time
+	}
+	for i, s := range daySeconds(times) {
+		k := track.K[i]
+		records = append(records, timedRecord{s, 3,
+			fmt.Sprintf("K%v%v", k.Time.Format(TimeFormat), extensionValues(jFields, k.Fields))})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].seconds != records[j].seconds {
+			return records[i].seconds < records[j].seconds
+		}
+		return records[i].order < records[j].order
+	})
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = r.line
+	}
+	return lines
+}
+
+// daySeconds returns the seconds since the start of the first day for each
+// of the given times, handling flights crossing midnight UTC.
+func daySeconds(times []time.Time) []int {
+	result := make([]int, len(times))
+	offset := 0
+	previous := 0
+	for i, t := range times {
+		s := t.Hour()*3600 + t.Minute()*60 + t.Second()
+		if i > 0 && s+12*3600 < previous {
+			offset += 24 * 3600
+		}
+		previous = s
+		result[i] = s + offset
+	}
+	return result
+}
+
+func pointExtensions(points []Point) []map[string]string {
+	result := make([]map[string]string, len(points))
+	for i, p := range points {
+		result[i] = p.IData
+	}
+	return result
+}
+
+func kExtensions(k []K) []map[string]string {
+	result := make([]map[string]string, len(k))
+	for i, v := range k {
+		result[i] = v.Fields
+	}
+	return result
+}
+
+// extensionFields returns the I or J record fields for the given values.
+//
+// Fields are sorted by their three letter code and placed right after the
+// fixed part of the record, which has the given size. The width of each field
+// is the largest of all its values.
+func extensionFields(size int64, values []map[string]string) ([]field, error) {
+	widths := make(map[string]int64)
+	for _, v := range values {
+		for tlc, value := range v {
+			if int64(len(value)) > widths[tlc] {
+				widths[tlc] = int64(len(value))
+			}
+		}
+	}
+	tlcs := make([]string, 0, len(widths))
+	for tlc := range widths {
+		if len(tlc) != 3 {
+			return []field{}, fmt.Errorf("invalid extension code :: %v", tlc)
+		}
+		tlcs = append(tlcs, tlc)
+	}
+	sort.Strings(tlcs)
+
+	fields := make([]field, len(tlcs))
+	start := size + 1
+	for i, tlc := range tlcs {
+		fields[i] = field{start: start, end: start + widths[tlc] - 1, tlc: tlc}
+		start = fields[i].end + 1
+	}
+	if start > 100 {
+		return []field{}, fmt.Errorf("extensions exceed max record size :: %v", tlcs)
+	}
+	return fields, nil
+}
+
+func extensionRecord(record byte, fields []field) string {
+	r := fmt.Sprintf("%c%02d", record, len(fields))
+	for _, f := range fields {
+		r = r + fmt.Sprintf("%02d%02d%v", f.start, f.end, f.tlc)
+	}
+	return r
+}
+
+func extensionValues(fields []field, values map[string]string) string {
+	r := ""
+	for _, f := range fields {
+		r = r + fmt.Sprintf("%-*v", f.end-f.start+1, values[f.tlc])
+	}
+	return r
+}
+
+func prefixed(prefix string, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + ":" + value
+}
+
+// latDMD returns the DMD representation of the given latitude in degrees.
+//
+// This is the inverse of DecimalFromDMD, example: 5107126N.
+func latDMD(lat float64) string {
+	d, m := splitDMD(lat)
+	hemisphere := 'N'
+	if lat < 0 {
+		hemisphere = 'S'
+	}
+	return fmt.Sprintf("%02d%05d%c", d, m, hemisphere)
+}
+
+// lngDMD returns the DMD representation of the given longitude in degrees.
+//
+// This is the inverse of DecimalFromDMD, example: 00149300W.
+func lngDMD(lng float64) string {
+	d, m := splitDMD(lng)
+	hemisphere := 'E'
+	if lng < 0 {
+		hemisphere = 'W'
+	}
+	return fmt.Sprintf("%03d%05d%c", d, m, hemisphere)
+}
+
+// splitDMD returns the degrees and thousandths of minutes of the given value.
+func splitDMD(v float64) (int, int) {
+	total := int(math.Round(math.Abs(v) * 60000))
+	return total / 60000, total % 60000
+}
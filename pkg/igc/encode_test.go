@@ -0,0 +1,254 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeIGC(t *testing.T) {
+	match, err := filepath.Glob("../../testdata/parse/parse-*.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, golden := range match {
+		t.Run(golden, func(t *testing.T) {
+			b, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var track Track
+			if err = json.Unmarshal(b, &track); err != nil {
+				t.Fatal(err)
+			}
+			expectedJSON, err := json.MarshalIndent(track, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := track.Encode("igc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Parse(string(content))
+			if err != nil {
+				t.Fatalf("%v :: %v", err, string(content))
+			}
+			resultJSON, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(resultJSON) != string(expectedJSON) {
+				t.Errorf("expected\n%v\ngot\n%v\nfrom\n%v", string(expectedJSON),
+					string(resultJSON), string(content))
+			}
+		})
+	}
+}
+
+func TestEncodeIGCDMD(t *testing.T) {
+	tests := []string{"5107126N", "4453183S", "00149300W", "00512633E", "17959999E"}
+	for _, test := range tests {
+		p := NewPointFromDMD(test, test)
+		var result string
+		if strings.HasSuffix(test, "N") || strings.HasSuffix(test, "S") {
+			result = latDMD(p.Lat.Degrees())
+		} else {
+			result = lngDMD(p.Lng.Degrees())
+		}
+		if result != test {
+			t.Errorf("expected %v got %v", test, result)
+		}
+	}
+}
+
+func TestEncodePhasesIGC(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := track.EncodePhases("igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Parse(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != len(track.Points) {
+		t.Errorf("expected %v got %v points", len(track.Points), len(result.Points))
+	}
+	if len(result.Logbook) != len(track.Logbook)+len(phases) {
+		t.Errorf("expected %v got %v L records", len(track.Logbook)+len(phases), len(result.Logbook))
+	}
+}
+
+func TestEncodeGPX(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := track.Encode("gpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseWithOptions(string(content), ParseOptions{Format: "gpx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != len(track.Points) {
+		t.Fatalf("expected %v got %v points", len(track.Points), len(result.Points))
+	}
+	p, e := result.Points[1], track.Points[1]
+	if p.GNSSAltitude != e.GNSSAltitude || !p.Time.Equal(e.Time) || p.IData["FXA"] != e.IData["FXA"] {
+		t.Errorf("expected %+v got %+v", e, p)
+	}
+}
+
+func TestEncodeGPXNoTime(t *testing.T) {
+	track := NewTrack()
+	track.Points = []Point{NewPointFromLatLng(45, 5), NewPointFromLatLng(45.1, 5)}
+	content, err := track.Encode("gpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "<time>") {
+		t.Errorf("expected no time elements without a date got\n%v", string(content))
+	}
+	result, err := ParseWithOptions(string(content), ParseOptions{Format: "gpx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != 2 || !result.Points[0].Time.IsZero() {
+		t.Errorf("expected 2 points without time got %+v", result.Points)
+	}
+}
+
+func TestEncodeGeoJSONGap(t *testing.T) {
+	track := straightTrack(0, 0, 0.01, 0.02, 0.03)
+	track.Points[2].Time = track.Points[1].Time.Add(2 * GeoJSONMaxGap)
+	track.Points[3].Time = track.Points[2].Time.Add(time.Second)
+	content, err := track.Encode("geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feature struct {
+		Geometry struct {
+			Type        string
+			Coordinates [][][]float64
+		}
+	}
+	if err := json.Unmarshal(content, &feature); err != nil {
+		t.Fatal(err)
+	}
+	if feature.Geometry.Type != "MultiLineString" || len(feature.Geometry.Coordinates) != 2 {
+		t.Errorf("expected MultiLineString with 2 lines got %+v", feature.Geometry)
+	}
+}
+
+func TestEncodePointsCSV(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, comma := range map[string]rune{"points-csv": ',', "points-tsv": '\t'} {
+		t.Run(format, func(t *testing.T) {
+			content, err := track.Encode(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := csv.NewReader(bytes.NewReader(content))
+			r.Comma = comma
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != len(track.Points)+1 {
+				t.Fatalf("expected %v got %v rows", len(track.Points)+1, len(records))
+			}
+			header := strings.Join(records[0], ",")
+			expected := "Time,Lat,Lng,PressureAltitude,GNSSAltitude,FixValidity,NumSatellites,ENL,FXA,SIU,Speed,Vario,Bearing,Distance,Phase"
+			if header != expected {
+				t.Errorf("expected header %v got %v", expected, header)
+			}
+			last := records[len(records)-1]
+			distance, _ := strconv.ParseFloat(last[13], 64)
+			total := 0.0
+			for i := 1; i < len(track.Points); i++ {
+				total += track.Points[i-1].Distance(track.Points[i])
+			}
+			if math.Abs(distance-total) > 0.001 {
+				t.Errorf("expected distance %v got %v", total, distance)
+			}
+			phases := make(map[string]bool)
+			for _, record := range records[1:] {
+				phases[record[14]] = true
+			}
+			if !phases[Circling.String()] || !phases[Cruising.String()] {
+				t.Errorf("expected circling and cruising phases got %v", phases)
+			}
+		})
+	}
+
+	// a single point has no phases, and is unknown
+	single := Track{Points: track.Points[:1]}
+	content, err := single.Encode("points-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(content)), ","+Unknown.String()) {
+		t.Errorf("expected unknown phase for a single point got %v", string(content))
+	}
+}
+
+func TestEncodePointsCSVVario(t *testing.T) {
+	// the gnss sensor failed, so the altitudes come from the pressure sensor
+	start := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	track := NewTrack()
+	for i := 0; i < 10; i++ {
+		p := NewPointFromLatLng(45+float64(i)*0.001, 5)
+		p.Time = start.Add(time.Duration(i) * time.Second)
+		p.PressureAltitude = 1000 + int64(i)*2
+		track.Points = append(track.Points, p)
+	}
+	content, err := track.Encode("points-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records[2:] {
+		if vario, _ := strconv.ParseFloat(record[8], 64); math.Abs(vario-2) > 0.5 {
+			t.Errorf("expected vario ~2 got %v", vario)
+		}
+	}
+}
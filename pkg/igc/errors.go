@@ -0,0 +1,92 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseError holds the details of an invalid record found while parsing.
+//
+// Line and columns are 1-based, with End being inclusive. Text holds the
+// full content of the offending line and Err the underlying cause.
+type ParseError struct {
+	Record byte
+	Line   int
+	Start  int
+	End    int
+	Text   string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v :: line %d columns %d-%d :: %v",
+		e.Err, e.Line, e.Start, e.End, e.Text)
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError returns a ParseError for the record starting at the given line.
+func newParseError(err error, lines []string, number int) *ParseError {
+	e := &ParseError{Line: number, Err: err}
+	var ce *columnError
+	if errors.As(err, &ce) {
+		e.Line = number + ce.offset
+		e.Start = ce.start
+		e.End = ce.end
+		e.Err = ce.err
+	}
+	if e.Line-number < len(lines) {
+		e.Text = strings.TrimSpace(lines[e.Line-number])
+	}
+	if len(e.Text) > 0 {
+		e.Record = e.Text[0]
+	}
+	if e.Start == 0 {
+		e.Start, e.End = 1, len(e.Text)
+	}
+	return e
+}
+
+// ParseErrors holds all errors found when parsing in lenient mode.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors found :: %v", len(e), strings.Join(msgs, "; "))
+}
+
+// ParseOptions holds configuration for the parser.
+//
+// When Lenient is set, invalid records are skipped instead of aborting the
+// parsing. The result is a Track with all the valid records, and an error of
+// type ParseErrors listing all skipped lines.
+//
+// Format is the name of the input format (see Formats()), detected from the
+// content if empty.
+type ParseOptions struct {
+	Lenient bool
+	Format  string
+}
@@ -0,0 +1,135 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type parseErrorTest struct {
+	name    string
+	content string
+	err     ParseError
+}
+
+var parseErrorTests = []parseErrorTest{
+	{
+		name:    "invalid-record",
+		content: "AFLA001\nXFOO",
+		err:     ParseError{Record: 'X', Line: 2, Start: 1, End: 1, Text: "XFOO"},
+	},
+	{
+		name:    "b-too-short",
+		content: "AFLA001\n\nB1602455107126N",
+		err:     ParseError{Record: 'B', Line: 3, Start: 16, End: 35, Text: "B1602455107126N"},
+	},
+	{
+		name:    "b-bad-pressure-altitude",
+		content: "B1602455107126N00149300WA0028a0042919509020",
+		err: ParseError{Record: 'B', Line: 1, Start: 26, End: 30,
+			Text: "B1602455107126N00149300WA0028a0042919509020"},
+	},
+	{
+		name:    "h-unknown",
+		content: "HFDTE010203\nHFXXXfoo",
+		err:     ParseError{Record: 'H', Line: 2, Start: 3, End: 5, Text: "HFXXXfoo"},
+	},
+	{
+		name:    "h-bad-timezone",
+		content: "HFTZNTimezone:abc",
+		err:     ParseError{Record: 'H', Line: 1, Start: 15, End: 17, Text: "HFTZNTimezone:abc"},
+	},
+	{
+		name: "c-invalid-tp",
+		content: "C150701213841160701000102500KTri\nC5111359N00101899WEZ TAKEOFF\n" +
+			"C5110179N00102644WEZ START\nC5209\nC5230147N00017612WEZ TP2\n" +
+			"C5110179N00102644WEZ FINISH\nC5111359N00101899WEZ LANDING",
+		err: ParseError{Record: 'C', Line: 4, Start: 6, End: 18, Text: "C5209"},
+	},
+}
+
+func TestParseError(t *testing.T) {
+	for _, test := range parseErrorTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.content)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a ParseError got %v", err)
+			}
+			if perr.Record != test.err.Record || perr.Line != test.err.Line ||
+				perr.Start != test.err.Start || perr.End != test.err.End ||
+				perr.Text != test.err.Text {
+				t.Errorf("expected %+v got %+v", test.err, *perr)
+			}
+			if perr.Err == nil {
+				t.Errorf("expected a cause for %+v", *perr)
+			}
+		})
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	_, err := Parse("B1602455107126N00149300WA0028a0042919509020")
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("expected strconv.ErrSyntax cause got %v", err)
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	content := `AFLA001
+HFDTE010203
+HFXXXfoo
+C150701213841160701000102500KTri
+C5111359N00101899WEZ TAKEOFF
+C5209
+B1602455107126N00149300WA002880042919509020
+Bbad
+B1603105107212N00149174WV002930043519608024
+`
+	track, err := ParseWithOptions(content, ParseOptions{Lenient: true})
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("expected ParseErrors got %v", err)
+	}
+	lines := []int{3, 6, 8}
+	if len(errs) != len(lines) {
+		t.Fatalf("expected %v errors got %v", len(lines), errs)
+	}
+	for i, l := range lines {
+		if errs[i].Line != l {
+			t.Errorf("expected error at line %v got %v", l, errs[i].Line)
+		}
+	}
+	if len(track.Points) != 2 {
+		t.Errorf("expected 2 points got %v", len(track.Points))
+	}
+	if track.Manufacturer != "FLA" || track.Date.IsZero() {
+		t.Errorf("expected header to be parsed got %+v", track.Header)
+	}
+	if len(track.Task.Turnpoints) != 0 {
+		t.Errorf("expected invalid task to be dropped got %+v", track.Task)
+	}
+}
+
+func TestParseLenientNoErrors(t *testing.T) {
+	_, err := ParseWithOptions("AFLA001\nHFDTE010203\n", ParseOptions{Lenient: true})
+	if err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
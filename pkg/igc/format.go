@@ -0,0 +1,155 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// detectSize is the number of bytes read to detect the input format.
+const detectSize = 1024
+
+// Format decodes tracks in a given file format.
+//
+// Detect returns true if the given content, the start of the input, is in
+// this format. Decode returns the Track with all the content in r.
+type Format interface {
+	Detect(head []byte) bool
+	Decode(r io.Reader, opts ParseOptions) (Track, error)
+}
+
+type namedFormat struct {
+	name   string
+	format Format
+}
+
+var (
+	formatsMu sync.RWMutex
+	// formats are checked in order when detecting the input format, with igc
+	// being the default when none matches
+	formats = []namedFormat{
+		{"igc", igcFormat{}},
+		{"gpx", gpxFormat{}},
+		{"kml", kmlFormat{}},
+		{"nmea", nmeaFormat{}},
+	}
+)
+
+// RegisterFormat makes the Format available with the given name.
+//
+// Registering a Format with an existing name replaces the previous one, or
+// it is checked right after igc, before all the others, when detecting the
+// format.
+func RegisterFormat(name string, f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i, v := range formats {
+		if v.name == name {
+			formats[i].format = f
+			return
+		}
+	}
+	formats = append(formats[:1], append([]namedFormat{{name, f}}, formats[1:]...)...)
+}
+
+// Formats returns the names of all available formats.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, len(formats))
+	for i, v := range formats {
+		names[i] = v.name
+	}
+	return names
+}
+
+// decodeFormat returns the Track in r, in the format given in opts or the
+// one detected from its content.
+func decodeFormat(r io.Reader, opts ParseOptions) (Track, error) {
+	// the lock is not held while reading, which might be from the network
+	formatsMu.RLock()
+	available := make([]namedFormat, len(formats))
+	copy(available, formats)
+	formatsMu.RUnlock()
+
+	if opts.Format != "" {
+		for _, v := range available {
+			if v.name == opts.Format {
+				return v.format.Decode(r, opts)
+			}
+		}
+		return Track{}, fmt.Errorf("unsupported format '%v'", opts.Format)
+	}
+
+	br := bufio.NewReaderSize(r, detectSize)
+	head, err := br.Peek(detectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return Track{}, err
+	}
+	for _, v := range available {
+		if v.format.Detect(head) {
+			return v.format.Decode(br, opts)
+		}
+	}
+	return igcFormat{}.Decode(br, opts)
+}
+
+// igcFormat decodes tracks in the IGC format.
+type igcFormat struct{}
+
+// Detect returns true if the content starts with an A record, the letter A
+// followed by the three characters of the manufacturer code.
+func (igcFormat) Detect(head []byte) bool {
+	head = bytes.TrimSpace(head)
+	if len(head) < 4 || head[0] != 'A' {
+		return false
+	}
+	for _, c := range head[1:4] {
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlRoot returns the name of the root element in the content, or an empty
+// string if it is not xml.
+func xmlRoot(head []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(head))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return ""
+			}
+		}
+	}
+}
+
+func (igcFormat) Decode(r io.Reader, opts ParseOptions) (Track, error) {
+	return NewDecoderWithOptions(r, opts).Decode()
+}
@@ -0,0 +1,190 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+)
+
+const decodeTolerance = 0.00001
+
+func TestDecodeFormats(t *testing.T) {
+	trk, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := trk.Points[:60]
+
+	for _, ext := range []string{"gpx", "kml", "nmea"} {
+		for _, format := range []string{"", ext} {
+			t.Run(fmt.Sprintf("%v-%v", ext, format), func(t *testing.T) {
+				f := fmt.Sprintf("../../testdata/decode/decode-short-flight-1.%v", ext)
+				result, err := ParseLocationWithOptions(f, ParseOptions{Format: format})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result.Points) != len(expected) {
+					t.Fatalf("expected %v got %v points", len(expected), len(result.Points))
+				}
+				if result.Date.Format(DateFormat) != "090817" {
+					t.Errorf("expected date 090817 got %v", result.Date)
+				}
+				for i, p := range result.Points {
+					e := expected[i]
+					if math.Abs(p.Lat.Degrees()-e.Lat.Degrees()) > decodeTolerance ||
+						math.Abs(p.Lng.Degrees()-e.Lng.Degrees()) > decodeTolerance {
+						t.Errorf("point %v expected %v got %v", i, e.LatLng, p.LatLng)
+					}
+					if p.GNSSAltitude != e.GNSSAltitude {
+						t.Errorf("point %v expected altitude %v got %v", i, e.GNSSAltitude, p.GNSSAltitude)
+					}
+					if p.Time.Format(TimeFormat) != e.Time.Format(TimeFormat) {
+						t.Errorf("point %v expected time %v got %v", i, e.Time, p.Time)
+					}
+				}
+				if _, err := result.Phases(); err != nil {
+					t.Errorf("failed to compute phases :: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeUnknownFormat(t *testing.T) {
+	_, err := ParseWithOptions("", ParseOptions{Format: "unknown"})
+	if err == nil {
+		t.Errorf("no error returned for unknown format")
+	}
+}
+
+func TestDecodeNMEAChecksum(t *testing.T) {
+	f := "../../testdata/decode/decode-bad-checksum.nmea"
+	_, err := ParseLocation(f)
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("expected ParseError got %v", err)
+	}
+	trk, err := ParseLocationWithOptions(f, ParseOptions{Lenient: true})
+	if errs, ok := err.(ParseErrors); !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 ParseError got %v", err)
+	}
+	if len(trk.Points) == 0 {
+		t.Errorf("expected points in lenient mode")
+	}
+}
+
+func TestDecodeNMEANoFix(t *testing.T) {
+	b, err := ioutil.ReadFile("../../testdata/decode/decode-short-flight-1.nmea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sentences logged before the first fix, without a position
+	content := "$GPGGA,121230.00,,,,,0,00,,,M,,M,,\n" +
+		"$GPRMC,121231.00,V,,,,,,,090817,,,N\n" + string(b)
+	trk, err := ParseWithOptions(content, ParseOptions{Format: "nmea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trk.Points) != 60 {
+		t.Fatalf("expected 60 points got %v", len(trk.Points))
+	}
+	if p := trk.Points[0]; p.Lat.Degrees() == 0 || p.Lng.Degrees() == 0 {
+		t.Errorf("expected first point with a position got %v", p.LatLng)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "igc", content: "AXXXABC\nHFPLTPILOTINCHARGE:<gpx>\n", expected: "igc"},
+		{name: "igc-space", content: "\n  AXCS000\n", expected: "igc"},
+		{name: "gpx", content: "<?xml version=\"1.0\"?>\n<!-- exported -->\n<gpx version=\"1.1\">", expected: "gpx"},
+		{name: "gpx-no-decl", content: "<gpx>", expected: "gpx"},
+		{name: "kml", content: "<?xml version=\"1.0\"?>\n<kml xmlns=\"http://www.opengis.net/kml/2.2\">", expected: "kml"},
+		{name: "kml-not-root", content: "<gpx><!-- <kml> --></gpx>", expected: "gpx"},
+		{name: "text-with-gpx", content: "some text <gpx>"},
+		{name: "nmea", content: "$GPGGA,121243.00,4723.238,N,00456.892,E,1,08,0.9,0.0,M,47.0,M,,", expected: "nmea"},
+		{name: "a-not-igc-record", content: "A b"},
+	}
+	for _, test := range tests {
+		detected := ""
+		for _, f := range formats {
+			if f.format.Detect([]byte(test.content)) {
+				detected = f.name
+				break
+			}
+		}
+		if detected != test.expected {
+			t.Errorf("%v :: expected format '%v' got '%v'", test.name, test.expected, detected)
+		}
+	}
+}
+
+func TestDecodeKMLLenient(t *testing.T) {
+	b, err := ioutil.ReadFile("../../testdata/decode/decode-short-flight-1.kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Replace(string(b), "<when>2017-08-09T12:12:47Z</when>", "<when>invalid</when>", 1)
+	_, err = ParseWithOptions(content, ParseOptions{Format: "kml"})
+	if e, ok := err.(*ParseError); !ok || e.Text != "invalid" || e.Line != 10 {
+		t.Fatalf("expected ParseError in line 10 got %v", err)
+	}
+	trk, err := ParseWithOptions(content, ParseOptions{Format: "kml", Lenient: true})
+	if errs, ok := err.(ParseErrors); !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 ParseError got %v", err)
+	}
+	if len(trk.Points) != 59 {
+		t.Errorf("expected 59 points got %v", len(trk.Points))
+	}
+}
+
+type testFormat struct{}
+
+func (testFormat) Detect(head []byte) bool {
+	return strings.HasPrefix(string(head), "TEST")
+}
+
+func (testFormat) Decode(r io.Reader, opts ParseOptions) (Track, error) {
+	trk := NewTrack()
+	trk.Pilot = "test"
+	return trk, nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("test", testFormat{})
+	trk, err := Parse("TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trk.Pilot != "test" {
+		t.Errorf("registered format not detected")
+	}
+	found := false
+	for _, name := range Formats() {
+		found = found || name == "test"
+	}
+	if !found {
+		t.Errorf("registered format missing in %v", Formats())
+	}
+}
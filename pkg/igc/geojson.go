@@ -0,0 +1,142 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// GeoJSONMaxGap is the max time between two consecutive points in the same
+// GeoJSON line. Tracks with longer gaps are encoded as a MultiLineString.
+const GeoJSONMaxGap = 60 * time.Second
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties interface{}     `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONPhase holds the properties of a phase feature.
+type geoJSONPhase struct {
+	Type          string    `json:"type"`
+	CirclingType  int       `json:"circlingType"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Duration      float64   `json:"duration"`
+	AltGain       int64     `json:"altGain"`
+	AvgVario      float64   `json:"avgVario"`
+	TopVario      float64   `json:"topVario"`
+	AvgGndSpeed   float64   `json:"avgGndSpeed"`
+	TopGndSpeed   float64   `json:"topGndSpeed"`
+	Distance      float64   `json:"distance"`
+	LD            float64   `json:"ld"`
+	WindSpeed     float64   `json:"windSpeed"`
+	WindDirection float64   `json:"windDirection"`
+	AvgAirSpeed   float64   `json:"avgAirSpeed"`
+}
+
+// encodeGeoJSON returns the track as a single GeoJSON Feature, with the
+// header fields as properties.
+func (track *Track) encodeGeoJSON() ([]byte, error) {
+	return json.MarshalIndent(track.geoJSONFeature(), "", "  ")
+}
+
+// encodePhasesGeoJSON returns a GeoJSON FeatureCollection with the track
+// Feature followed by one Feature per phase, including the phase stats.
+func (track *Track) encodePhasesGeoJSON(phases []Phase) ([]byte, error) {
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{track.geoJSONFeature()},
+	}
+	for _, p := range phases {
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: geoJSONCoordinates(track.Points[p.StartIndex : p.EndIndex+1]),
+			},
+			Properties: geoJSONPhase{
+				Type:          p.Type.String(),
+				CirclingType:  int(p.CirclingType),
+				Start:         p.Start.Time,
+				End:           p.End.Time,
+				Duration:      p.Duration().Seconds(),
+				AltGain:       p.EndAltitude - p.StartAltitude,
+				AvgVario:      p.AvgVario,
+				TopVario:      p.TopVario,
+				AvgGndSpeed:   p.AvgGndSpeed,
+				TopGndSpeed:   p.TopGndSpeed,
+				Distance:      p.Distance,
+				LD:            p.LD,
+				WindSpeed:     p.WindSpeed,
+				WindDirection: p.WindDirection,
+				AvgAirSpeed:   p.AvgAirSpeed,
+			},
+		})
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+// geoJSONFeature returns the track line, split where the gap between points
+// is longer than GeoJSONMaxGap.
+func (track *Track) geoJSONFeature() geoJSONFeature {
+	var lines [][][]float64
+	start := 0
+	for i := 1; i <= len(track.Points); i++ {
+		if i == len(track.Points) || track.Points[i].Time.Sub(track.Points[i-1].Time) > GeoJSONMaxGap {
+			lines = append(lines, geoJSONCoordinates(track.Points[start:i]))
+			start = i
+		}
+	}
+
+	geometry := geoJSONGeometry{Type: "MultiLineString", Coordinates: lines}
+	if len(lines) == 1 {
+		geometry = geoJSONGeometry{Type: "LineString", Coordinates: lines[0]}
+	} else if len(lines) == 0 {
+		geometry.Coordinates = [][][]float64{}
+	}
+	return geoJSONFeature{
+		Type:       "Feature",
+		Geometry:   geometry,
+		Properties: track.Header,
+	}
+}
+
+// geoJSONCoordinates returns the lng, lat and altitude of the given points,
+// with 7 decimal places (around 1cm).
+func geoJSONCoordinates(points []Point) [][]float64 {
+	coords := make([][]float64, len(points))
+	for i, p := range points {
+		coords[i] = []float64{
+			math.Round(p.Lng.Degrees()*1e7) / 1e7,
+			math.Round(p.Lat.Degrees()*1e7) / 1e7,
+			float64(p.GNSSAltitude),
+		}
+	}
+	return coords
+}
@@ -0,0 +1,218 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	// gpxExtensionsNamespace is used for the point IData extensions
+	gpxExtensionsNamespace = "https://github.com/ezgliding/goigc"
+)
+
+// gpxFormat decodes tracks in the GPX 1.1 format.
+//
+// All track points (trk/trkseg/trkpt) are added to the track, in order. The
+// elevation is kept as the GNSSAltitude, and any point extensions as IData.
+// The document is decoded as a whole, so lenient mode has no effect and any
+// invalid value fails the decoding.
+type gpxFormat struct{}
+
+type gpxFile struct {
+	XMLName  xml.Name    `xml:"gpx"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Tracks   []gpxTrack  `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name   string     `xml:"name,omitempty"`
+	Author string     `xml:"author>name,omitempty"`
+	Time   *time.Time `xml:"time,omitempty"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Desc     string       `xml:"desc,omitempty"`
+	Type     string       `xml:"type,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat        float64        `xml:"lat,attr"`
+	Lon        float64        `xml:"lon,attr"`
+	Ele        float64        `xml:"ele"`
+	Time       *time.Time     `xml:"time,omitempty"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+}
+
+type gpxExtensions struct {
+	Values []gpxExtension `xml:",any"`
+}
+
+type gpxExtension struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+func (gpxFormat) Detect(head []byte) bool {
+	return xmlRoot(head) == "gpx"
+}
+
+func (gpxFormat) Decode(r io.Reader, opts ParseOptions) (Track, error) {
+	var g gpxFile
+	if err := xml.NewDecoder(r).Decode(&g); err != nil {
+		return Track{}, err
+	}
+
+	track := NewTrack()
+	track.SoftwareVersion = g.Creator
+	track.Pilot = g.Metadata.Author
+	track.Site = g.Metadata.Name
+	for _, t := range g.Tracks {
+		for _, s := range t.Segments {
+			for _, p := range s.Points {
+				point := NewPointFromLatLng(p.Lat, p.Lon)
+				if p.Time != nil {
+					point.Time = p.Time.UTC()
+				}
+				point.FixValidity = 'A'
+				point.GNSSAltitude = int64(math.Round(p.Ele))
+				if p.Extensions != nil {
+					for _, e := range p.Extensions.Values {
+						point.IData[e.XMLName.Local] = e.Value
+					}
+				}
+				track.Points = append(track.Points, point)
+			}
+		}
+	}
+	setDate(&track)
+	return track, nil
+}
+
+// setDate sets the track date from the first point, or leaves it unset if
+// the track has no points.
+func setDate(track *Track) {
+	if len(track.Points) == 0 {
+		return
+	}
+	t := track.Points[0].Time
+	track.Date = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// encodeGPX returns the track in GPX 1.1 format, with all points in a single
+// track segment.
+func (track *Track) encodeGPX() ([]byte, error) {
+	g := track.gpx()
+	g.Tracks = []gpxTrack{{
+		Name:     track.Pilot,
+		Segments: []gpxSegment{{Points: gpxPoints(track.Points)}},
+	}}
+	return writeGPX(g)
+}
+
+// encodePhasesGPX returns the track in GPX 1.1 format, with one track per
+// phase having the phase type and stats.
+func (track *Track) encodePhasesGPX(phases []Phase) ([]byte, error) {
+	g := track.gpx()
+	for _, p := range phases {
+		g.Tracks = append(g.Tracks, gpxTrack{
+			Name: fmt.Sprintf("%v %v", p.Type, p.Start.Time.Format("15:04:05")),
+			Desc: fmt.Sprintf("Alt Gain: %dm Distance: %.2fkm Speed: %.2fkm/h LD: %.1f Vario: %.1fm/s Wind: %.0f° %.1fkm/h",
+				p.EndAltitude-p.StartAltitude, p.Distance,
+				p.AvgGndSpeed, p.LD, p.AvgVario, p.WindDirection, p.WindSpeed),
+			Type:     p.Type.String(),
+			Segments: []gpxSegment{{Points: gpxPoints(track.Points[p.StartIndex : p.EndIndex+1])}},
+		})
+	}
+	return writeGPX(g)
+}
+
+func (track *Track) gpx() gpxFile {
+	return gpxFile{
+		Xmlns:   gpxNamespace,
+		Version: "1.1",
+		Creator: "goigc",
+		Metadata: gpxMetadata{
+			Name:   track.Site,
+			Author: track.Pilot,
+			Time:   gpxTime(track.Date),
+		},
+	}
+}
+
+// gpxTime returns the time to encode, nil to omit it if unset.
+func gpxTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func gpxPoints(points []Point) []gpxPoint {
+	result := make([]gpxPoint, len(points))
+	for i, p := range points {
+		result[i] = gpxPoint{
+			Lat:  p.Lat.Degrees(),
+			Lon:  p.Lng.Degrees(),
+			Ele:  float64(p.GNSSAltitude),
+			Time: gpxTime(p.Time),
+		}
+		if len(p.IData) == 0 {
+			continue
+		}
+		// sorted for a stable output
+		keys := make([]string, 0, len(p.IData))
+		for k := range p.IData {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		result[i].Extensions = &gpxExtensions{}
+		for _, k := range keys {
+			result[i].Extensions.Values = append(result[i].Extensions.Values, gpxExtension{
+				XMLName: xml.Name{Space: gpxExtensionsNamespace, Local: k},
+				Value:   p.IData[k],
+			})
+		}
+	}
+	return result
+}
+
+func writeGPX(g gpxFile) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return buf.Bytes(), err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
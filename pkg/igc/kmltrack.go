@@ -0,0 +1,157 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kmlFormat decodes tracks in KML, from gx:Track elements.
+//
+// Each gx:Track holds a list of when elements followed by the same number of
+// gx:coord elements, which are matched by order. Multiple tracks are added
+// in the order they appear. In lenient mode points with an invalid time or
+// coordinate are skipped, as are the unmatched ones.
+type kmlFormat struct{}
+
+func (kmlFormat) Detect(head []byte) bool {
+	return xmlRoot(head) == "kml"
+}
+
+// kmlValue is the text of a when or gx:coord element, with its line number.
+type kmlValue struct {
+	text string
+	line int
+}
+
+func (kmlFormat) Decode(r io.Reader, opts ParseOptions) (Track, error) {
+	track := NewTrack()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return track, err
+	}
+	var errs ParseErrors
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var when []kmlValue
+	var coords []kmlValue
+	inTrack := false
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return track, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "Track":
+				inTrack, when, coords = true, []kmlValue{}, []kmlValue{}
+			case "when", "coord":
+				if !inTrack {
+					continue
+				}
+				line := bytes.Count(data[:d.InputOffset()], []byte("\n")) + 1
+				var v string
+				if err := d.DecodeElement(&v, &t); err != nil {
+					return track, err
+				}
+				value := kmlValue{text: strings.TrimSpace(v), line: line}
+				if t.Name.Local == "when" {
+					when = append(when, value)
+				} else {
+					coords = append(coords, value)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "Track" {
+				continue
+			}
+			inTrack = false
+			points, perrs := kmlTrackPoints(when, coords)
+			if len(perrs) > 0 && !opts.Lenient {
+				return track, perrs[0]
+			}
+			errs = append(errs, perrs...)
+			track.Points = append(track.Points, points...)
+		}
+	}
+	setDate(&track)
+	if len(errs) > 0 {
+		return track, errs
+	}
+	return track, nil
+}
+
+// kmlTrackPoints returns the valid points of a track, and an error for each
+// invalid or unmatched value.
+func kmlTrackPoints(when []kmlValue, coords []kmlValue) ([]Point, ParseErrors) {
+	var errs ParseErrors
+	invalid := func(v kmlValue, err error) {
+		errs = append(errs, &ParseError{Line: v.line, Start: 1, End: len(v.text),
+			Text: v.text, Err: err})
+	}
+	n := len(when)
+	if len(coords) < n {
+		n = len(coords)
+	}
+	for _, v := range when[n:] {
+		invalid(v, fmt.Errorf("time without coordinate"))
+	}
+	for _, v := range coords[n:] {
+		invalid(v, fmt.Errorf("coordinate without time"))
+	}
+
+	points := make([]Point, 0, n)
+	for i := 0; i < n; i++ {
+		t, err := time.Parse(time.RFC3339, when[i].text)
+		if err != nil {
+			invalid(when[i], err)
+			continue
+		}
+		values := strings.Fields(coords[i].text)
+		if len(values) < 2 {
+			invalid(coords[i], fmt.Errorf("invalid coordinate"))
+			continue
+		}
+		v := make([]float64, len(values))
+		for j := range values {
+			if v[j], err = strconv.ParseFloat(values[j], 64); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			invalid(coords[i], fmt.Errorf("invalid coordinate :: %v", err))
+			continue
+		}
+		p := NewPointFromLatLng(v[1], v[0])
+		p.Time = t.UTC()
+		p.FixValidity = 'A'
+		if len(v) > 2 {
+			p.GNSSAltitude = int64(math.Round(v[2]))
+		}
+		points = append(points, p)
+	}
+	return points, errs
+}
@@ -0,0 +1,200 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// MinFlyingSpeed is the min ground speed in km/h to consider flying.
+	MinFlyingSpeed = 35.0
+	// MinFlyingTime is the time the ground speed has to be kept above
+	// MinFlyingSpeed for takeoff, or below it for landing.
+	MinFlyingTime = 20 * time.Second
+	// MinReleaseHeight is the min height gain in meters of a launch.
+	MinReleaseHeight = 100
+	// ReleaseWindow is the time after the release in which the altitude is
+	// not higher than the release altitude.
+	ReleaseWindow = 30 * time.Second
+	// MaxWinchTime is the max duration of a winch launch, longer launches
+	// are aerotows.
+	MaxWinchTime = 2 * time.Minute
+	// EngineNoiseLevel is the min value of the ENL or MOP extensions
+	// (0-999) to consider the engine running.
+	EngineNoiseLevel = 500
+	// MinEngineTime is the min duration of an engine run.
+	MinEngineTime = 10 * time.Second
+)
+
+// LaunchType is the type of launch of a flight.
+type LaunchType int
+
+const (
+	UnknownLaunch LaunchType = 0
+	Aerotow       LaunchType = 1
+	Winch         LaunchType = 2
+	SelfLaunch    LaunchType = 3
+)
+
+// flight holds the indexes of the points where each flight stage starts.
+//
+// Points up to takeoff are on the ground, from takeoff to release are the
+// launch (of the given phase type) and from landing on the ground again.
+// Engine runs are given as pairs of start and end indexes.
+type flight struct {
+	takeoff int
+	release int
+	landing int
+	launch  PhaseType
+	engine  [][2]int
+}
+
+// detectFlight detects the takeoff, launch, landing and engine runs in the
+// Track, setting the corresponding Track fields.
+//
+// Takeoff and landing are where the ground speed gets above or below
+// MinFlyingSpeed for at least MinFlyingTime. Tracks starting in flight have
+// no takeoff, and those ending in flight no landing.
+//
+// The release is the first point after takeoff which is not exceeded in
+// altitude within ReleaseWindow, if at least MinReleaseHeight above the
+// takeoff. With the engine running at takeoff it is the engine stop instead.
+func (track *Track) detectFlight() flight {
+	n := len(track.Points)
+	f := flight{landing: n - 1, launch: Towing}
+	track.TakeoffTime, track.LandingTime = time.Time{}, time.Time{}
+	track.Launch, track.ReleasePoint, track.ReleaseHeight = UnknownLaunch, Point{}, 0
+
+	f.takeoff = track.takeoffIndex()
+	for f.landing > f.takeoff && !track.flying(f.landing, -1) {
+		f.landing--
+	}
+	if f.landing == f.takeoff {
+		// never flying, all on the ground
+		f.takeoff, f.release, f.landing = 0, 0, 0
+		return f
+	}
+	f.release = f.takeoff
+	if f.landing < n-1 {
+		track.LandingTime = track.Points[f.landing].Time
+	}
+	f.engine = track.engineRuns(f.takeoff, f.landing)
+	if f.takeoff == 0 {
+		return f
+	}
+	track.TakeoffTime = track.Points[f.takeoff].Time
+
+	takeoff := track.Points[f.takeoff]
+	if len(f.engine) > 0 && f.engine[0][0] <= f.takeoff {
+		f.release, f.launch = f.engine[0][1], EngineOn
+		f.engine = f.engine[1:]
+		track.Launch = SelfLaunch
+	} else {
+		f.release = track.release(f.takeoff, f.landing)
+		if track.altitude(f.release)-track.altitude(f.takeoff) < MinReleaseHeight {
+			f.release = f.takeoff
+			return f
+		}
+		track.Launch = Aerotow
+		if track.Points[f.release].Time.Sub(takeoff.Time) <= MaxWinchTime {
+			track.Launch = Winch
+		}
+	}
+	track.ReleasePoint = track.Points[f.release]
+	track.ReleaseHeight = track.altitude(f.release) - track.altitude(f.takeoff)
+	return f
+}
+
+// takeoffIndex returns the index of the first point from which the Track
+// is flying, or the last point if it never is.
+func (track *Track) takeoffIndex() int {
+	i := 0
+	for i < len(track.Points)-1 && !track.flying(i, 1) {
+		i++
+	}
+	return i
+}
+
+// flying checks if the ground speed to the next point from point i, in the
+// given direction, and the average for MinFlyingTime are above
+// MinFlyingSpeed.
+func (track *Track) flying(i int, direction int) bool {
+	speed := func(j int) float64 {
+		a, b := track.Points[i], track.Points[j]
+		if direction < 0 {
+			a, b = b, a
+		}
+		return a.Speed(b)
+	}
+	for j := i + direction; j >= 0 && j < len(track.Points); j += direction {
+		if j == i+direction && speed(j) < MinFlyingSpeed {
+			return false
+		}
+		if d := track.Points[j].Time.Sub(track.Points[i].Time); d >= MinFlyingTime || -d >= MinFlyingTime {
+			return speed(j) >= MinFlyingSpeed
+		}
+	}
+	return false
+}
+
+// release returns the first point after from which is not exceeded in
+// altitude within ReleaseWindow.
+func (track *Track) release(from, to int) int {
+	for i := from; i < to; i++ {
+		exceeded := false
+		for j := i + 1; j <= to && track.Points[j].Time.Sub(track.Points[i].Time) <= ReleaseWindow; j++ {
+			if track.altitude(j) > track.altitude(i) {
+				exceeded = true
+				break
+			}
+		}
+		if !exceeded {
+			return i
+		}
+	}
+	return to
+}
+
+// engineRuns returns the start and end indexes of the engine runs between
+// the given points, from the ENL or MOP extensions.
+func (track *Track) engineRuns(from, to int) [][2]int {
+	runs := [][2]int{}
+	start := -1
+	for i := from; i <= to; i++ {
+		on := i < to && engineOn(track.Points[i])
+		if on && start < 0 {
+			start = i
+		} else if !on && start >= 0 {
+			if track.Points[i].Time.Sub(track.Points[start].Time) >= MinEngineTime {
+				runs = append(runs, [2]int{start, i})
+			}
+			start = -1
+		}
+	}
+	return runs
+}
+
+// engineOn checks the point ENL and MOP extensions for a running engine.
+func engineOn(p Point) bool {
+	for _, k := range []string{"ENL", "MOP"} {
+		if v, err := strconv.Atoi(p.IData[k]); err == nil && v >= EngineNoiseLevel {
+			return true
+		}
+	}
+	return false
+}
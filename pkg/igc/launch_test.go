@@ -0,0 +1,143 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// launchTrack returns a track heading east with one point per second, for
+// each of the given stages of speed (km/h), vario (m/s), duration (s) and
+// ENL value.
+func launchTrack(stages ...[4]float64) Track {
+	track := NewTrack()
+	t := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	lng, alt := 0.0, 200.0
+	for _, s := range stages {
+		for i := 0; i < int(s[2]); i++ {
+			p := NewPointFromLatLng(0, lng)
+			p.Time = t
+			p.GNSSAltitude = int64(alt)
+			p.IData["ENL"] = fmt.Sprintf("%03d", int(s[3]))
+			track.Points = append(track.Points, p)
+			t = t.Add(time.Second)
+			lng += s[0] / 3600 / (EarthRadius * math.Pi / 180)
+			alt += s[1]
+		}
+	}
+	return track
+}
+
+func TestDetectWinch(t *testing.T) {
+	track := launchTrack(
+		[4]float64{0, 0, 60, 0},
+		[4]float64{100, 10, 40, 0},
+		[4]float64{90, -1, 300, 0},
+		[4]float64{0, 0, 60, 0},
+	)
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := track.Points[0].Time
+	if d := track.TakeoffTime.Sub(start); d < 55*time.Second || d > 65*time.Second {
+		t.Errorf("expected takeoff after 60s got %v", d)
+	}
+	if track.Launch != Winch {
+		t.Errorf("expected winch launch got %v", track.Launch)
+	}
+	if track.ReleaseHeight < 350 || track.ReleaseHeight > 410 {
+		t.Errorf("expected release height 400m got %v", track.ReleaseHeight)
+	}
+	if d := track.LandingTime.Sub(start); d < 395*time.Second || d > 405*time.Second {
+		t.Errorf("expected landing after 400s got %v", d)
+	}
+	types := []PhaseType{Unknown, Towing, Cruising, Unknown}
+	if len(phases) != len(types) {
+		t.Fatalf("expected %v phases got %+v", len(types), phases)
+	}
+	for i, p := range phases {
+		if p.Type != types[i] {
+			t.Errorf("expected phase %v to be %v got %v", i, types[i], p.Type)
+		}
+	}
+}
+
+func TestDetectAerotow(t *testing.T) {
+	track := launchTrack(
+		[4]float64{0, 0, 60, 0},
+		[4]float64{120, 3, 400, 0},
+		[4]float64{90, -1, 300, 0},
+	)
+	if _, err := track.Phases(); err != nil {
+		t.Fatal(err)
+	}
+	if track.Launch != Aerotow {
+		t.Errorf("expected aerotow got %v", track.Launch)
+	}
+	if !track.LandingTime.IsZero() {
+		t.Errorf("expected no landing got %v", track.LandingTime)
+	}
+}
+
+func TestDetectEngine(t *testing.T) {
+	track := launchTrack(
+		[4]float64{0, 0, 60, 900},
+		[4]float64{100, 2, 200, 900},
+		[4]float64{90, -1, 100, 0},
+		[4]float64{90, 1, 60, 800},
+		[4]float64{90, -1, 100, 0},
+	)
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Launch != SelfLaunch {
+		t.Errorf("expected self launch got %v", track.Launch)
+	}
+	types := []PhaseType{Unknown, EngineOn, Cruising, EngineOn, Cruising}
+	if len(phases) != len(types) {
+		t.Fatalf("expected %v phases got %+v", len(types), phases)
+	}
+	for i, p := range phases {
+		if p.Type != types[i] {
+			t.Errorf("expected phase %v to be %v got %v", i, types[i], p.Type)
+		}
+	}
+}
+
+func TestDetectFlight(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-long-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Launch != Aerotow || track.TakeoffTime.Format(TimeFormat) != "083557" {
+		t.Errorf("expected aerotow at 083557 got %v at %v", track.Launch, track.TakeoffTime)
+	}
+	if track.LandingTime.Format(TimeFormat) != "173559" {
+		t.Errorf("expected landing at 173559 got %v", track.LandingTime)
+	}
+	if phases[0].Type != Unknown || phases[1].Type != Towing || phases[len(phases)-1].Type != Unknown {
+		t.Errorf("expected unknown, towing ... unknown phases")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmeaFormat decodes raw NMEA 0183 logs, from the GGA and RMC sentences.
+//
+// Sentences with the same time are merged in a single Point, with RMC
+// providing the date and fix validity and GGA the altitude and number of
+// satellites. Points without a position in any of their sentences (like the
+// ones logged before the first fix) are dropped. Other sentences are ignored.
+type nmeaFormat struct{}
+
+func (nmeaFormat) Detect(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("$"))
+}
+
+func (nmeaFormat) Decode(r io.Reader, opts ParseOptions) (Track, error) {
+	track := NewTrack()
+	var errs ParseErrors
+	var date time.Time
+	dated := 0
+	seconds := -1
+	// fixed tells if each point got a position from any of its sentences
+	var fixed []bool
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		fields, err := nmeaFields(line)
+		if err == nil && len(fields[0]) == 5 && (fields[0][2:] == "GGA" || fields[0][2:] == "RMC") {
+			var p nmeaPoint
+			if p, err = parseNMEA(fields); err == nil {
+				if p.seconds != seconds {
+					track.Points = append(track.Points, NewPoint())
+					fixed = append(fixed, false)
+					seconds = p.seconds
+				}
+				last := &track.Points[len(track.Points)-1]
+				p.merge(last)
+				fixed[len(fixed)-1] = fixed[len(fixed)-1] || p.hasFix
+				if !p.date.IsZero() && p.date != date {
+					date = p.date
+					// points until the first date are set to the same day
+					if dated == 0 {
+						for i := range track.Points[:len(track.Points)-1] {
+							track.Points[i].Time = date.Add(track.Points[i].Time.Sub(time.Time{}))
+						}
+					}
+					dated = len(track.Points)
+				}
+				last.Time = date.Add(time.Duration(p.seconds) * time.Second)
+			}
+		}
+		if err != nil {
+			perr := &ParseError{Record: line[0], Line: number, Start: 1,
+				End: len(line), Text: line, Err: err}
+			if !opts.Lenient {
+				return track, perr
+			}
+			errs = append(errs, perr)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return track, err
+	}
+	points := track.Points[:0]
+	for i, p := range track.Points {
+		if fixed[i] {
+			points = append(points, p)
+		}
+	}
+	track.Points = points
+	setDate(&track)
+	if len(errs) > 0 {
+		return track, errs
+	}
+	return track, nil
+}
+
+// nmeaFields returns the fields of the sentence, after validating the
+// checksum if present.
+func nmeaFields(line string) ([]string, error) {
+	if line[0] != '$' {
+		return nil, fmt.Errorf("invalid sentence")
+	}
+	line = line[1:]
+	if i := strings.LastIndexByte(line, '*'); i >= 0 {
+		expected, err := strconv.ParseUint(line[i+1:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum :: %v", err)
+		}
+		var sum byte
+		for j := 0; j < i; j++ {
+			sum ^= line[j]
+		}
+		if uint64(sum) != expected {
+			return nil, fmt.Errorf("checksum mismatch :: expected %02X got %02X", expected, sum)
+		}
+		line = line[:i]
+	}
+	return strings.Split(line, ","), nil
+}
+
+// nmeaPoint holds the values of a single GGA or RMC sentence.
+type nmeaPoint struct {
+	seconds    int
+	date       time.Time
+	lat, lng   float64
+	hasFix     bool
+	validity   byte
+	altitude   int64
+	satellites int
+	gga        bool
+}
+
+func (n nmeaPoint) merge(p *Point) {
+	if n.hasFix {
+		p.LatLng = NewPointFromLatLng(n.lat, n.lng).LatLng
+	}
+	if n.gga {
+		p.GNSSAltitude = n.altitude
+		p.NumSatellites = n.satellites
+		if p.FixValidity == 0 {
+			p.FixValidity = n.validity
+		}
+	} else {
+		p.FixValidity = n.validity
+	}
+}
+
+func parseNMEA(fields []string) (nmeaPoint, error) {
+	var p nmeaPoint
+	var err error
+	switch fields[0][2:] {
+	case "GGA":
+		// GGA,time,lat,N,lng,E,quality,satellites,hdop,altitude,M,...
+		if len(fields) < 10 {
+			return p, fmt.Errorf("GGA too short")
+		}
+		p.gga = true
+		if p.seconds, err = nmeaTime(fields[1]); err != nil {
+			return p, err
+		}
+		p.validity = 'A'
+		if fields[6] == "0" {
+			p.validity = 'V'
+		}
+		if fields[7] != "" {
+			if p.satellites, err = strconv.Atoi(fields[7]); err != nil {
+				return p, fmt.Errorf("invalid satellites :: %v", err)
+			}
+		}
+		if fields[9] != "" {
+			alt, err := strconv.ParseFloat(fields[9], 64)
+			if err != nil {
+				return p, fmt.Errorf("invalid altitude :: %v", err)
+			}
+			p.altitude = int64(math.Round(alt))
+		}
+		err = p.position(fields[2:6])
+	case "RMC":
+		// RMC,time,status,lat,N,lng,E,speed,course,date,...
+		if len(fields) < 10 {
+			return p, fmt.Errorf("RMC too short")
+		}
+		if p.seconds, err = nmeaTime(fields[1]); err != nil {
+			return p, err
+		}
+		p.validity = 'V'
+		if fields[2] == "A" {
+			p.validity = 'A'
+		}
+		if p.date, err = time.Parse(DateFormat, fields[9]); err != nil {
+			return p, fmt.Errorf("invalid date :: %v", err)
+		}
+		err = p.position(fields[3:7])
+	}
+	return p, err
+}
+
+// position parses the lat, N/S, lng, E/W fields.
+func (p *nmeaPoint) position(fields []string) error {
+	if fields[0] == "" || fields[2] == "" {
+		return nil
+	}
+	lat, err := nmeaCoordinate(fields[0], 2, fields[1] == "S")
+	if err != nil {
+		return fmt.Errorf("invalid latitude :: %v", err)
+	}
+	lng, err := nmeaCoordinate(fields[2], 3, fields[3] == "W")
+	if err != nil {
+		return fmt.Errorf("invalid longitude :: %v", err)
+	}
+	p.lat, p.lng, p.hasFix = lat, lng, true
+	return nil
+}
+
+// nmeaCoordinate parses a coordinate in the (D)DDMM.mmmm format.
+func nmeaCoordinate(value string, digits int, negative bool) (float64, error) {
+	if len(value) < digits+2 {
+		return 0, fmt.Errorf("too short '%v'", value)
+	}
+	d, err := strconv.Atoi(value[:digits])
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.ParseFloat(value[digits:], 64)
+	if err != nil {
+		return 0, err
+	}
+	result := float64(d) + m/60
+	if negative {
+		result = -result
+	}
+	return result, nil
+}
+
+// nmeaTime returns the seconds since midnight of a time in hhmmss.ss format.
+func nmeaTime(value string) (int, error) {
+	if len(value) < 6 {
+		return 0, fmt.Errorf("invalid time '%v'", value)
+	}
+	t, err := time.Parse(TimeFormat, value[:6])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time :: %v", err)
+	}
+	return t.Hour()*3600 + t.Minute()*60 + t.Second(), nil
+}
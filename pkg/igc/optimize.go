@@ -16,6 +16,10 @@
 
 package igc
 
+import (
+	"reflect"
+)
+
 // Score functions calculate a score for the given Task.
 //
 // The main use of these functions is in passing them to the Optimizers, so
@@ -33,6 +37,40 @@ func Distance(task Task) float64 {
 	return task.Distance()
 }
 
+// Additive marks the Score as a sum over the legs of the Task, so that it
+// can be used with Optimizers scoring each leg on its own (like the DP
+// optimizer) by passing them a Task with only a Start and a Finish.
+//
+// Distance is additive, while scores with per leg factors or looking at the
+// shape of the whole Task (like Triangle) are not.
+func Additive(score Score) Score {
+	return (&additiveScore{score: score}).Score
+}
+
+// IsAdditive returns true if the Score is Distance or marked with Additive.
+func IsAdditive(score Score) bool {
+	if score == nil {
+		return false
+	}
+	p := reflect.ValueOf(score).Pointer()
+	return p == distancePointer || p == additivePointer
+}
+
+// additiveScore is a Score marked with Additive. Its method values share the
+// same code pointer, which is how IsAdditive recognizes them.
+type additiveScore struct {
+	score Score
+}
+
+func (a *additiveScore) Score(task Task) float64 {
+	return a.score(task)
+}
+
+var (
+	distancePointer = reflect.ValueOf(Distance).Pointer()
+	additivePointer = reflect.ValueOf((&additiveScore{}).Score).Pointer()
+)
+
 // Optimizer returns an optimal Task for the given turnpoints and Score function.
 //
 // Available score functions include MaxDistance and MaxPoints, but it is
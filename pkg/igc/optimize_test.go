@@ -23,6 +23,9 @@ import (
 
 const (
 	errorMargin float64 = 0.02
+	// optimizeTolerance is the tolerance used to simplify tracks before
+	// running the slower optimizers.
+	optimizeTolerance float64 = 0.00005
 )
 
 type optimizeTest struct {
@@ -49,10 +52,19 @@ var benchmarkTests = []optimizeTest{
 }
 
 var optimizeTests = []optimizeTest{
-	//	{
-	//		name:   "optimize-short-flight-1",
-	//		result: map[int]float64{1: 35.44619896425489, 2: 0.0, 3: 507.80108709626626},
-	//	},
+	{
+		name:   "optimize-short-flight-1",
+		result: map[int]float64{1: 35.44619896425489, 2: 42.1706196102479, 3: 45.49220580490592},
+	},
+}
+
+// declaredTests hold the distance of the task declared and completed in the
+// flight, which is a lower bound for the optimal distance.
+var declaredTests = []optimizeTest{
+	{
+		name:   "optimize-long-flight-1",
+		result: map[int]float64{3: 507.80108709626626},
+	},
 }
 
 type distanceTest struct {
@@ -97,3 +109,24 @@ func TestDistance(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAdditive(t *testing.T) {
+	local := func(task Task) float64 { return 0 }
+	tests := map[string]struct {
+		score    Score
+		additive bool
+	}{
+		"distance":          {score: Distance, additive: true},
+		"additive-northing": {score: Additive(northing), additive: true},
+		"additive-local":    {score: Additive(local), additive: true},
+		"northing":          {score: northing},
+		"local":             {score: local},
+		"triangle":          {score: Triangle},
+		"nil":               {},
+	}
+	for name, test := range tests {
+		if IsAdditive(test.score) != test.additive {
+			t.Errorf("%v :: expected additive %v", name, test.additive)
+		}
+	}
+}
@@ -18,8 +18,8 @@ package igc
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -34,26 +34,30 @@ const (
 
 // ParseLocation returns a Track object corresponding to the given file.
 //
-// It calls Parse internatlly, so the file content should be in IGC format.
+// It decodes the content as it is read. The format is detected from the
+// content, and can be any of the available Formats (IGC by default).
 func ParseLocation(location string) (Track, error) {
-	var content []byte
+	return ParseLocationWithOptions(location, ParseOptions{})
+}
+
+// ParseLocationWithOptions returns a Track object corresponding to the given
+// file, parsed with the given options.
+//
+// See ParseLocation() and ParseOptions.
+func ParseLocationWithOptions(location string, opts ParseOptions) (Track, error) {
 	resp, err := http.Get(location)
 	// case http
 	if err == nil {
 		defer resp.Body.Close()
-		content, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return Track{}, err
-		}
-	} else { // case file
-		resp, err := ioutil.ReadFile(location)
-		if err != nil {
-			return Track{}, err
-		}
-		content = resp
+		return decodeFormat(resp.Body, opts)
 	}
-
-	return Parse(string(content))
+	// case file
+	file, err := os.Open(location)
+	if err != nil {
+		return Track{}, err
+	}
+	defer file.Close()
+	return decodeFormat(file, opts)
 }
 
 // ParseCleanLocation returns a cleaned up Track object.
@@ -81,54 +85,17 @@ func ParseClean(content string) (Track, error) {
 // Parse returns a Track object corresponding to the given content.
 //
 // The value of content should be a text string with all the flight data
-// in the IGC format.
+// in the IGC format, or any of the other available Formats.
 func Parse(content string) (Track, error) {
-	f := NewTrack()
-	var err error
-	p := parser{}
-	lines := strings.Split(content, "\n")
-	for i := range lines {
-		line := strings.TrimSpace(lines[i])
-		// ignore empty lines
-		if len(strings.Trim(line, " ")) < 1 {
-			continue
-		}
-		switch line[0] {
-		case 'A':
-			err = p.parseA(line, &f)
-		case 'B':
-			err = p.parseB(line, &f)
-		case 'C':
-			if !p.taskDone {
-				err = p.parseC(lines[i:], &f)
-			}
-		case 'D':
-			err = p.parseD(line, &f)
-		case 'E':
-			err = p.parseE(line, &f)
-		case 'F':
-			err = p.parseF(line, &f)
-		case 'G':
-			err = p.parseG(line, &f)
-		case 'H':
-			err = p.parseH(line, &f)
-		case 'I':
-			err = p.parseI(line)
-		case 'J':
-			err = p.parseJ(line)
-		case 'K':
-			err = p.parseK(line, &f)
-		case 'L':
-			err = p.parseL(line, &f)
-		default:
-			err = fmt.Errorf("invalid record :: %v", line)
-		}
-		if err != nil {
-			return f, err
-		}
-	}
+	return ParseWithOptions(content, ParseOptions{})
+}
 
-	return f, nil
+// ParseWithOptions returns a Track object corresponding to the given content,
+// parsed with the given options.
+//
+// See Parse() and ParseOptions.
+func ParseWithOptions(content string, opts ParseOptions) (Track, error) {
+	return decodeFormat(strings.NewReader(content), opts)
 }
 
 type field struct {
@@ -144,9 +111,44 @@ type parser struct {
 	numSat   int
 }
 
+// columnError is an error affecting the given column range of a record.
+//
+// Columns are 1-based and inclusive, as in the IGC specification. The offset
+// is the number of lines between the first line of the record and the line
+// with the error, only relevant for multi line records (C).
+type columnError struct {
+	offset int
+	start  int
+	end    int
+	err    error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+// errorAt returns an error for the given column range.
+func errorAt(start int, end int, format string, a ...interface{}) error {
+	return &columnError{start: start, end: end, err: fmt.Errorf(format, a...)}
+}
+
+// wrapAt returns an error for the given column range with err as the cause.
+func wrapAt(start int, end int, err error) error {
+	return &columnError{start: start, end: end, err: err}
+}
+
+// tooShort returns an error for a line shorter than the given size.
+func tooShort(line string, size int) error {
+	return errorAt(len(line)+1, size, "line too short")
+}
+
 func (p *parser) parseA(line string, f *Track) error {
 	if len(line) < 4 {
-		return fmt.Errorf("line too short :: %v", line)
+		return tooShort(line, 4)
 	}
 	f.Manufacturer = line[1:4]
 	if len(line) >= 7 {
@@ -156,9 +158,9 @@ func (p *parser) parseA(line string, f *Track) error {
 	return nil
 }
 
-func (p *parser) parseB(line string, f *Track) error {
+func (p *parser) parseB(line string, f *Track) (Point, error) {
 	if len(line) < 35 {
-		return fmt.Errorf("line too short :: %v", line)
+		return Point{}, tooShort(line, 35)
 	}
 	pt := NewPointFromDMD(
 		line[7:15], line[15:24])
@@ -167,83 +169,84 @@ func (p *parser) parseB(line string, f *Track) error {
 	pt.Time, err = time.Parse(TimeFormat, line[1:7])
 	if err != nil {
 		if !strings.Contains(err.Error(), "out of range") {
-			return err
+			return Point{}, wrapAt(2, 7, err)
 		}
 	}
 	pt.Time = pt.Time.AddDate(f.Date.Year(), int(f.Date.Month()), f.Date.Day())
 	if line[24] == 'A' || line[24] == 'V' {
 		pt.FixValidity = line[24]
 	} else {
-		return fmt.Errorf("invalid fix validity :: %v", line)
+		return Point{}, errorAt(25, 25, "invalid fix validity")
 	}
 	pt.PressureAltitude, err = strconv.ParseInt(line[25:30], 10, 64)
 	if err != nil {
-		return err
+		return Point{}, wrapAt(26, 30, err)
 	}
 	pt.GNSSAltitude, err = strconv.ParseInt(line[30:35], 10, 64)
 	if err != nil {
-		return err
+		return Point{}, wrapAt(31, 35, err)
 	}
 	for _, f := range p.IFields {
 		if int64(len(line)) < f.end {
-			return fmt.Errorf("wrong line size :: %v", line)
+			return Point{}, errorAt(int(f.start), int(f.end), "wrong line size")
 		}
 		pt.IData[f.tlc] = line[f.start-1 : f.end]
 	}
 	pt.NumSatellites = p.numSat
-	f.Points = append(f.Points, pt)
-	return nil
+	return pt, nil
 }
 
 func (p *parser) parseC(lines []string, f *Track) error {
-	line := lines[0]
+	line := strings.TrimSpace(lines[0])
 	if len(line) < 25 {
-		return fmt.Errorf("wrong line size :: %v", line)
+		return errorAt(len(line)+1, 25, "wrong line size")
 	}
 	var err error
 	var nTP int
 	if nTP, err = strconv.Atoi(line[23:25]); err != nil {
-		return fmt.Errorf("invalid number of turnpoints :: %v", line)
+		return errorAt(24, 25, "invalid number of turnpoints")
 	}
 	if len(lines) < 5+nTP {
-		return fmt.Errorf("invalid number of C record lines :: %v", lines)
+		return errorAt(1, len(line), "invalid number of C record lines :: %v", len(lines))
 	}
-	if f.Task.DeclarationDate, err = time.Parse(DateFormat+TimeFormat, lines[0][1:13]); err != nil {
+	if f.Task.DeclarationDate, err = time.Parse(DateFormat+TimeFormat, line[1:13]); err != nil {
 		f.Task.DeclarationDate = time.Time{}
 	}
-	if f.Task.Date, err = time.Parse(DateFormat, lines[0][13:19]); err != nil {
+	if f.Task.Date, err = time.Parse(DateFormat, line[13:19]); err != nil {
 		f.Task.Date = time.Time{}
 	}
 	if f.Task.Number, err = strconv.Atoi(line[19:23]); err != nil {
-		return err
+		return wrapAt(20, 23, err)
 	}
 	f.Task.Description = line[25:]
-	if f.Task.Takeoff, err = p.taskPoint(lines[1]); err != nil {
+	if f.Task.Takeoff, err = p.taskPoint(lines, 1); err != nil {
 		return err
 	}
-	if f.Task.Start, err = p.taskPoint(lines[2]); err != nil {
+	if f.Task.Start, err = p.taskPoint(lines, 2); err != nil {
 		return err
 	}
 	for i := 0; i < nTP; i++ {
 		var tp Point
-		if tp, err = p.taskPoint(lines[3+i]); err != nil {
+		if tp, err = p.taskPoint(lines, 3+i); err != nil {
 			return err
 		}
 		f.Task.Turnpoints = append(f.Task.Turnpoints, tp)
 	}
-	if f.Task.Finish, err = p.taskPoint(lines[3+nTP]); err != nil {
+	if f.Task.Finish, err = p.taskPoint(lines, 3+nTP); err != nil {
 		return err
 	}
-	if f.Task.Landing, err = p.taskPoint(lines[4+nTP]); err != nil {
+	if f.Task.Landing, err = p.taskPoint(lines, 4+nTP); err != nil {
 		return err
 	}
 	p.taskDone = true
 	return nil
 }
 
-func (p *parser) taskPoint(line string) (Point, error) {
+func (p *parser) taskPoint(lines []string, offset int) (Point, error) {
+	line := strings.TrimSpace(lines[offset])
 	if len(line) < 18 {
-		return Point{}, fmt.Errorf("line too short :: %v", line)
+		return Point{}, &columnError{offset: offset, start: len(line) + 1, end: 18,
+			err: fmt.Errorf("line too short")}
 	}
 	pt := NewPointFromDMD(
 		line[1:9], line[9:18])
@@ -253,7 +256,7 @@ func (p *parser) taskPoint(line string) (Point, error) {
 
 func (p *parser) parseD(line string, f *Track) error {
 	if len(line) < 6 {
-		return fmt.Errorf("line too short :: %v", line)
+		return tooShort(line, 6)
 	}
 	if line[1] == '2' {
 		f.DGPSStationID = line[2:6]
@@ -261,33 +264,31 @@ func (p *parser) parseD(line string, f *Track) error {
 	return nil
 }
 
-func (p *parser) parseE(line string, f *Track) error {
+func (p *parser) parseE(line string) (Event, error) {
 	if len(line) < 10 {
-		return fmt.Errorf("line too short :: %v", line)
+		return Event{}, tooShort(line, 10)
 	}
 	t, err := time.Parse(TimeFormat, line[1:7])
 	if err != nil {
-		return err
+		return Event{}, wrapAt(2, 7, err)
 	}
-	f.Events = append(f.Events, Event{Time: t, Type: line[7:10], Data: line[10:]})
-	return nil
+	return Event{Time: t, Type: line[7:10], Data: line[10:]}, nil
 }
 
-func (p *parser) parseF(line string, f *Track) error {
+func (p *parser) parseF(line string) (Satellite, error) {
 	if len(line) < 7 {
-		return fmt.Errorf("line too short :: %v", line)
+		return Satellite{}, tooShort(line, 7)
 	}
 	t, err := time.Parse(TimeFormat, line[1:7])
 	if err != nil {
-		return err
+		return Satellite{}, wrapAt(2, 7, err)
 	}
 	ids := []string{}
 	for i := 7; i < len(line)-1; i = i + 2 {
 		ids = append(ids, line[i:i+2])
 	}
-	f.Satellites = append(f.Satellites, Satellite{Time: t, Ids: ids})
 	p.numSat = len(ids)
-	return nil
+	return Satellite{Time: t, Ids: ids}, nil
 }
 
 func (p *parser) parseG(line string, f *Track) error {
@@ -298,22 +299,34 @@ func (p *parser) parseG(line string, f *Track) error {
 func (p *parser) parseH(line string, f *Track) error {
 	var err error
 	if len(line) < 5 {
-		return fmt.Errorf("line too short :: %v", line)
+		return tooShort(line, 5)
+	}
+
+	// value columns for errors, after the optional long name
+	start := strings.Index(line[5:], ":") + 7
+	if start < 7 {
+		start = 6
 	}
+	end := len(line)
 
 	switch line[2:5] {
 	case "DTE":
 		if len(line) < 11 {
-			return fmt.Errorf("line too short :: %v", line)
+			return tooShort(line, 11)
 		}
 		if len(line) > 12 {
+			if len(line) < 16 {
+				return tooShort(line, 16)
+			}
+			start, end = 11, 16
 			f.Date, err = time.Parse(DateFormat, line[10:16])
 		} else {
+			start, end = 6, 11
 			f.Date, err = time.Parse(DateFormat, line[5:11])
 		}
 	case "FXA":
 		if len(line) < 6 {
-			return fmt.Errorf("line too short :: %v", line)
+			return tooShort(line, 6)
 		}
 		f.FixAccuracy, err = strconv.ParseInt(line[5:], 10, 64)
 	case "PLT":
@@ -326,7 +339,7 @@ func (p *parser) parseH(line string, f *Track) error {
 		f.GliderID = stripUpTo(line[5:], ":")
 	case "DTM":
 		if len(line) < 8 {
-			return fmt.Errorf("line too short :: %v", line)
+			return tooShort(line, 8)
 		}
 		f.GPSDatum = stripUpTo(line[5:], ":")
 	case "RFW":
@@ -344,17 +357,13 @@ func (p *parser) parseH(line string, f *Track) error {
 	case "CCL":
 		f.CompetitionClass = stripUpTo(line[5:], ":")
 	case "TZN", "TZO":
-		z, err := strconv.ParseFloat(
+		var z float64
+		z, err = strconv.ParseFloat(
 			strings.TrimLeft(stripUpTo(line[5:], ":"), " "), 64)
-		if err != nil {
-			return err
-		}
 		f.Timezone = int(z)
 	case "ATS":
-		ats, err := strconv.ParseFloat(stripUpTo(line[5:], ":"), 64)
-		if err != nil {
-			return err
-		}
+		var ats float64
+		ats, err = strconv.ParseFloat(stripUpTo(line[5:], ":"), 64)
 		f.AltimeterPressure = ats / 100
 	case "DB1":
 		f.PilotBirth, err = time.Parse(DateFormat, stripUpTo(line[5:], ":"))
@@ -378,68 +387,66 @@ func (p *parser) parseH(line string, f *Track) error {
 	case "UNT":
 		// seen once, not sure what it's supposed to mean
 	default:
-		err = fmt.Errorf("unknown record :: %v", line)
+		return errorAt(3, 5, "unknown record")
 	}
 
-	return err
-}
-
-func (p *parser) parseI(line string) error {
-	if len(line) < 3 {
-		return fmt.Errorf("line too short :: %v", line)
-	}
-	n, err := strconv.ParseInt(line[1:3], 10, 0)
 	if err != nil {
-		return fmt.Errorf("invalid number of I fields :: %v", line)
-	}
-	if len(line) != int(n*7+3) {
-		return fmt.Errorf("wrong line size :: %v", line)
-	}
-	for i := 0; i < int(n); i++ {
-		s := i*7 + 3
-		start, _ := strconv.ParseInt(line[s:s+2], 10, 0)
-		end, _ := strconv.ParseInt(line[s+2:s+4], 10, 0)
-		tlc := line[s+4 : s+7]
-		p.IFields = append(p.IFields, field{start: start, end: end, tlc: tlc})
+		return wrapAt(start, end, err)
 	}
 	return nil
 }
 
+func (p *parser) parseI(line string) error {
+	fields, err := p.parseExtension(line)
+	p.IFields = append(p.IFields, fields...)
+	return err
+}
+
 func (p *parser) parseJ(line string) error {
+	fields, err := p.parseExtension(line)
+	p.JFields = append(p.JFields, fields...)
+	return err
+}
+
+// parseExtension returns the fields declared in an I or J record.
+func (p *parser) parseExtension(line string) ([]field, error) {
+	fields := []field{}
 	if len(line) < 3 {
-		return fmt.Errorf("line too short :: %v", line)
+		return fields, tooShort(line, 3)
 	}
 	n, err := strconv.ParseInt(line[1:3], 10, 0)
 	if err != nil {
-		return fmt.Errorf("invalid number of J fields :: %v", line)
+		return fields, errorAt(2, 3, "invalid number of %c fields", line[0])
 	}
 	if len(line) != int(n*7+3) {
-		return fmt.Errorf("wrong line size :: %v", line)
+		return fields, errorAt(1, len(line), "wrong line size")
 	}
 	for i := 0; i < int(n); i++ {
 		s := i*7 + 3
 		start, _ := strconv.ParseInt(line[s:s+2], 10, 0)
 		end, _ := strconv.ParseInt(line[s+2:s+4], 10, 0)
 		tlc := line[s+4 : s+7]
-		p.JFields = append(p.JFields, field{start: start, end: end, tlc: tlc})
+		fields = append(fields, field{start: start, end: end, tlc: tlc})
 	}
-	return nil
+	return fields, nil
 }
 
-func (p *parser) parseK(line string, f *Track) error {
+func (p *parser) parseK(line string) (K, error) {
 	if len(line) < 7 {
-		return fmt.Errorf("line too short :: %v", line)
+		return K{}, tooShort(line, 7)
 	}
 	t, err := time.Parse(TimeFormat, line[1:7])
 	if err != nil {
-		return err
+		return K{}, wrapAt(2, 7, err)
 	}
 	fields := make(map[string]string)
 	for _, f := range p.JFields {
+		if int64(len(line)) < f.end {
+			return K{}, errorAt(int(f.start), int(f.end), "wrong line size")
+		}
 		fields[f.tlc] = line[f.start-1 : f.end]
 	}
-	f.K = append(f.K, K{Time: t, Fields: fields})
-	return nil
+	return K{Time: t, Fields: fields}, nil
 }
 
 func (p *parser) parseL(line string, f *Track) error {
@@ -81,6 +81,50 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// TestParseBenchmark checks the benchmark flight, too big for a full golden
+// copy, against a summary of the header, task and first and last points.
+func TestParseBenchmark(t *testing.T) {
+	in := "../../testdata/parse/benchmark-0.igc"
+	out := fmt.Sprintf("%v.golden", in)
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(track.Points) == 0 {
+		t.Fatalf("expected points in benchmark flight")
+	}
+	summary := struct {
+		Header Header
+		Task   Task
+		Points int
+		First  Point
+		Last   Point
+	}{track.Header, track.Task, len(track.Points), track.Points[0], track.Points[len(track.Points)-1]}
+	resultJSON, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// update golden if flag is passed
+	if *update {
+		if err = ioutil.WriteFile(out, resultJSON, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedJSON, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resultJSON) != string(expectedJSON) {
+		t.Errorf("expected\n%+v\ngot\n%+v", string(expectedJSON), string(resultJSON))
+	}
+}
+
 func TestParseLocationMissing(t *testing.T) {
 	_, err := ParseLocation("does-not-exist")
 	if err == nil {
@@ -126,7 +170,7 @@ HFPLTPilotincharge:EZ PILOT
 }
 
 func BenchmarkParse(b *testing.B) {
-	c, err := ioutil.ReadFile("../../testdata/parse/parse-0-benchmark-0.igc")
+	c, err := ioutil.ReadFile("../../testdata/parse/benchmark-0.igc")
 	if err != nil {
 		b.Errorf("failed to load sample flight :: %v", err)
 	}
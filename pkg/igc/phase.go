@@ -32,7 +32,7 @@ import (
 // PhaseType represents a flight phase.
 //
 // Possible values include Towing, PossibleCruising/Cruising,
-// PossibleCircling/Circling, Unknown.
+// PossibleCircling/Circling, EngineOn, Unknown.
 type PhaseType int
 
 const (
@@ -42,8 +42,27 @@ const (
 	Cruising         PhaseType = 3
 	PossibleCircling PhaseType = 4
 	Circling         PhaseType = 5
+	EngineOn         PhaseType = 6
 )
 
+var phaseTypeNames = map[PhaseType]string{
+	Unknown:          "unknown",
+	Towing:           "towing",
+	PossibleCruising: "possible-cruising",
+	Cruising:         "cruising",
+	PossibleCircling: "possible-circling",
+	Circling:         "circling",
+	EngineOn:         "engine-on",
+}
+
+// String returns the lower case name of the phase type.
+func (t PhaseType) String() string {
+	if name, ok := phaseTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("PhaseType(%d)", int(t))
+}
+
 // CirclingType indicates Left, Right or Mixed circling.
 type CirclingType int
 
@@ -68,47 +87,134 @@ const (
 	MinCruisingTime = 10
 )
 
+// PhaseOptions holds the thresholds used when calculating flight phases.
+//
+// Turn rates are in degrees per second. A turn rate above MaxTurnRate is
+// considered invalid (not turning), and a zero value disables this check.
+// SmoothingWindow is the number of consecutive turn rates averaged before
+// comparing them with the thresholds, with 0 or 1 disabling smoothing.
+// Altitude selects the altitude used for the phase stats, with QNH in hPa
+// used by AltitudeQNH (see Track.Altitudes()).
+type PhaseOptions struct {
+	MinTurnRate     float64
+	MaxTurnRate     float64
+	MinCirclingTime time.Duration
+	MinCruisingTime time.Duration
+	SmoothingWindow int
+	Altitude        AltitudeSource
+	QNH             float64
+}
+
+// DefaultPhaseOptions returns the PhaseOptions used by Phases(), tuned for
+// sailplanes. The MaxTurnRate check is disabled.
+func DefaultPhaseOptions() PhaseOptions {
+	return PhaseOptions{
+		MinTurnRate:     MinTurnRate,
+		MinCirclingTime: MinCirclingTime * time.Second,
+		MinCruisingTime: MinCruisingTime * time.Second,
+	}
+}
+
+// PhasePresets holds the PhaseOptions for different aircraft types.
+var PhasePresets = map[string]PhaseOptions{
+	"glider": DefaultPhaseOptions(),
+	"motorglider": {
+		MinTurnRate:     MinTurnRate,
+		MaxTurnRate:     MaxTurnRate,
+		MinCirclingTime: 20 * time.Second,
+		MinCruisingTime: 15 * time.Second,
+		SmoothingWindow: 2,
+	},
+	"hangglider": {
+		MinTurnRate:     8,
+		MaxTurnRate:     35,
+		MinCirclingTime: 15 * time.Second,
+		MinCruisingTime: 10 * time.Second,
+		SmoothingWindow: 2,
+	},
+	"paraglider": {
+		MinTurnRate:     8,
+		MaxTurnRate:     45,
+		MinCirclingTime: 20 * time.Second,
+		MinCruisingTime: 15 * time.Second,
+		SmoothingWindow: 3,
+	},
+}
+
+// PhasePreset returns the PhaseOptions for the given aircraft type, one of
+// the keys in PhasePresets.
+func PhasePreset(name string) (PhaseOptions, error) {
+	opts, ok := PhasePresets[name]
+	if !ok {
+		return PhaseOptions{}, fmt.Errorf("unknown phase preset '%v'", name)
+	}
+	return opts, nil
+}
+
 // Phase is a flight phase (towing, cruising, circling).
+//
+// WindSpeed (km/h) and WindDirection (degrees, where it blows from) are
+// estimated from the circling drift for circling phases, and taken from the
+// flight wind profile for cruising phases (see Track.Wind()). AvgAirSpeed is
+// the cruising speed in km/h with the wind removed.
+//
+// StartAltitude and EndAltitude are taken from the altitude source in the
+// PhaseOptions.
 type Phase struct {
-	Type         PhaseType
-	CirclingType CirclingType
-	Start        Point
-	StartIndex   int
-	End          Point
-	EndIndex     int
-	AvgVario     float64
-	TopVario     float64
-	AvgGndSpeed  float64
-	TopGndSpeed  float64
-	Distance     float64
-	LD           float64
-	Centroid     s2.LatLng
-	CellID       s2.CellID
+	Type          PhaseType
+	CirclingType  CirclingType
+	Start         Point
+	StartIndex    int
+	StartAltitude int64
+	End           Point
+	EndIndex      int
+	EndAltitude   int64
+	AvgVario      float64
+	TopVario      float64
+	AvgGndSpeed   float64
+	TopGndSpeed   float64
+	Distance      float64
+	LD            float64
+	Centroid      s2.LatLng
+	CellID        s2.CellID
+	WindSpeed     float64
+	WindDirection float64
+	AvgAirSpeed   float64
 }
 
 // Phases returns the list of flight phases for the Track.
-// Each phases is one of Cruising, Circling, Towing or Unknown.
+// Each phases is one of Cruising, Circling, Towing, EngineOn or Unknown.
+//
+// Points before takeoff and after landing are Unknown, and the launch is
+// Towing (or EngineOn for self launching gliders). See Track.TakeoffTime.
+//
+// It uses the DefaultPhaseOptions(), see PhasesWithOptions() for others.
 func (track *Track) Phases() ([]Phase, error) {
+	return track.PhasesWithOptions(DefaultPhaseOptions())
+}
 
-	if len(track.phases) > 0 {
+// PhasesWithOptions returns the list of flight phases for the Track, using
+// the given thresholds.
+//
+// Phases are cached in the Track, and calculated again only if the options
+// differ from the previous call.
+func (track *Track) PhasesWithOptions(opts PhaseOptions) ([]Phase, error) {
+
+	if len(track.phases) > 0 && opts == track.phaseOpts {
 		return track.phases, nil
 	}
+	track.phases = nil
+	track.phaseOpts = opts
 
 	if len(track.Points) < 2 {
 		return []Phase{}, fmt.Errorf("track has %v points, min 2 required",
 			len(track.Points))
 	}
-
-	var currPhase PhaseType
-	var startIndex int
-	var currPoint Point
-	var turning bool
-	//var turnRate float64
-
-	currPhase = Cruising
-	track.phases = []Phase{
-		Phase{Type: Cruising, StartIndex: 0, Start: track.Points[0]},
+	alts, err := track.Altitudes(opts.Altitude, opts.QNH)
+	if err != nil {
+		return []Phase{}, err
 	}
+	track.altitudes = alts
 
 	// we need the bearings for each point to calculate turn rates
 	var d float64
@@ -119,9 +225,56 @@ func (track *Track) Phases() ([]Phase, error) {
 		track.Points[i].speed = d / track.Points[i].Time.Sub(track.Points[i-1].Time).Seconds()
 	}
 
-	for i := 0; i < len(track.Points)-1; i++ {
+	// labels holds the phase type of each segment between consecutive points
+	labels := make([]PhaseType, len(track.Points)-1)
+	flight := track.detectFlight()
+	for i := range labels {
+		labels[i] = Unknown
+	}
+	track.circlingLabels(labels, flight.release, flight.landing, opts)
+	for i := flight.takeoff; i < flight.release; i++ {
+		labels[i] = flight.launch
+	}
+	for _, run := range flight.engine {
+		for i := run[0]; i < run[1]; i++ {
+			labels[i] = EngineOn
+		}
+	}
+
+	for start, i := 0, 1; i <= len(labels); i++ {
+		if i < len(labels) && labels[i] == labels[start] {
+			continue
+		}
+		p := Phase{Type: labels[start], StartIndex: start, EndIndex: i}
+		track.phaseStats(&p)
+		track.phases = append(track.phases, p)
+		start = i
+	}
+	track.setPhasesWind()
+
+	return track.phases, nil
+}
+
+// circlingLabels sets the labels between points from and to as Cruising or
+// Circling, based on the turn rate.
+func (track *Track) circlingLabels(labels []PhaseType, from, to int, opts PhaseOptions) {
+	var currPhase PhaseType
+	var startIndex int
+	var currPoint Point
+	var turning bool
+
+	currPhase = Cruising
+	phaseType, phaseStart := Cruising, from
+	wrapPhase := func(index int, next PhaseType) {
+		for i := phaseStart; i < index; i++ {
+			labels[i] = phaseType
+		}
+		phaseType, phaseStart = next, index
+	}
+
+	for i := from; i < to; i++ {
 		currPoint = track.Points[i]
-		turning, _ = track.isTurning(i)
+		turning, _ = track.isTurning(i, opts)
 
 		if currPhase == Cruising {
 			// if cruising check for turning
@@ -133,10 +286,10 @@ func (track *Track) Phases() ([]Phase, error) {
 		} else if currPhase == PossibleCircling {
 			// if possible circling check for turning longer than min circling time
 			if turning {
-				if currPoint.Time.Sub(track.Points[startIndex].Time).Seconds() > MinCirclingTime {
+				if currPoint.Time.Sub(track.Points[startIndex].Time).Seconds() > opts.MinCirclingTime.Seconds() {
 					// if true then set circling
 					currPhase = Circling
-					track.wrapPhase(startIndex, Circling)
+					wrapPhase(startIndex, Circling)
 				}
 			} else {
 				// if not go back to cruising
@@ -152,10 +305,10 @@ func (track *Track) Phases() ([]Phase, error) {
 		} else if currPhase == PossibleCruising {
 			// if possible cruising check for longer than min cruising
 			if !turning {
-				if currPoint.Time.Sub(track.Points[startIndex].Time).Seconds() > MinCruisingTime {
+				if currPoint.Time.Sub(track.Points[startIndex].Time).Seconds() > opts.MinCruisingTime.Seconds() {
 					// if true then set cruising
 					currPhase = Cruising
-					track.wrapPhase(startIndex, Cruising)
+					wrapPhase(startIndex, Cruising)
 				}
 			} else {
 				// if not go back to circling
@@ -163,18 +316,18 @@ func (track *Track) Phases() ([]Phase, error) {
 			}
 		}
 	}
-
-	return track.phases, nil
+	wrapPhase(to, Unknown)
 }
 
-func (track *Track) wrapPhase(index int, phaseType PhaseType) {
-	p := &track.phases[len(track.phases)-1]
+// phaseStats sets the start and end points of the phase, and computes its
+// stats.
+func (track *Track) phaseStats(p *Phase) {
+	p.Start = track.Points[p.StartIndex]
+	p.End = track.Points[p.EndIndex]
+	p.StartAltitude = track.altitude(p.StartIndex)
+	p.EndAltitude = track.altitude(p.EndIndex)
 
-	p.EndIndex = index
-	p.End = track.Points[index]
-
-	// compute phase stats
-	altGain := float64(p.End.GNSSAltitude - p.Start.GNSSAltitude)
+	altGain := float64(p.EndAltitude - p.StartAltitude)
 	p.Distance = p.End.distance - p.Start.distance
 	duration := p.Duration().Seconds()
 	if duration != 0 {
@@ -185,21 +338,51 @@ func (track *Track) wrapPhase(index int, phaseType PhaseType) {
 	if p.Type == Cruising && altGain != 0 {
 		p.LD = p.Distance * 1000.0 / math.Abs(altGain)
 	}
-	pts := make([]s2.LatLng, p.EndIndex-p.StartIndex)
-	for i := p.StartIndex; i < p.EndIndex; i++ {
-		pts[i-p.StartIndex] = track.Points[i].LatLng
+	for i := p.StartIndex + 1; i <= p.EndIndex; i++ {
+		a, b := track.Points[i-1], track.Points[i]
+		if s := b.Time.Sub(a.Time).Seconds(); s != 0 {
+			p.TopVario = math.Max(p.TopVario, float64(track.altitude(i)-track.altitude(i-1))/s)
+		}
+		p.TopGndSpeed = math.Max(p.TopGndSpeed, a.Speed(b))
+	}
+	if p.Type == Circling {
+		p.CirclingType = circlingType(track.Points[p.StartIndex : p.EndIndex+1])
 	}
-	centroid := s2.LatLngFromPoint(s2.PolylineFromLatLngs(pts).Centroid())
-	p.CellID = s2.CellIDFromLatLng(centroid)
-	p.CellID = p.CellID.Parent(14)
-	p.Centroid = centroid
+	p.Centroid = centroid(track.Points[p.StartIndex:p.EndIndex])
+	p.CellID = s2.CellIDFromLatLng(p.Centroid).Parent(14)
+}
 
-	track.phases = append(track.phases, Phase{Type: phaseType, StartIndex: index, Start: track.Points[index]})
+// isTurning checks the turn rate at point i, averaged over the smoothing
+// window, against the given options.
+func (track *Track) isTurning(i int, opts PhaseOptions) (bool, float64) {
+	window := opts.SmoothingWindow
+	if window < 1 {
+		window = 1
+	}
+	if window > i+1 {
+		window = i + 1
+	}
+	turnRate := 0.0
+	for j := i - window + 1; j <= i; j++ {
+		turnRate += track.turnRate(j, opts.MaxTurnRate > 0)
+	}
+	turnRate /= float64(window)
+	if opts.MaxTurnRate > 0 && turnRate > opts.MaxTurnRate {
+		return false, turnRate
+	}
+	return turnRate > opts.MinTurnRate, turnRate
 }
 
-func (track *Track) isTurning(i int) (bool, float64) {
-	turnRate := (track.Points[i+1].bearing - track.Points[i].bearing).Abs().Degrees() / track.Points[i+1].Time.Sub(track.Points[i].Time).Seconds()
-	return math.Abs(turnRate) > MinTurnRate, turnRate
+// turnRate returns the absolute turn rate in degrees per second at point i.
+//
+// If normalized is set the bearing change is taken in the range [0, 180],
+// otherwise it is the plain difference in bearing.
+func (track *Track) turnRate(i int, normalized bool) float64 {
+	change := (track.Points[i+1].bearing - track.Points[i].bearing).Abs().Degrees()
+	if normalized {
+		change = math.Abs(math.Remainder(change, 360))
+	}
+	return change / track.Points[i+1].Time.Sub(track.Points[i].Time).Seconds()
 }
 
 // Duration returns the duration of this flight phase.
@@ -208,8 +391,14 @@ func (p *Phase) Duration() time.Duration {
 }
 
 func (track *Track) EncodePhases(format string) ([]byte, error) {
+	return track.EncodePhasesWithOptions(format, DefaultPhaseOptions())
+}
+
+// EncodePhasesWithOptions returns the flight phases calculated with the given
+// options in the given format.
+func (track *Track) EncodePhasesWithOptions(format string, opts PhaseOptions) ([]byte, error) {
 
-	phases, err := track.Phases()
+	phases, err := track.PhasesWithOptions(opts)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -219,10 +408,7 @@ func (track *Track) EncodePhases(format string) ([]byte, error) {
 		return json.MarshalIndent(phases, "", "  ")
 	case "kml", "kmz":
 		buf := new(bytes.Buffer)
-		k, err := track.encodePhasesKML()
-		if err != nil {
-			return buf.Bytes(), err
-		}
+		k := track.encodePhasesKML(phases)
 		if err := k.WriteIndent(buf, "", "  "); err != nil {
 			return buf.Bytes(), err
 		}
@@ -230,24 +416,27 @@ func (track *Track) EncodePhases(format string) ([]byte, error) {
 	case "yaml":
 		return yaml.Marshal(phases)
 	case "csv":
-		return track.encodePhasesCSV()
+		return track.encodePhasesCSV(phases)
+	case "igc":
+		return track.encodePhasesIGC(phases)
+	case "geojson":
+		return track.encodePhasesGeoJSON(phases)
+	case "gpx":
+		return track.encodePhasesGPX(phases)
 	default:
 		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
 	}
 }
 
-func (track *Track) encodePhasesCSV() ([]byte, error) {
+func (track *Track) encodePhasesCSV(phases []Phase) ([]byte, error) {
 
-	phases, err := track.Phases()
-	if err != nil {
-		return []byte{}, err
-	}
 	records := make([][]string, len(phases))
 	/**records[0] = []string{
 	"TrackID", "Type", "CirclingType", "StartTime", "StartAlt",
 	"StartIndex", "EndTime", "EndAlt", "EndIndex", "Duration",
 	"AvgVario", "TopVario", "AvgGndSpeed", "TopGndSpeed", "Distance",
-	"LD", "CentroidLat", "CentroidLng", "CellID"}*/
+	"LD", "CentroidLat", "CentroidLng", "CellID", "WindSpeed",
+	"WindDirection", "AvgAirSpeed"}*/
 	var p Phase
 	for i := 0; i < len(phases); i++ {
 		p = phases[i]
@@ -257,10 +446,10 @@ func (track *Track) encodePhasesCSV() ([]byte, error) {
 			fmt.Sprintf("%d", p.Type),
 			fmt.Sprintf("%d", p.CirclingType),
 			fmt.Sprintf("%v", p.Start.Time.Format("15:04:05")),
-			fmt.Sprintf("%d", p.Start.GNSSAltitude),
+			fmt.Sprintf("%d", p.StartAltitude),
 			fmt.Sprintf("%d", p.StartIndex),
 			fmt.Sprintf("%v", p.End.Time.Format("15:04:05")),
-			fmt.Sprintf("%d", p.End.GNSSAltitude),
+			fmt.Sprintf("%d", p.EndAltitude),
 			fmt.Sprintf("%d", p.EndIndex),
 			fmt.Sprintf("%f", p.Duration().Seconds()),
 			fmt.Sprintf("%f", p.AvgVario), fmt.Sprintf("%f", p.TopVario),
@@ -269,19 +458,34 @@ func (track *Track) encodePhasesCSV() ([]byte, error) {
 			fmt.Sprintf("%f", p.Distance), fmt.Sprintf("%f", p.LD),
 			fmt.Sprintf("%f", p.Centroid.Lat.Degrees()),
 			fmt.Sprintf("%f", p.Centroid.Lng.Degrees()),
-			fmt.Sprintf("%d", p.CellID)}
+			fmt.Sprintf("%d", p.CellID),
+			fmt.Sprintf("%f", p.WindSpeed), fmt.Sprintf("%f", p.WindDirection),
+			fmt.Sprintf("%f", p.AvgAirSpeed)}
 	}
 
 	buf := new(bytes.Buffer)
 	w := csv.NewWriter(buf)
-	err = w.WriteAll(records)
+	err := w.WriteAll(records)
 	if err != nil {
 		return buf.Bytes(), err
 	}
 	return buf.Bytes(), nil
 }
 
-func (track *Track) encodePhasesKML() (*kml.CompoundElement, error) {
+// encodePhasesIGC returns the track in IGC format with one L record per phase.
+func (track *Track) encodePhasesIGC(phases []Phase) ([]byte, error) {
+
+	t := *track
+	t.Logbook = append([]string{}, track.Logbook...)
+	for _, p := range phases {
+		t.Logbook = append(t.Logbook, fmt.Sprintf("XEZPHASE %d %v %v %d %d",
+			p.Type, p.Start.Time.Format(TimeFormat), p.End.Time.Format(TimeFormat),
+			p.StartIndex, p.EndIndex))
+	}
+	return t.encodeIGC()
+}
+
+func (track *Track) encodePhasesKML(phases []Phase) *kml.CompoundElement {
 
 	result := kml.Document()
 	result.Add(
@@ -306,15 +510,21 @@ func (track *Track) encodePhasesKML() (*kml.CompoundElement, error) {
 				kml.Width(4),
 			),
 		),
+		kml.SharedStyle(
+			"launch",
+			kml.LineStyle(
+				kml.Color(color.RGBA{R: 127, G: 127, B: 127, A: 127}),
+				kml.Width(4),
+			),
+		),
 	)
 
-	phases, err := track.Phases()
-	if err != nil {
-		return result, err
-	}
-
-	for i := 0; i < len(phases)-2; i++ {
+	for i := 0; i < len(phases); i++ {
 		phase := phases[i]
+		// ground points before takeoff and after landing
+		if phase.Type == Unknown {
+			continue
+		}
 		coords := make([]kml.Coordinate, phase.EndIndex-phase.StartIndex+1)
 		for i := phase.StartIndex; i <= phase.EndIndex; i++ {
 			p := track.Points[i]
@@ -327,6 +537,8 @@ func (track *Track) encodePhasesKML() (*kml.CompoundElement, error) {
 			style = "#attempt"
 		} else if phase.Type == Circling {
 			style = "#circling"
+		} else if phase.Type == Towing || phase.Type == EngineOn {
+			style = "#launch"
 		}
 		result.Add(
 			kml.Placemark(
@@ -342,8 +554,8 @@ func (track *Track) encodePhasesKML() (*kml.CompoundElement, error) {
 		name := fmt.Sprintf("Lat: %v Lng: %v",
 			phase.Centroid.Lat.Degrees(), phase.Centroid.Lng.Degrees())
 		desc := fmt.Sprintf("Alt Gain: %dm (%dm %dm)<br/>Distance: %.2fkm<br/>Speed: %.2fkm/h<br/>LD: %v<br/>Vario: %.1fm/s<br/>Cell: %v<br/>",
-			phase.End.GNSSAltitude-phase.Start.GNSSAltitude,
-			phase.Start.GNSSAltitude, phase.End.GNSSAltitude, phase.Distance,
+			phase.EndAltitude-phase.StartAltitude,
+			phase.StartAltitude, phase.EndAltitude, phase.Distance,
 			phase.AvgGndSpeed, phase.LD, phase.AvgVario, phase.CellID)
 		result.Add(
 			kml.Placemark(
@@ -356,5 +568,5 @@ func (track *Track) encodePhasesKML() (*kml.CompoundElement, error) {
 				),
 			))
 	}
-	return result, nil
+	return result
 }
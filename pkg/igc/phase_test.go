@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 type phaseTest struct {
@@ -100,10 +101,11 @@ func TestEncodePhasesKML(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			kml, err := track.encodePhasesKML()
+			phases, err := track.Phases()
 			if err != nil {
 				t.Fatal(err)
 			}
+			kml := track.encodePhasesKML(phases)
 
 			buf := new(bytes.Buffer)
 			err = kml.WriteIndent(buf, "", "  ")
@@ -124,3 +126,145 @@ func TestEncodePhasesKML(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodePhasesFormats(t *testing.T) {
+	// only the short flight, the long flight goldens would be too big
+	test := phaseTests[0]
+	for _, format := range []string{"geojson", "gpx"} {
+		t.Run(format, func(t *testing.T) {
+			f := filepath.Join("../../testdata/phases", fmt.Sprintf("%v.igc", test.name))
+			golden := fmt.Sprintf("%v.golden.%v", f, format)
+			track, err := ParseLocation(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := track.EncodePhases(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// update golden if flag is passed
+			if *update {
+				if err = ioutil.WriteFile(golden, result, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			b, _ := ioutil.ReadFile(golden)
+			if string(b) != string(result) {
+				t.Errorf("expected\n%v\ngot\n%v\n", string(b), string(result))
+			}
+		})
+	}
+}
+
+func TestPhasesStats(t *testing.T) {
+	track := circlingTrack(100, 25*time.Second, false, 2, 0, 120*time.Second)
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	circling := 0
+	for _, p := range phases {
+		if p.Type != Circling {
+			continue
+		}
+		circling++
+		if p.CirclingType != Left {
+			t.Errorf("expected left circling got %v", p.CirclingType)
+		}
+		if p.TopVario < 2 || p.TopGndSpeed < p.AvgGndSpeed {
+			t.Errorf("expected top vario and speed set got %v %v", p.TopVario, p.TopGndSpeed)
+		}
+	}
+	if circling != 1 {
+		t.Errorf("expected 1 circling phase got %v", circling)
+	}
+}
+
+func TestPhasesWithOptions(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases, err := track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := len(phases)
+
+	opts := DefaultPhaseOptions()
+	opts.MinTurnRate = 1000
+	phases, err = track.PhasesWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range phases {
+		if p.Type == Circling {
+			t.Errorf("expected no circling phases got %+v", p)
+		}
+	}
+	// phases always uses the default options
+	phases, err = track.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != expected {
+		t.Errorf("expected %v phases got %v", expected, len(phases))
+	}
+}
+
+func TestPhasePresets(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range PhasePresets {
+		opts, err := PhasePreset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := track.PhasesWithOptions(opts); err != nil {
+			t.Errorf("%v :: %v", name, err)
+		}
+	}
+	if _, err := PhasePreset("unknown"); err == nil {
+		t.Errorf("expected error for unknown preset")
+	}
+}
+
+func TestPhasesMaxTurnRate(t *testing.T) {
+	// circling at 14.4°/s is ignored with a lower max turn rate
+	track := circlingTrack(100, 25*time.Second, true, 2, 0, 120*time.Second)
+	opts := DefaultPhaseOptions()
+	opts.MaxTurnRate = 10
+	phases, err := track.PhasesWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range phases {
+		if p.Type == Circling {
+			t.Errorf("expected no circling with max turn rate %v", opts.MaxTurnRate)
+		}
+	}
+}
+
+func TestSimplifyPhases(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := track.Phases(); err != nil {
+		t.Fatal(err)
+	}
+	simplified, _ := track.Simplify(0.0001)
+	phases, err := simplified.Phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range phases {
+		if p.StartIndex >= len(simplified.Points) || p.EndIndex >= len(simplified.Points) {
+			t.Errorf("phase %+v out of range for %v points", p, len(simplified.Points))
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// HeightBandStep is the height in meters of each band in the Stats height
+// histogram.
+const HeightBandStep = 250
+
+// LDBands are the upper limits of the glide ratio bands in Stats, with an
+// extra band for higher values.
+var LDBands = []float64{10, 20, 30, 40, 50}
+
+// PhaseStats holds the totals for all phases of the same type.
+type PhaseStats struct {
+	Count    int
+	Duration time.Duration
+	Distance float64
+}
+
+// LDBand holds the cruising distance in kms flown with a glide ratio
+// between Min and Max. Max is zero for the last band, with no upper limit.
+type LDBand struct {
+	Min      float64
+	Max      float64
+	Count    int
+	Distance float64
+}
+
+// HeightBand holds the time spent between Altitude and Altitude plus
+// HeightBandStep.
+type HeightBand struct {
+	Altitude int64
+	Duration time.Duration
+}
+
+// Stats is a summary of the flight.
+//
+// Only the flying part of the track is considered, from the first to the
+// last phase which is not Unknown (see Track.Phases()). Distances are in kms,
+// speeds in km/h, climb rates in m/s and altitudes in meters, taken from the
+// altitude source of the phases (see PhaseOptions).
+type Stats struct {
+	Start              time.Time
+	End                time.Time
+	Duration           time.Duration
+	Distance           float64
+	MaxAltitude        int64
+	MaxAltitudeTime    time.Time
+	MinAltitude        int64
+	MinAltitudeTime    time.Time
+	Phases             map[string]PhaseStats
+	CirclingPercentage float64
+	Thermals           int
+	AvgClimb           float64
+	AvgCruiseSpeed     float64
+	AvgLD              float64
+	LDBands            []LDBand
+	HeightBands        []HeightBand
+}
+
+// Stats returns the summary of the Track, aggregated from its Phases().
+func (track *Track) Stats() (Stats, error) {
+	return track.StatsWithOptions(DefaultPhaseOptions())
+}
+
+// StatsWithOptions returns the summary of the Track, aggregated from the
+// phases calculated with the given options (see PhasesWithOptions()).
+func (track *Track) StatsWithOptions(opts PhaseOptions) (Stats, error) {
+	phases, err := track.PhasesWithOptions(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	thermals, err := track.ThermalsWithOptions(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{Phases: make(map[string]PhaseStats), LDBands: ldBands()}
+	first, last := -1, -1
+	var circling, cruising time.Duration
+	var cruiseDistance, cruiseLoss float64
+	for _, p := range phases {
+		if p.Type == Unknown {
+			continue
+		}
+		if first < 0 {
+			first = p.StartIndex
+		}
+		last = p.EndIndex
+
+		ps := stats.Phases[p.Type.String()]
+		ps.Count++
+		ps.Duration += p.Duration()
+		ps.Distance += p.Distance
+		stats.Phases[p.Type.String()] = ps
+		stats.Duration += p.Duration()
+		stats.Distance += p.Distance
+
+		switch p.Type {
+		case Circling:
+			circling += p.Duration()
+		case Cruising:
+			cruising += p.Duration()
+			cruiseDistance += p.Distance
+			loss := float64(p.StartAltitude - p.EndAltitude)
+			if loss > 0 {
+				cruiseLoss += loss
+			}
+			for i := range stats.LDBands {
+				b := &stats.LDBands[i]
+				if p.LD >= b.Min && (p.LD < b.Max || b.Max == 0) {
+					b.Count++
+					b.Distance += p.Distance
+					break
+				}
+			}
+		}
+	}
+	if first < 0 {
+		return stats, nil
+	}
+
+	stats.Start, stats.End = track.Points[first].Time, track.Points[last].Time
+	if stats.Duration > 0 {
+		stats.CirclingPercentage = 100 * circling.Seconds() / stats.Duration.Seconds()
+	}
+	if cruising > 0 {
+		stats.AvgCruiseSpeed = cruiseDistance / cruising.Hours()
+	}
+	if cruiseLoss > 0 {
+		stats.AvgLD = cruiseDistance * 1000 / cruiseLoss
+	}
+
+	stats.Thermals = len(thermals)
+	var gain int64
+	var climbing time.Duration
+	for _, t := range thermals {
+		gain += t.AltGain()
+		climbing += t.Duration()
+	}
+	if climbing > 0 {
+		stats.AvgClimb = float64(gain) / climbing.Seconds()
+	}
+
+	stats.MaxAltitude, stats.MinAltitude = math.MinInt64, math.MaxInt64
+	bands := make(map[int64]time.Duration)
+	for i := first; i <= last; i++ {
+		p, alt := track.Points[i], track.altitude(i)
+		if alt > stats.MaxAltitude {
+			stats.MaxAltitude, stats.MaxAltitudeTime = alt, p.Time
+		}
+		if alt < stats.MinAltitude {
+			stats.MinAltitude, stats.MinAltitudeTime = alt, p.Time
+		}
+		if i < last {
+			band := alt - mod(alt, HeightBandStep)
+			bands[band] += track.Points[i+1].Time.Sub(p.Time)
+		}
+	}
+	for alt, d := range bands {
+		stats.HeightBands = append(stats.HeightBands, HeightBand{Altitude: alt, Duration: d})
+	}
+	sort.Slice(stats.HeightBands, func(i, j int) bool {
+		return stats.HeightBands[i].Altitude < stats.HeightBands[j].Altitude
+	})
+	return stats, nil
+}
+
+func ldBands() []LDBand {
+	bands := make([]LDBand, len(LDBands)+1)
+	min := 0.0
+	for i, max := range LDBands {
+		bands[i] = LDBand{Min: min, Max: max}
+		min = max
+	}
+	bands[len(LDBands)] = LDBand{Min: min}
+	return bands
+}
@@ -0,0 +1,112 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStats(t *testing.T) {
+	track := launchTrack(
+		[4]float64{0, 0, 60, 0},
+		[4]float64{100, 10, 40, 0},
+		[4]float64{90, -1, 300, 0},
+		[4]float64{0, 0, 60, 0},
+	)
+	stats, err := track.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := stats.Duration.Seconds(); math.Abs(d-340) > 10 {
+		t.Errorf("expected duration 340s got %v", d)
+	}
+	// 40s at 100km/h and 300s at 90km/h
+	if math.Abs(stats.Distance-8.61) > 0.3 {
+		t.Errorf("expected distance 8.61km got %v", stats.Distance)
+	}
+	if stats.MaxAltitude < 590 || stats.MaxAltitude > 610 {
+		t.Errorf("expected max altitude 600m got %v", stats.MaxAltitude)
+	}
+	if stats.Phases["towing"].Count != 1 || stats.Phases["cruising"].Count != 1 {
+		t.Errorf("expected one towing and cruising phase got %+v", stats.Phases)
+	}
+	if stats.CirclingPercentage != 0 || stats.Thermals != 0 {
+		t.Errorf("expected no circling got %v%% %v thermals", stats.CirclingPercentage, stats.Thermals)
+	}
+	if math.Abs(stats.AvgCruiseSpeed-90) > 2 || math.Abs(stats.AvgLD-25) > 1 {
+		t.Errorf("expected 90km/h with LD 25 got %v %v", stats.AvgCruiseSpeed, stats.AvgLD)
+	}
+	if b := stats.LDBands[2]; b.Count != 1 {
+		t.Errorf("expected cruise in LD band 20-30 got %+v", stats.LDBands)
+	}
+	total := time.Duration(0)
+	for _, b := range stats.HeightBands {
+		total += b.Duration
+	}
+	if total != stats.End.Sub(stats.Start) {
+		t.Errorf("expected height bands total %v got %v", stats.End.Sub(stats.Start), total)
+	}
+}
+
+func TestStatsFlight(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-long-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := track.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Thermals == 0 || stats.CirclingPercentage <= 0 || stats.MaxAltitude <= 0 {
+		t.Errorf("expected thermals in flight got %+v", stats)
+	}
+	if _, err := json.Marshal(stats); err != nil {
+		t.Error(err)
+	}
+	if _, err := yaml.Marshal(stats); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatsAltitude(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-long-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultPhaseOptions()
+	opts.Altitude = AltitudeAuto
+	stats, err := track.StatsWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// gnss altitude dropouts are filled from the pressure altitude
+	if stats.MinAltitude <= 0 || stats.AvgClimb <= 0 {
+		t.Errorf("expected positive min altitude and climb got %v %v",
+			stats.MinAltitude, stats.AvgClimb)
+	}
+}
+
+func TestStatsZeroPoints(t *testing.T) {
+	track := NewTrack()
+	if _, err := track.Stats(); err == nil {
+		t.Errorf("expected error for track with 0 points")
+	}
+}
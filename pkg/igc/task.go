@@ -0,0 +1,189 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image/color"
+
+	kml "github.com/twpayne/go-kml"
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeTask returns the given Task in the requested format.
+//
+// Supported formats are json, yaml, csv, kml and kmz. The csv has one row for
+// each of the task points, with the distance of the leg ending there. The
+// kml and kmz draw the task legs over the track.
+func (track *Track) EncodeTask(task Task, format string) ([]byte, error) {
+	return track.EncodeTaskWithOptions(task, format, EncodeTaskOptions{})
+}
+
+// EncodeTaskOptions holds the settings to encode a Task.
+//
+// Closed is set for closed courses (triangles), where the kml and kmz task
+// line goes back from the last turnpoint to the first one and is named after
+// the course length (see Triangle()).
+type EncodeTaskOptions struct {
+	Closed bool
+}
+
+// EncodeTaskWithOptions returns the given Task in the requested format, as
+// EncodeTask but with the given options.
+func (track *Track) EncodeTaskWithOptions(task Task, format string, opts EncodeTaskOptions) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(task, "", "  ")
+	case "yaml":
+		return yaml.Marshal(task)
+	case "csv":
+		return encodeTaskCSV(task)
+	case "kml", "kmz":
+		return encodeTaskKML(track, task, format, opts)
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+// points returns the task points from Start to Finish, with their names.
+func (task *Task) points() ([]Point, []string) {
+	p := []Point{task.Start}
+	names := []string{"Start"}
+	for i, t := range task.Turnpoints {
+		p = append(p, t)
+		names = append(names, fmt.Sprintf("TP%d", i+1))
+	}
+	return append(p, task.Finish), append(names, "Finish")
+}
+
+func encodeTaskCSV(task Task) ([]byte, error) {
+	points, names := task.points()
+	records := make([][]string, len(points))
+	total := 0.0
+	for i, p := range points {
+		leg := 0.0
+		if i > 0 {
+			leg = points[i-1].Distance(p)
+		}
+		total += leg
+		records[i] = []string{
+			names[i],
+			p.Time.Format("15:04:05"),
+			fmt.Sprintf("%f", p.Lat.Degrees()),
+			fmt.Sprintf("%f", p.Lng.Degrees()),
+			fmt.Sprintf("%d", p.GNSSAltitude),
+			fmt.Sprintf("%f", leg),
+			fmt.Sprintf("%f", total)}
+	}
+
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	err := w.WriteAll(records)
+	if err != nil {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
+
+func (track *Track) encodeTaskKML(task Task, opts EncodeTaskOptions) *kml.CompoundElement {
+	result := kml.Document()
+	result.Add(
+		kml.SharedStyle(
+			"track",
+			kml.LineStyle(
+				kml.Color(color.RGBA{R: 0, G: 0, B: 255, A: 127}),
+				kml.Width(2),
+			),
+		),
+		kml.SharedStyle(
+			"task",
+			kml.LineStyle(
+				kml.Color(color.RGBA{R: 255, G: 0, B: 0, A: 255}),
+				kml.Width(4),
+			),
+		),
+	)
+
+	coords := make([]kml.Coordinate, len(track.Points))
+	for i, p := range track.Points {
+		coords[i] = kml.Coordinate{Lon: p.Lng.Degrees(), Lat: p.Lat.Degrees(),
+			Alt: float64(p.GNSSAltitude)}
+	}
+	result.Add(
+		kml.Placemark(
+			kml.Name("Track"),
+			kml.StyleURL("#track"),
+			kml.LineString(
+				kml.Extrude(false),
+				kml.Tessellate(false),
+				kml.AltitudeMode("absolute"),
+				kml.Coordinates(coords...),
+			),
+		))
+
+	points, names := task.points()
+	coords = make([]kml.Coordinate, len(points))
+	for i, p := range points {
+		coords[i] = kml.Coordinate{Lon: p.Lng.Degrees(), Lat: p.Lat.Degrees()}
+	}
+	line, distance := coords, task.Distance()
+	if opts.Closed && len(task.Turnpoints) > 0 {
+		// close the course from the last turnpoint back to the first one
+		n := len(task.Turnpoints)
+		line = append(append([]kml.Coordinate{}, coords[:n+1]...), coords[1], coords[n+1])
+		distance = Triangle(task)
+	}
+	result.Add(
+		kml.Placemark(
+			kml.Name(fmt.Sprintf("Task %.2fkm", distance)),
+			kml.StyleURL("#task"),
+			kml.LineString(
+				kml.Tessellate(true),
+				kml.AltitudeMode("clampToGround"),
+				kml.Coordinates(line...),
+			),
+		))
+
+	for i, p := range points {
+		desc := fmt.Sprintf("Time: %v<br/>Alt: %dm<br/>", p.Time.Format("15:04:05"), p.GNSSAltitude)
+		if i > 0 {
+			desc = desc + fmt.Sprintf("Leg: %.2fkm<br/>", points[i-1].Distance(p))
+		}
+		result.Add(
+			kml.Placemark(
+				kml.Name(names[i]),
+				kml.Description(desc),
+				kml.Point(
+					kml.Coordinates(coords[i]),
+				),
+			))
+	}
+	return result
+}
+
+func encodeTaskKML(track *Track, task Task, format string, opts EncodeTaskOptions) ([]byte, error) {
+	metadata := fmt.Sprintf("%v : %v : %v", track.Date, track.Pilot, track.GliderType)
+
+	k := kml.Document(
+		kml.Name(metadata),
+		kml.Description(""),
+		track.encodeTaskKML(task, opts),
+	)
+	return writeKML(k, format)
+}
@@ -0,0 +1,113 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEncodeTask(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err = track.Simplify(0.0001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := NewDPOptimizer().Optimize(track, 2, Distance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"csv", "kml"} {
+		t.Run(format, func(t *testing.T) {
+			golden := fmt.Sprintf("../../testdata/optimize/optimize-short-flight-1.igc.golden.task.%v", format)
+			result, err := track.EncodeTask(task, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// update golden if flag is passed
+			if *update {
+				if err = ioutil.WriteFile(golden, result, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			b, _ := ioutil.ReadFile(golden)
+			if string(b) != string(result) {
+				t.Errorf("expected\n%v\ngot\n%v\n", string(b), string(result))
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		result, err := track.EncodeTask(task, "json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Task
+		if err := json.Unmarshal(result, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Turnpoints) != 2 || decoded.Distance() != task.Distance() {
+			t.Errorf("expected %v got %v", task, decoded)
+		}
+	})
+
+	t.Run("kmz", func(t *testing.T) {
+		result, err := track.EncodeTask(task, "kmz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := zip.NewReader(bytes.NewReader(result), int64(len(result))); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if _, err := track.EncodeTask(task, "unknown"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
+
+func TestEncodeTaskClosed(t *testing.T) {
+	track := Track{Points: []Point{NewPointFromLatLng(45, 5), NewPointFromLatLng(45.5, 5)}}
+	task := Task{
+		Start: NewPointFromLatLng(45, 5.01),
+		Turnpoints: []Point{NewPointFromLatLng(45, 5), NewPointFromLatLng(45.5, 5),
+			NewPointFromLatLng(45.25, 5.5)},
+		Finish: NewPointFromLatLng(45.01, 5),
+	}
+	result, err := track.EncodeTaskWithOptions(task, "kml", EncodeTaskOptions{Closed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("<name>Task %.2fkm</name>", Triangle(task))
+	if !strings.Contains(string(result), name) {
+		t.Errorf("expected task named after the course length %v", name)
+	}
+	// start, the turnpoints, back to the first turnpoint and finish
+	line := "5.01,45 5,45 5,45.5 5.5,45.25 5,45 5,45.01"
+	if !strings.Contains(string(result), line) {
+		t.Errorf("expected closed task line %v got\n%v", line, string(result))
+	}
+}
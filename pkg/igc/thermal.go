@@ -0,0 +1,340 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+	kml "github.com/twpayne/go-kml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ThermalMaxGap is the max time between two circling phases for them
+	// to be considered part of the same thermal.
+	ThermalMaxGap = 30 * time.Second
+	// MinThermalTime is the min duration of a thermal, shorter circling is
+	// considered an attempt and ignored.
+	MinThermalTime = 45 * time.Second
+	// BestClimbWindow is the time window used for the best climb rate.
+	BestClimbWindow = 30 * time.Second
+	// MinCirclingRatio is the min ratio of turns in one direction to
+	// consider the circling Left or Right instead of Mixed.
+	MinCirclingRatio = 0.8
+	// Gravity is the standard acceleration of gravity in m/s².
+	Gravity = 9.80665
+)
+
+// Thermal is a climb made of one or more consecutive circling phases.
+//
+// Altitudes are in meters, taken from the altitude source of the phases (see
+// PhaseOptions), and climb rates in m/s. Radius is in meters, BankAngle and
+// DriftBearing in degrees and DriftSpeed in km/h. The drift is the movement
+// of the circles center, which is where the wind blows to.
+type Thermal struct {
+	Start         Point
+	StartIndex    int
+	End           Point
+	EndIndex      int
+	EntryAltitude int64
+	ExitAltitude  int64
+	AvgClimb      float64
+	BestClimb     float64
+	Turns         float64
+	CirclingType  CirclingType
+	Radius        float64
+	BankAngle     float64
+	DriftBearing  float64
+	DriftSpeed    float64
+	Centroid      s2.LatLng
+}
+
+// Duration returns the duration of this thermal.
+func (t *Thermal) Duration() time.Duration {
+	return t.End.Time.Sub(t.Start.Time)
+}
+
+// AltGain returns the altitude gained in the thermal, in meters.
+func (t *Thermal) AltGain() int64 {
+	return t.ExitAltitude - t.EntryAltitude
+}
+
+// Thermals returns the list of thermals in the Track.
+//
+// Circling phases separated by less than ThermalMaxGap are grouped in the
+// same thermal, and thermals shorter than MinThermalTime are discarded.
+func (track *Track) Thermals() ([]Thermal, error) {
+	return track.ThermalsWithOptions(DefaultPhaseOptions())
+}
+
+// ThermalsWithOptions returns the list of thermals in the Track, from the
+// phases calculated with the given options (see PhasesWithOptions()).
+func (track *Track) ThermalsWithOptions(opts PhaseOptions) ([]Thermal, error) {
+	phases, err := track.PhasesWithOptions(opts)
+	if err != nil {
+		return []Thermal{}, err
+	}
+
+	thermals := []Thermal{}
+	start, end := -1, -1
+	for _, p := range phases {
+		if p.Type != Circling {
+			continue
+		}
+		if start >= 0 && p.Start.Time.Sub(track.Points[end].Time) > ThermalMaxGap {
+			thermals = track.appendThermal(thermals, start, end)
+			start = -1
+		}
+		if start < 0 {
+			start = p.StartIndex
+		}
+		end = p.EndIndex
+	}
+	if start >= 0 {
+		thermals = track.appendThermal(thermals, start, end)
+	}
+	return thermals, nil
+}
+
+func (track *Track) appendThermal(thermals []Thermal, start, end int) []Thermal {
+	if track.Points[end].Time.Sub(track.Points[start].Time) < MinThermalTime {
+		return thermals
+	}
+	return append(thermals, track.thermal(start, end))
+}
+
+// thermal returns the Thermal between the points at start and end.
+func (track *Track) thermal(start, end int) Thermal {
+	points := track.Points[start : end+1]
+	t := Thermal{
+		Start: points[0], StartIndex: start,
+		End: points[len(points)-1], EndIndex: end,
+		EntryAltitude: track.altitude(start),
+		ExitAltitude:  track.altitude(end),
+		CirclingType:  circlingType(points),
+	}
+	duration := t.Duration().Seconds()
+	if duration == 0 {
+		return t
+	}
+	t.AvgClimb = float64(t.AltGain()) / duration
+	t.BestClimb = track.bestClimb(start, end, BestClimbWindow)
+
+	// turns and radius from the total heading change and path length
+	turns := headingChanges(points)
+	total, length := 0.0, 0.0
+	for i, v := range turns {
+		total += math.Abs(v)
+		length += points[i].Distance(points[i+1]) * 1000
+	}
+	t.Turns = total / 360
+	if t.Turns > 0 {
+		t.Radius = length / (2 * math.Pi * t.Turns)
+		speed := length / duration
+		t.BankAngle = math.Atan(speed*speed/(Gravity*t.Radius)) * 180 / math.Pi
+	}
+
+	// drift from the center of the first to the center of the last turn
+	first, last := firstTurn(points, turns), lastTurn(points, turns)
+	a, b := centroid(points[:first+1]), centroid(points[last:])
+	pa, pb := Point{LatLng: a}, Point{LatLng: b}
+	pa.Time, pb.Time = points[0].Time, points[len(points)-1].Time
+	if first < last {
+		pa.Time, pb.Time = points[first/2].Time, points[(last+len(points)-1)/2].Time
+	}
+	t.DriftBearing = math.Mod(pa.Bearing(pb).Degrees()+360, 360)
+	t.DriftSpeed = pa.Speed(pb)
+	t.Centroid = centroid(points)
+	return t
+}
+
+// headingChanges returns the signed heading change in degrees between each
+// pair of consecutive legs, positive when turning right. The value at index
+// i is the change between the legs ending and starting at point i+1.
+func headingChanges(points []Point) []float64 {
+	changes := make([]float64, len(points)-1)
+	for i := 1; i < len(points)-1; i++ {
+		a := points[i-1].Bearing(points[i]).Degrees()
+		b := points[i].Bearing(points[i+1]).Degrees()
+		changes[i-1] = math.Remainder(b-a, 360)
+	}
+	return changes
+}
+
+// circlingType returns Left or Right if at least MinCirclingRatio of the
+// heading change is in that direction, Mixed otherwise.
+func circlingType(points []Point) CirclingType {
+	left, right := 0.0, 0.0
+	for _, v := range headingChanges(points) {
+		if v < 0 {
+			left -= v
+		} else {
+			right += v
+		}
+	}
+	if left+right == 0 {
+		return Mixed
+	} else if right >= MinCirclingRatio*(left+right) {
+		return Right
+	} else if left >= MinCirclingRatio*(left+right) {
+		return Left
+	}
+	return Mixed
+}
+
+// firstTurn returns the index of the point completing the first full turn,
+// or the last point if there is none.
+func firstTurn(points []Point, turns []float64) int {
+	total := 0.0
+	for i, v := range turns {
+		if total += math.Abs(v); total >= 360 {
+			return i + 1
+		}
+	}
+	return len(points) - 1
+}
+
+// lastTurn returns the index of the point starting the last full turn, or
+// the first point if there is none.
+func lastTurn(points []Point, turns []float64) int {
+	total := 0.0
+	for i := len(turns) - 1; i >= 0; i-- {
+		if total += math.Abs(turns[i]); total >= 360 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// bestClimb returns the best average climb rate between the points at start
+// and end over the given window, or the overall climb rate for shorter
+// periods.
+func (track *Track) bestClimb(start, end int, window time.Duration) float64 {
+	points := track.Points
+	best := math.Inf(-1)
+	j := start
+	for i := start; i <= end; i++ {
+		for j <= end && points[j].Time.Sub(points[i].Time) < window {
+			j++
+		}
+		if j > end {
+			break
+		}
+		s := points[j].Time.Sub(points[i].Time).Seconds()
+		best = math.Max(best, float64(track.altitude(j)-track.altitude(i))/s)
+	}
+	if math.IsInf(best, -1) {
+		if s := points[end].Time.Sub(points[start].Time).Seconds(); s != 0 {
+			return float64(track.altitude(end)-track.altitude(start)) / s
+		}
+		return 0
+	}
+	return best
+}
+
+// centroid returns the centroid of the given points.
+func centroid(points []Point) s2.LatLng {
+	if len(points) == 1 {
+		return points[0].LatLng
+	}
+	pts := make([]s2.LatLng, len(points))
+	for i, p := range points {
+		pts[i] = p.LatLng
+	}
+	return s2.LatLngFromPoint(s2.PolylineFromLatLngs(pts).Centroid())
+}
+
+// EncodeThermals returns the track thermals in the given format.
+//
+// Supported formats are json, yaml, csv, kml and kmz. The kml output has one
+// placemark per thermal with its stats.
+func (track *Track) EncodeThermals(format string) ([]byte, error) {
+	thermals, err := track.Thermals()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(thermals, "", "  ")
+	case "yaml":
+		return yaml.Marshal(thermals)
+	case "csv":
+		return encodeThermalsCSV(thermals)
+	case "kml", "kmz":
+		return writeKML(encodeThermalsKML(thermals), format)
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+func encodeThermalsCSV(thermals []Thermal) ([]byte, error) {
+	records := [][]string{{
+		"StartTime", "StartIndex", "EndTime", "EndIndex", "Duration",
+		"EntryAltitude", "ExitAltitude", "AvgClimb", "BestClimb", "Turns",
+		"CirclingType", "Radius", "BankAngle", "DriftBearing", "DriftSpeed",
+		"CentroidLat", "CentroidLng"}}
+	for _, t := range thermals {
+		records = append(records, []string{
+			t.Start.Time.Format(time.RFC3339), fmt.Sprintf("%d", t.StartIndex),
+			t.End.Time.Format(time.RFC3339), fmt.Sprintf("%d", t.EndIndex),
+			fmt.Sprintf("%.0f", t.Duration().Seconds()),
+			fmt.Sprintf("%d", t.EntryAltitude), fmt.Sprintf("%d", t.ExitAltitude),
+			fmt.Sprintf("%.2f", t.AvgClimb), fmt.Sprintf("%.2f", t.BestClimb),
+			fmt.Sprintf("%.1f", t.Turns), fmt.Sprintf("%d", t.CirclingType),
+			fmt.Sprintf("%.0f", t.Radius), fmt.Sprintf("%.0f", t.BankAngle),
+			fmt.Sprintf("%.0f", t.DriftBearing), fmt.Sprintf("%.1f", t.DriftSpeed),
+			fmt.Sprintf("%f", t.Centroid.Lat.Degrees()),
+			fmt.Sprintf("%f", t.Centroid.Lng.Degrees())})
+	}
+
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(records); err != nil {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeThermalsKML(thermals []Thermal) *kml.CompoundElement {
+	result := kml.Document()
+	for _, t := range thermals {
+		name := fmt.Sprintf("%+.1fm/s %dm", t.AvgClimb, t.AltGain())
+		desc := fmt.Sprintf("Time: %v - %v<br/>Alt: %dm - %dm<br/>Climb: %.1fm/s (best %.1fm/s)<br/>Turns: %.1f<br/>Radius: %.0fm<br/>Bank: %.0f°<br/>Drift: %.0f° %.1fkm/h<br/>",
+			t.Start.Time.Format("15:04:05"), t.End.Time.Format("15:04:05"),
+			t.EntryAltitude, t.ExitAltitude, t.AvgClimb, t.BestClimb,
+			t.Turns, t.Radius, t.BankAngle, t.DriftBearing, t.DriftSpeed)
+		result.Add(
+			kml.Placemark(
+				kml.Name(name),
+				kml.Description(desc),
+				kml.Point(
+					kml.AltitudeMode("absolute"),
+					kml.Coordinates(kml.Coordinate{
+						Lon: t.Centroid.Lng.Degrees(), Lat: t.Centroid.Lat.Degrees(),
+						Alt: float64(t.ExitAltitude),
+					}),
+				),
+			))
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// circlingTrack returns a track cruising north, then circling with the given
+// radius (m), period, direction and climb (m/s) while drifting east at the
+// given speed (m/s), and cruising north again.
+func circlingTrack(radius float64, period time.Duration, right bool, climb, drift float64, circling time.Duration) Track {
+	track := NewTrack()
+	t := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	mPerDeg := EarthRadius * 1000 * math.Pi / 180
+	lat0 := 45.0
+	north, east, alt := 0.0, 0.0, 1000.0
+	add := func(n, e, a float64) {
+		p := NewPointFromLatLng(lat0+n/mPerDeg, e/(mPerDeg*math.Cos(lat0*math.Pi/180)))
+		p.Time = t
+		p.GNSSAltitude = int64(math.Round(a))
+		track.Points = append(track.Points, p)
+		t = t.Add(time.Second)
+	}
+	speed := 2 * math.Pi * radius / period.Seconds()
+	for i := 0; i < 60; i++ {
+		add(north, east, alt)
+		north += speed
+		alt--
+	}
+	// circle center to the east (right) or west (left) of the track
+	sign := 1.0
+	if !right {
+		sign = -1
+	}
+	cn, ce := north, east+sign*radius
+	for i := 0; i < int(circling.Seconds()); i++ {
+		a := 2 * math.Pi * float64(i) / period.Seconds()
+		ce += drift
+		add(cn+radius*math.Sin(a), ce-sign*radius*math.Cos(a), alt)
+		alt += climb
+	}
+	// continue from the next point in the circle
+	a := 2 * math.Pi * math.Floor(circling.Seconds()) / period.Seconds()
+	north, east = cn+radius*math.Sin(a), ce+drift-sign*radius*math.Cos(a)
+	for i := 0; i < 60; i++ {
+		add(north, east, alt)
+		north += speed
+		alt--
+	}
+	return track
+}
+
+func TestThermals(t *testing.T) {
+	for _, right := range []bool{true, false} {
+		track := circlingTrack(100, 25*time.Second, right, 2, 5, 250*time.Second)
+		thermals, err := track.Thermals()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(thermals) != 1 {
+			t.Fatalf("expected 1 thermal got %v", len(thermals))
+		}
+		th := thermals[0]
+		expected := Left
+		if right {
+			expected = Right
+		}
+		if th.CirclingType != expected {
+			t.Errorf("expected circling type %v got %v", expected, th.CirclingType)
+		}
+		if math.Abs(th.Turns-10) > 1 {
+			t.Errorf("expected 10 turns got %v", th.Turns)
+		}
+		if math.Abs(th.AvgClimb-2) > 0.2 || math.Abs(th.BestClimb-2) > 0.2 {
+			t.Errorf("expected climb 2m/s got %v (best %v)", th.AvgClimb, th.BestClimb)
+		}
+		if math.Abs(th.Radius-100) > 15 {
+			t.Errorf("expected radius 100m got %v", th.Radius)
+		}
+		// tan(bank) = v²/(g*r), with v = 2*pi*100/25
+		if math.Abs(th.BankAngle-32.6) > 5 {
+			t.Errorf("expected bank angle 32.6 got %v", th.BankAngle)
+		}
+		if math.Abs(th.DriftBearing-90) > 10 || math.Abs(th.DriftSpeed-18) > 3 {
+			t.Errorf("expected drift 90° 18km/h got %v° %vkm/h", th.DriftBearing, th.DriftSpeed)
+		}
+	}
+}
+
+func TestThermalsShortCircling(t *testing.T) {
+	track := circlingTrack(100, 25*time.Second, true, 2, 0, MinThermalTime-10*time.Second)
+	thermals, err := track.Thermals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thermals) != 0 {
+		t.Errorf("expected no thermals got %v", len(thermals))
+	}
+}
+
+func TestThermalsFlight(t *testing.T) {
+	track, err := ParseLocation("../../testdata/phases/phases-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	thermals, err := track.Thermals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thermals) == 0 {
+		t.Fatalf("expected thermals in flight")
+	}
+	for _, th := range thermals {
+		if th.Duration() < MinThermalTime || th.Turns <= 0 || th.Radius <= 0 {
+			t.Errorf("invalid thermal %+v", th)
+		}
+	}
+	for _, format := range []string{"json", "yaml", "csv", "kml", "kmz"} {
+		if _, err := track.EncodeThermals(format); err != nil {
+			t.Errorf("failed to encode %v :: %v", format, err)
+		}
+	}
+	if _, err := track.EncodeThermals("unknown"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
@@ -22,6 +22,9 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/golang/geo/s1"
@@ -36,6 +39,9 @@ import (
 const MaxSpeed float64 = 500.0
 
 // Track holds all IGC flight data (header and GPS points).
+//
+// TakeoffTime, Launch, ReleasePoint, ReleaseHeight and LandingTime are set
+// when calculating the flight phases, see Track.Phases().
 type Track struct {
 	Header
 	ID            string
@@ -47,7 +53,14 @@ type Track struct {
 	Task          Task
 	DGPSStationID string
 	Signature     string
+	TakeoffTime   time.Time
+	Launch        LaunchType
+	ReleasePoint  Point
+	ReleaseHeight int64
+	LandingTime   time.Time
 	phases        []Phase
+	phaseOpts     PhaseOptions
+	altitudes     []int64
 }
 
 // NewTrack returns a new instance of Track, with fields initialized to zero.
@@ -193,6 +206,7 @@ var Manufacturers = map[string]Manufacturer{
 
 func (track *Track) Cleanup() (Track, error) {
 	clean := *track
+	clean.phases = nil
 
 	i := 1
 	for i < len(clean.Points) {
@@ -213,6 +227,7 @@ func (track *Track) Simplify(tolerance float64) (Track, error) {
 
 	simplified := *track
 	simplified.Points = points
+	simplified.phases = nil
 	return simplified, nil
 }
 
@@ -226,6 +241,16 @@ func (track *Track) Encode(format string) ([]byte, error) {
 		return yaml.Marshal(track)
 	case "csv":
 		return track.encodeCSV()
+	case "points-csv":
+		return track.encodePointsCSV(',')
+	case "points-tsv":
+		return track.encodePointsCSV('\t')
+	case "igc":
+		return track.encodeIGC()
+	case "geojson":
+		return track.encodeGeoJSON()
+	case "gpx":
+		return track.encodeGPX()
 	default:
 		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
 	}
@@ -255,11 +280,101 @@ func (track *Track) encodeCSV() ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
+// encodePointsCSV returns one row per point, separated by the given comma.
+//
+// Besides the recorded values and the I record extensions, each row includes
+// the ground speed (km/h), vario (m/s) and bearing (degrees) from the
+// previous point, the cumulative distance (km) and the type of the phase the
+// point belongs to. The vario uses the altitudes of the phases (see
+// Track.Altitudes()).
+func (track *Track) encodePointsCSV(comma rune) ([]byte, error) {
+	keys := []string{}
+	seen := make(map[string]bool)
+	for _, p := range track.Points {
+		for k := range p.IData {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	// the phases are not available for tracks with less than 2 points, which
+	// are all unknown
+	phaseTypes := make([]PhaseType, len(track.Points))
+	if len(track.Points) >= 2 {
+		phases, err := track.Phases()
+		if err != nil {
+			return []byte{}, err
+		}
+		for i, p := range phases {
+			end := len(track.Points)
+			if i < len(phases)-1 {
+				end = phases[i+1].StartIndex
+			}
+			for j := p.StartIndex; j < end; j++ {
+				phaseTypes[j] = p.Type
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	w.Comma = comma
+	header := []string{"Time", "Lat", "Lng", "PressureAltitude", "GNSSAltitude",
+		"FixValidity", "NumSatellites"}
+	header = append(header, keys...)
+	header = append(header, "Speed", "Vario", "Bearing", "Distance", "Phase")
+	if err := w.Write(header); err != nil {
+		return buf.Bytes(), err
+	}
+
+	distance := 0.0
+	for i, p := range track.Points {
+		var speed, vario, bearing float64
+		if i > 0 {
+			prev := track.Points[i-1]
+			distance += prev.Distance(p)
+			speed = prev.Speed(p)
+			if s := p.Time.Sub(prev.Time).Seconds(); s != 0 {
+				vario = float64(track.altitude(i)-track.altitude(i-1)) / s
+			}
+			bearing = math.Mod(prev.Bearing(p).Degrees()+360, 360)
+		}
+		validity := ""
+		if p.FixValidity != 0 {
+			validity = string(p.FixValidity)
+		}
+		record := []string{
+			p.Time.Format(time.RFC3339),
+			strconv.FormatFloat(p.Lat.Degrees(), 'f', 7, 64),
+			strconv.FormatFloat(p.Lng.Degrees(), 'f', 7, 64),
+			fmt.Sprintf("%d", p.PressureAltitude),
+			fmt.Sprintf("%d", p.GNSSAltitude),
+			validity,
+			fmt.Sprintf("%d", p.NumSatellites),
+		}
+		for _, k := range keys {
+			record = append(record, p.IData[k])
+		}
+		record = append(record,
+			fmt.Sprintf("%.2f", speed), fmt.Sprintf("%.2f", vario),
+			fmt.Sprintf("%.1f", bearing), fmt.Sprintf("%.3f", distance),
+			phaseTypes[i].String())
+		if err := w.Write(record); err != nil {
+			return buf.Bytes(), err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
 func encodeKML(track *Track, format string) ([]byte, error) {
 
 	metadata := fmt.Sprintf("%v : %v : %v", track.Date, track.Pilot, track.GliderType)
 
-	phasesKML, err := track.encodePhasesKML()
+	phases, err := track.Phases()
 	if err != nil {
 		return []byte{}, err
 	}
@@ -267,9 +382,13 @@ func encodeKML(track *Track, format string) ([]byte, error) {
 	k := kml.Document(
 		kml.Name(metadata),
 		kml.Description(""),
-		phasesKML,
+		track.encodePhasesKML(phases),
 	)
+	return writeKML(k, format)
+}
 
+// writeKML returns the given kml document, zipped if the format is kmz.
+func writeKML(k *kml.CompoundElement, format string) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	if err := k.WriteIndent(buf, "", "  "); err != nil {
 		return buf.Bytes(), err
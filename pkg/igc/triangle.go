@@ -0,0 +1,184 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"fmt"
+	"math"
+)
+
+// FAIMinLegRatio is the min length of each leg of an FAI triangle, as a ratio
+// of the total course length.
+const FAIMinLegRatio = 0.28
+
+// TriangleOptions holds the constraints for closed course optimization.
+//
+// When FAI is set, each leg must be at least FAIMinLegRatio of the course
+// length. Otherwise any (flat) triangle is accepted.
+//
+// MaxGap is the max distance in kms between the start and finish points, and
+// MaxGapPercent the same but as a percentage of the course length. A zero
+// value disables the corresponding limit, and if both are set the strictest
+// applies.
+type TriangleOptions struct {
+	FAI           bool
+	MaxGap        float64
+	MaxGapPercent float64
+}
+
+// NewTriangleOptimizer returns an Optimizer for FAI triangles, with a max
+// closing gap of 20% of the course length.
+func NewTriangleOptimizer() Optimizer {
+	return NewTriangleOptimizerWithOptions(TriangleOptions{FAI: true, MaxGapPercent: 20})
+}
+
+// NewTriangleOptimizerWithOptions returns an Optimizer for closed courses
+// (triangles) with the given constraints.
+//
+// The only supported number of turnpoints is 3, the triangle vertices. The
+// returned Task has the vertices as Turnpoints, and the Start and Finish
+// points with the smallest closing gap (see Task.ClosingGap()) for those
+// vertices. Candidates are evaluated with the given Score function, Triangle
+// being the one measuring the course length.
+//
+// The complexity is O(n³) for n points, so it is best to pass it a simplified
+// Track (see Track.Simplify()).
+func NewTriangleOptimizerWithOptions(opts TriangleOptions) Optimizer {
+	return &triangleOptimizer{opts: opts}
+}
+
+type triangleOptimizer struct {
+	opts TriangleOptions
+}
+
+// Triangle returns the length of the closed course through the Turnpoints.
+//
+// The Start and Finish points are not considered, see Task.ClosingGap().
+func Triangle(task Task) float64 {
+	d := 0.0
+	n := len(task.Turnpoints)
+	if n < 2 {
+		return d
+	}
+	for i := 0; i < n; i++ {
+		d += task.Turnpoints[i].Distance(task.Turnpoints[(i+1)%n])
+	}
+	return d
+}
+
+// ClosingGap returns the distance in kms between the Start and Finish points.
+func (task *Task) ClosingGap() float64 {
+	return task.Start.Distance(task.Finish)
+}
+
+func (o *triangleOptimizer) Optimize(track Track, nPoints int, score Score) (Task, error) {
+	if nPoints != 3 {
+		return Task{}, fmt.Errorf("%v turn points not supported by this optimizer", nPoints)
+	}
+	points := track.Points
+	n := len(points)
+	if n < 3 {
+		return Task{}, fmt.Errorf("track has %v points, min 3 required", n)
+	}
+
+	gap, start, finish := closingGaps(points)
+	d := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d[i*n+j] = points[i].Distance(points[j])
+		}
+	}
+
+	var optimal Task
+	optimalScore := math.Inf(-1)
+	found := false
+	task := Task{Turnpoints: make([]Point, 3)}
+	for i := 0; i < n-2; i++ {
+		for j := i + 1; j < n-1; j++ {
+			for k := j + 1; k < n; k++ {
+				a, b, c := d[i*n+j], d[j*n+k], d[i*n+k]
+				course := a + b + c
+				if !o.valid(a, b, c, course, gap[i*n+k]) {
+					continue
+				}
+				task.Start = points[start[i*n+k]]
+				task.Turnpoints[0], task.Turnpoints[1], task.Turnpoints[2] = points[i], points[j], points[k]
+				task.Finish = points[finish[i*n+k]]
+				if s := score(task); s > optimalScore {
+					optimalScore = s
+					optimal = task
+					optimal.Turnpoints = append([]Point{}, task.Turnpoints...)
+					found = true
+				}
+			}
+		}
+	}
+	if !found {
+		return Task{}, fmt.Errorf("no triangle found matching the constraints")
+	}
+	return optimal, nil
+}
+
+// valid checks the triangle with legs a, b, c against the optimizer options.
+func (o *triangleOptimizer) valid(a, b, c, course, gap float64) bool {
+	if course == 0 {
+		return false
+	}
+	if o.opts.FAI && math.Min(a, math.Min(b, c)) < FAIMinLegRatio*course {
+		return false
+	}
+	if o.opts.MaxGap > 0 && gap > o.opts.MaxGap {
+		return false
+	}
+	if o.opts.MaxGapPercent > 0 && gap > course*o.opts.MaxGapPercent/100 {
+		return false
+	}
+	return true
+}
+
+// closingGaps returns for each pair of points i <= k the smallest distance
+// between a start point s <= i and a finish point f >= k, along with s and f.
+//
+// Results are stored in row major order, so the values for (i, k) are at
+// index i*n+k.
+func closingGaps(points []Point) ([]float64, []int, []int) {
+	n := len(points)
+	gap := make([]float64, n*n)
+	start := make([]int, n*n)
+	finish := make([]int, n*n)
+
+	// row s holds the best finish f >= k for a start at exactly s
+	row := make([]float64, n)
+	rowFinish := make([]int, n)
+	for s := 0; s < n; s++ {
+		for k := n - 1; k >= s; k-- {
+			row[k], rowFinish[k] = points[s].Distance(points[k]), k
+			if k < n-1 && row[k+1] < row[k] {
+				row[k], rowFinish[k] = row[k+1], rowFinish[k+1]
+			}
+		}
+		// keep the best of all starts up to s
+		for k := s; k < n; k++ {
+			idx := s*n + k
+			gap[idx], start[idx], finish[idx] = row[k], s, rowFinish[k]
+			if s > 0 && gap[idx-n] <= gap[idx] {
+				gap[idx], start[idx], finish[idx] = gap[idx-n], start[idx-n], finish[idx-n]
+			}
+		}
+	}
+	return gap, start, finish
+}
@@ -0,0 +1,200 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+type triangleTest struct {
+	name     string
+	opts     TriangleOptions
+	expected float64
+}
+
+var triangleTests = []triangleTest{
+	{
+		name:     "optimize-short-flight-1",
+		opts:     TriangleOptions{FAI: true, MaxGapPercent: 20},
+		expected: 21.716625014455555,
+	},
+	{
+		name:     "optimize-short-flight-1",
+		opts:     TriangleOptions{MaxGapPercent: 20},
+		expected: 42.248215289790714,
+	},
+	{
+		name:     "optimize-long-flight-1",
+		opts:     TriangleOptions{FAI: true, MaxGap: 1},
+		expected: 427.6382127214272,
+	},
+}
+
+func TestTriangleOptimize(t *testing.T) {
+	for _, test := range triangleTests {
+		t.Run(fmt.Sprintf("%v/%+v", test.name, test.opts), func(t *testing.T) {
+			track, err := ParseLocation(filepath.Join("../../testdata/optimize", fmt.Sprintf("%v.igc", test.name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			track, err = track.Simplify(0.0001)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := NewTriangleOptimizerWithOptions(test.opts).Optimize(track, 3, Triangle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := Triangle(task)
+			if math.Abs(result-test.expected) > test.expected*errorMargin {
+				t.Errorf("expected %v got %v", test.expected, result)
+			}
+			gap := task.ClosingGap()
+			if test.opts.MaxGap > 0 && gap > test.opts.MaxGap {
+				t.Errorf("expected gap up to %v got %v", test.opts.MaxGap, gap)
+			}
+			if test.opts.MaxGapPercent > 0 && gap > result*test.opts.MaxGapPercent/100 {
+				t.Errorf("expected gap up to %v%% got %v", test.opts.MaxGapPercent, gap)
+			}
+			if test.opts.FAI {
+				for i := range task.Turnpoints {
+					leg := task.Turnpoints[i].Distance(task.Turnpoints[(i+1)%3])
+					if leg < FAIMinLegRatio*result {
+						t.Errorf("leg %v has %v, less than %v%% of %v", i, leg, FAIMinLegRatio*100, result)
+					}
+				}
+			}
+		})
+	}
+}
+
+// gapPenalty scores a triangle by its length minus the closing gap.
+func gapPenalty(task Task) float64 {
+	return Triangle(task) - task.ClosingGap()
+}
+
+// naiveTriangle tries every start, vertices and finish combination.
+func naiveTriangle(points []Point, opts TriangleOptions, score Score) float64 {
+	o := triangleOptimizer{opts: opts}
+	best := math.Inf(-1)
+	n := len(points)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				a, b, c := points[i].Distance(points[j]), points[j].Distance(points[k]), points[i].Distance(points[k])
+				for s := 0; s <= i; s++ {
+					for f := k; f < n; f++ {
+						if !o.valid(a, b, c, a+b+c, points[s].Distance(points[f])) {
+							continue
+						}
+						task := Task{Start: points[s], Turnpoints: []Point{points[i], points[j], points[k]}, Finish: points[f]}
+						best = math.Max(best, score(task))
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+func TestTriangleOptimizeNaive(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err = track.Simplify(0.0001)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scores := map[string]Score{"triangle": Triangle, "gap-penalty": gapPenalty}
+	options := []TriangleOptions{{FAI: true}, {MaxGap: 1}, {FAI: true, MaxGapPercent: 5}}
+	for name, score := range scores {
+		for _, opts := range options {
+			t.Run(fmt.Sprintf("%v/%+v", name, opts), func(t *testing.T) {
+				task, err := NewTriangleOptimizerWithOptions(opts).Optimize(track, 3, score)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := naiveTriangle(track.Points, opts, score)
+				if math.Abs(expected-score(task)) > 1e-9 {
+					t.Errorf("expected %v got %v", expected, score(task))
+				}
+			})
+		}
+	}
+}
+
+func TestTriangleOptimizeErrors(t *testing.T) {
+	line := Track{Points: []Point{
+		NewPointFromDMD("4453183N", "00512633E"),
+		NewPointFromDMD("4453183N", "00522633E"),
+		NewPointFromDMD("4453183N", "00532633E"),
+	}}
+	opt := NewTriangleOptimizer()
+	if _, err := opt.Optimize(line, 2, Triangle); err == nil {
+		t.Errorf("expected error for 2 turnpoints")
+	}
+	if _, err := opt.Optimize(Track{Points: line.Points[:2]}, 3, Triangle); err == nil {
+		t.Errorf("expected error for track with too few points")
+	}
+	if _, err := opt.Optimize(line, 3, Triangle); err == nil {
+		t.Errorf("expected error for track with no valid triangle")
+	}
+}
+
+func TestClosingGaps(t *testing.T) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-short-flight-1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := track.Points[:50]
+	n := len(points)
+	gap, start, finish := closingGaps(points)
+	for i := 0; i < n; i++ {
+		for k := i; k < n; k++ {
+			expected := math.Inf(1)
+			for s := 0; s <= i; s++ {
+				for f := k; f < n; f++ {
+					expected = math.Min(expected, points[s].Distance(points[f]))
+				}
+			}
+			idx := i*n + k
+			if gap[idx] != expected || start[idx] > i || finish[idx] < k {
+				t.Errorf("%v-%v :: expected %v got %v (%v-%v)", i, k, expected, gap[idx], start[idx], finish[idx])
+			}
+		}
+	}
+}
+
+func BenchmarkTriangleOptimize(b *testing.B) {
+	track, err := ParseLocation("../../testdata/optimize/optimize-long-flight-1.igc")
+	if err != nil {
+		b.Fatal(err)
+	}
+	track, err = track.Simplify(0.0001)
+	if err != nil {
+		b.Fatal(err)
+	}
+	opt := NewTriangleOptimizer()
+	for i := 0; i < b.N; i++ {
+		_, _ = opt.Optimize(track, 3, Triangle)
+	}
+}
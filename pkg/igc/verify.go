@@ -0,0 +1,110 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNoSignature is returned when verifying content without G records.
+	ErrNoSignature = errors.New("no security signature found")
+	// ErrInvalidSignature is returned when the G records do not match the content.
+	ErrInvalidSignature = errors.New("invalid security signature")
+	// ErrNoVerifier is returned when no Verifier is available for the manufacturer.
+	ErrNoVerifier = errors.New("no verifier available")
+)
+
+// Verifier checks the security signature (G record) of a flight recorder.
+//
+// This is the G record in the IGC specification, section A5. The algorithm
+// is specific to each manufacturer, with records holding all lines of the
+// file except the G records (surrounding whitespace and line terminators
+// removed, blank lines skipped)
+// and signature holding the content of each G record without the leading G.
+//
+// Verify returns nil if the signature is valid, ErrInvalidSignature if it
+// does not match the records.
+type Verifier interface {
+	Verify(records []string, signature []string) error
+}
+
+var (
+	verifiersMu sync.RWMutex
+	verifiers   = map[string]Verifier{
+		"XCS": xcsVerifier{},
+	}
+)
+
+// RegisterVerifier makes the Verifier available for the given manufacturer.
+//
+// The manufacturer must be one of the three letter codes in Manufacturers.
+// Registering a Verifier for a manufacturer replaces any previous one.
+func RegisterVerifier(manufacturer string, v Verifier) error {
+	if _, ok := Manufacturers[manufacturer]; !ok {
+		return fmt.Errorf("unknown manufacturer :: %v", manufacturer)
+	}
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	verifiers[manufacturer] = v
+	return nil
+}
+
+// Verify checks the security signature of the given content in IGC format.
+//
+// The Verifier is picked from the manufacturer in the A record. It returns
+// nil if the signature is valid, or one of ErrNoSignature, ErrNoVerifier or
+// ErrInvalidSignature (possibly wrapped) otherwise.
+func Verify(content string) error {
+	records, signature := signedContent(content)
+	if len(records) == 0 || records[0][0] != 'A' || len(records[0]) < 4 {
+		return fmt.Errorf("missing A record as first line")
+	}
+	if len(signature) == 0 {
+		return ErrNoSignature
+	}
+
+	manufacturer := records[0][1:4]
+	verifiersMu.RLock()
+	v, ok := verifiers[manufacturer]
+	verifiersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w :: %v", ErrNoVerifier, manufacturer)
+	}
+	return v.Verify(records, signature)
+}
+
+// signedContent splits content into the G records and everything else.
+func signedContent(content string) ([]string, []string) {
+	records := []string{}
+	signature := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == 'G' {
+			signature = append(signature, line[1:])
+		} else {
+			records = append(records, line)
+		}
+	}
+	return records, signature
+}
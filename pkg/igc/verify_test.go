@@ -0,0 +1,126 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	// testdata/verify file name format is testname.[1|0].igc
+	match, err := filepath.Glob("../../testdata/verify/verify-*.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range match {
+		t.Run(in, func(t *testing.T) {
+			parts := strings.Split(in, ".")
+			ok, _ := strconv.ParseBool(parts[len(parts)-2])
+			data, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Verify(string(data))
+			if ok && err != nil {
+				t.Errorf("expected valid signature got %v", err)
+			} else if !ok && err == nil {
+				t.Errorf("expected invalid signature")
+			}
+		})
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	tests := map[string]error{
+		"verify-xcs-tampered.0.igc":         ErrInvalidSignature,
+		"verify-xcs-no-signature.0.igc":     ErrNoSignature,
+		"verify-unknown-manufacturer.0.igc": ErrNoVerifier,
+	}
+	for f, expected := range tests {
+		data, err := ioutil.ReadFile(filepath.Join("../../testdata/verify", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Verify(string(data)); !errors.Is(err, expected) {
+			t.Errorf("%v :: expected %v got %v", f, expected, err)
+		}
+	}
+}
+
+func TestVerifyWhitespace(t *testing.T) {
+	data, err := ioutil.ReadFile("../../testdata/verify/verify-xcs-valid.1.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	content := strings.Join(lines, " \r\n\t") + "\r\n\r\n"
+	if err := Verify(content); err != nil {
+		t.Errorf("expected valid signature with extra whitespace got %v", err)
+	}
+}
+
+type acceptVerifier struct{}
+
+func (v acceptVerifier) Verify(records []string, signature []string) error {
+	return nil
+}
+
+func TestRegisterVerifier(t *testing.T) {
+	if err := RegisterVerifier("ZZZ", acceptVerifier{}); err == nil {
+		t.Errorf("expected error registering unknown manufacturer")
+	}
+	// restore the global verifiers, not to leak XYY into other tests
+	verifiersMu.RLock()
+	previous, ok := verifiers["XYY"]
+	verifiersMu.RUnlock()
+	defer func() {
+		verifiersMu.Lock()
+		defer verifiersMu.Unlock()
+		if ok {
+			verifiers["XYY"] = previous
+		} else {
+			delete(verifiers, "XYY")
+		}
+	}()
+	if err := RegisterVerifier("XYY", acceptVerifier{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify("AXYY001\nB1602455107126N00149300WA002880042919509\nGABC\n"); err != nil {
+		t.Errorf("expected registered verifier to be used got %v", err)
+	}
+}
+
+func TestMD5State(t *testing.T) {
+	tests := []string{"", "a", strings.Repeat("ezgliding", 7), strings.Repeat("x", 64)}
+	for _, test := range tests {
+		m := newMD5State(xcsKeys[2])
+		for i := 0; i < len(test); i++ {
+			m.write(test[i])
+		}
+		expected := md5.Sum([]byte(test))
+		if m.sum() != hex.EncodeToString(expected[:]) {
+			t.Errorf("%q :: expected %x got %v", test, expected, m.sum())
+		}
+	}
+}
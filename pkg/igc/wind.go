@@ -0,0 +1,313 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// WindProfileStep is the altitude band in meters of each layer in the
+	// wind profile.
+	WindProfileStep = 250
+	// MinWindSamples is the min number of ground velocity samples in a
+	// circling phase to estimate the wind.
+	MinWindSamples = 8
+)
+
+// WindEstimate is the wind estimated from a single circling phase.
+//
+// The ground velocity while circling at a constant airspeed describes a
+// circle centered at the wind vector, with the airspeed as radius. Speed and
+// Airspeed are in km/h, and Direction in degrees is where the wind blows
+// from.
+type WindEstimate struct {
+	Time       time.Time
+	Altitude   int64
+	StartIndex int
+	EndIndex   int
+	Speed      float64
+	Direction  float64
+	Airspeed   float64
+}
+
+// WindLayer is the average wind in an altitude band of the wind profile.
+//
+// Altitude is the bottom of the band, which is WindProfileStep high.
+type WindLayer struct {
+	Altitude  int64
+	Speed     float64
+	Direction float64
+	Samples   int
+}
+
+// Wind holds the wind estimates for each circling phase, and the smoothed
+// wind-vs-altitude profile for the whole flight.
+type Wind struct {
+	Estimates []WindEstimate
+	Profile   []WindLayer
+}
+
+// Wind returns the wind estimated from the circling phases of the Track.
+func (track *Track) Wind() (Wind, error) {
+	if _, err := track.Phases(); err != nil {
+		return Wind{}, err
+	}
+	estimates := track.windEstimates()
+	return Wind{Estimates: estimates, Profile: windProfile(estimates)}, nil
+}
+
+// At returns the wind speed and direction at the given altitude, linearly
+// interpolated between the profile layers. It returns false if the profile
+// is empty.
+func (w *Wind) At(altitude int64) (float64, float64, bool) {
+	if len(w.Profile) == 0 {
+		return 0, 0, false
+	}
+	// layer values are set at the middle of each band
+	mid := func(l WindLayer) float64 { return float64(l.Altitude) + WindProfileStep/2 }
+	alt := float64(altitude)
+	i := sort.Search(len(w.Profile), func(i int) bool { return mid(w.Profile[i]) >= alt })
+	if i == 0 {
+		return w.Profile[0].Speed, w.Profile[0].Direction, true
+	} else if i == len(w.Profile) {
+		l := w.Profile[i-1]
+		return l.Speed, l.Direction, true
+	}
+	a, b := w.Profile[i-1], w.Profile[i]
+	f := (alt - mid(a)) / (mid(b) - mid(a))
+	ax, ay := windVector(a.Speed, a.Direction)
+	bx, by := windVector(b.Speed, b.Direction)
+	speed, direction := windPolar(ax+f*(bx-ax), ay+f*(by-ay))
+	return speed, direction, true
+}
+
+// windEstimates returns the wind estimate for each circling phase with at
+// least one full turn.
+func (track *Track) windEstimates() []WindEstimate {
+	estimates := []WindEstimate{}
+	for _, p := range track.phases {
+		if p.Type != Circling || p.EndIndex <= p.StartIndex {
+			continue
+		}
+		if e, ok := windEstimate(track.Points[p.StartIndex : p.EndIndex+1]); ok {
+			e.StartIndex, e.EndIndex = p.StartIndex, p.EndIndex
+			e.Altitude = (p.StartAltitude + p.EndAltitude) / 2
+			estimates = append(estimates, e)
+		}
+	}
+	return estimates
+}
+
+// windEstimate fits a circle to the ground velocities of the given points,
+// the center being the wind and the radius the airspeed.
+func windEstimate(points []Point) (WindEstimate, bool) {
+	if len(points) < MinWindSamples+1 {
+		return WindEstimate{}, false
+	}
+	total := 0.0
+	for _, v := range headingChanges(points) {
+		total += math.Abs(v)
+	}
+	if total < 360 {
+		return WindEstimate{}, false
+	}
+
+	// least squares fit of x² + y² + Dx + Ey + F = 0
+	var a [3][4]float64
+	for i := 1; i < len(points); i++ {
+		x, y, ok := groundVelocity(points[i-1], points[i])
+		if !ok {
+			continue
+		}
+		row := [4]float64{x, y, 1, -(x*x + y*y)}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 4; c++ {
+				a[r][c] += row[r] * row[c]
+			}
+		}
+	}
+	d, ok := solve3(a)
+	if !ok {
+		return WindEstimate{}, false
+	}
+	cx, cy := -d[0]/2, -d[1]/2
+	r2 := cx*cx + cy*cy - d[2]
+	if r2 <= 0 {
+		return WindEstimate{}, false
+	}
+
+	e := WindEstimate{
+		Time:     points[len(points)/2].Time,
+		Airspeed: math.Sqrt(r2) * 3.6,
+	}
+	e.Speed, e.Direction = windPolar(cx*3.6, cy*3.6)
+	return e, true
+}
+
+// groundVelocity returns the east and north velocity in m/s between a and b.
+func groundVelocity(a, b Point) (float64, float64, bool) {
+	s := b.Time.Sub(a.Time).Seconds()
+	if s <= 0 {
+		return 0, 0, false
+	}
+	v := a.Distance(b) * 1000 / s
+	bearing := a.Bearing(b).Radians()
+	return v * math.Sin(bearing), v * math.Cos(bearing), true
+}
+
+// solve3 solves the 3x3 linear system in the augmented matrix a, using
+// gaussian elimination with partial pivoting.
+func solve3(a [3][4]float64) ([3]float64, bool) {
+	var x [3]float64
+	for c := 0; c < 3; c++ {
+		pivot := c
+		for r := c + 1; r < 3; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[pivot][c]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][c]) < 1e-12 {
+			return x, false
+		}
+		a[c], a[pivot] = a[pivot], a[c]
+		for r := c + 1; r < 3; r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < 4; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+		}
+	}
+	for r := 2; r >= 0; r-- {
+		x[r] = a[r][3]
+		for k := r + 1; k < 3; k++ {
+			x[r] -= a[r][k] * x[k]
+		}
+		x[r] /= a[r][r]
+	}
+	return x, true
+}
+
+// windProfile groups the estimates in altitude bands, averaging the wind
+// vectors weighted by the circling time, and smooths each layer with its
+// neighbours.
+func windProfile(estimates []WindEstimate) []WindLayer {
+	type band struct {
+		x, y, weight float64
+		samples      int
+	}
+	bands := make(map[int64]*band)
+	for _, e := range estimates {
+		alt := e.Altitude - mod(e.Altitude, WindProfileStep)
+		b, ok := bands[alt]
+		if !ok {
+			b = &band{}
+			bands[alt] = b
+		}
+		w := float64(e.EndIndex - e.StartIndex)
+		x, y := windVector(e.Speed, e.Direction)
+		b.x, b.y, b.weight = b.x+w*x, b.y+w*y, b.weight+w
+		b.samples++
+	}
+
+	alts := make([]int64, 0, len(bands))
+	for alt := range bands {
+		alts = append(alts, alt)
+	}
+	sort.Slice(alts, func(i, j int) bool { return alts[i] < alts[j] })
+
+	profile := make([]WindLayer, len(alts))
+	for i, alt := range alts {
+		b := bands[alt]
+		x, y, weight := b.x, b.y, b.weight
+		// adjacent bands count half
+		for _, n := range []int64{alt - WindProfileStep, alt + WindProfileStep} {
+			if nb, ok := bands[n]; ok {
+				x, y, weight = x+nb.x/2, y+nb.y/2, weight+nb.weight/2
+			}
+		}
+		profile[i] = WindLayer{Altitude: alt, Samples: b.samples}
+		if weight > 0 {
+			profile[i].Speed, profile[i].Direction = windPolar(x/weight, y/weight)
+		}
+	}
+	return profile
+}
+
+// setPhasesWind sets the wind of each circling phase from its estimate, and
+// the wind and airspeed of each cruising phase from the wind profile.
+func (track *Track) setPhasesWind() {
+	estimates := track.windEstimates()
+	wind := Wind{Estimates: estimates, Profile: windProfile(estimates)}
+	byStart := make(map[int]WindEstimate)
+	for _, e := range estimates {
+		byStart[e.StartIndex] = e
+	}
+
+	for i := range track.phases {
+		p := &track.phases[i]
+		// no wind for the ground phases
+		if p.EndIndex <= p.StartIndex || p.Type == Unknown {
+			continue
+		}
+		if p.Type == Circling {
+			if e, ok := byStart[p.StartIndex]; ok {
+				p.WindSpeed, p.WindDirection = e.Speed, e.Direction
+			}
+			continue
+		}
+		speed, direction, ok := wind.At((p.StartAltitude + p.EndAltitude) / 2)
+		if !ok {
+			continue
+		}
+		p.WindSpeed, p.WindDirection = speed, direction
+		wx, wy := windVector(speed/3.6, direction)
+		total, duration := 0.0, 0.0
+		for j := p.StartIndex + 1; j <= p.EndIndex; j++ {
+			x, y, ok := groundVelocity(track.Points[j-1], track.Points[j])
+			if !ok {
+				continue
+			}
+			s := track.Points[j].Time.Sub(track.Points[j-1].Time).Seconds()
+			total += math.Hypot(x-wx, y-wy) * s
+			duration += s
+		}
+		if duration > 0 {
+			p.AvgAirSpeed = total / duration * 3.6
+		}
+	}
+}
+
+// windVector returns the east and north components of the wind, given its
+// speed and the direction it blows from.
+func windVector(speed, direction float64) (float64, float64) {
+	rad := direction * math.Pi / 180
+	return -speed * math.Sin(rad), -speed * math.Cos(rad)
+}
+
+// windPolar returns the speed and direction the wind blows from, given its
+// east and north components.
+func windPolar(x, y float64) (float64, float64) {
+	direction := math.Mod(math.Atan2(-x, -y)*180/math.Pi+360, 360)
+	return math.Hypot(x, y), direction
+}
+
+// mod returns the euclidean modulo of a by b, always positive.
+func mod(a, b int64) int64 {
+	return ((a % b) + b) % b
+}
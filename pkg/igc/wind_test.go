@@ -0,0 +1,112 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package igc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWind(t *testing.T) {
+	// drift east at 5m/s is a west wind of 18km/h, airspeed is 2*pi*100/25
+	track := circlingTrack(100, 25*time.Second, true, 2, 5, 120*time.Second)
+	wind, err := track.Wind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wind.Estimates) != 1 || len(wind.Profile) != 1 {
+		t.Fatalf("expected 1 estimate and layer got %+v", wind)
+	}
+	e := wind.Estimates[0]
+	if math.Abs(e.Speed-18) > 1 || math.Abs(e.Direction-270) > 5 {
+		t.Errorf("expected 270° 18km/h got %v° %vkm/h", e.Direction, e.Speed)
+	}
+	if math.Abs(e.Airspeed-90.5) > 3 {
+		t.Errorf("expected airspeed 90.5km/h got %v", e.Airspeed)
+	}
+
+	phases, _ := track.Phases()
+	for _, p := range phases {
+		if p.EndIndex <= p.StartIndex {
+			continue
+		}
+		if math.Abs(p.WindSpeed-18) > 1 || math.Abs(p.WindDirection-270) > 5 {
+			t.Errorf("expected phase wind 270° 18km/h got %v° %vkm/h", p.WindDirection, p.WindSpeed)
+		}
+		// cruising north at 90.5km/h ground speed with a west wind
+		if p.Type == Cruising && math.Abs(p.AvgAirSpeed-math.Hypot(90.5, 18)) > 3 {
+			t.Errorf("expected airspeed %v got %v", math.Hypot(90.5, 18), p.AvgAirSpeed)
+		}
+	}
+}
+
+func TestWindNoCircling(t *testing.T) {
+	track := straightTrack(0, 0, 0.1)
+	wind, err := track.Wind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wind.Estimates) != 0 {
+		t.Errorf("expected no estimates got %v", wind.Estimates)
+	}
+	if _, _, ok := wind.At(1000); ok {
+		t.Errorf("expected no wind for empty profile")
+	}
+}
+
+func TestWindProfile(t *testing.T) {
+	estimates := []WindEstimate{
+		{Altitude: 1100, Speed: 10, Direction: 270, EndIndex: 10},
+		{Altitude: 1200, Speed: 20, Direction: 270, EndIndex: 10},
+		{Altitude: 2100, Speed: 30, Direction: 90, EndIndex: 10},
+	}
+	wind := Wind{Estimates: estimates, Profile: windProfile(estimates)}
+	if len(wind.Profile) != 2 {
+		t.Fatalf("expected 2 layers got %+v", wind.Profile)
+	}
+	if l := wind.Profile[0]; l.Altitude != 1000 || l.Samples != 2 || math.Abs(l.Speed-15) > 0.001 {
+		t.Errorf("expected 15km/h at 1000m got %+v", l)
+	}
+	tests := []struct {
+		altitude  int64
+		speed     float64
+		direction float64
+	}{
+		{0, 15, 270},
+		{1125, 15, 270},
+		{3000, 30, 90},
+		// half way between 1125 and 2125
+		{1625, 7.5, 90},
+	}
+	for _, test := range tests {
+		speed, direction, ok := wind.At(test.altitude)
+		if !ok || math.Abs(speed-test.speed) > 0.001 || math.Abs(direction-test.direction) > 0.001 {
+			t.Errorf("expected %v° %vkm/h at %vm got %v° %vkm/h",
+				test.direction, test.speed, test.altitude, direction, speed)
+		}
+	}
+}
+
+func TestWindPolar(t *testing.T) {
+	for _, direction := range []float64{0, 45, 90, 180, 270, 359} {
+		x, y := windVector(10, direction)
+		s, d := windPolar(x, y)
+		if math.Abs(s-10) > 1e-9 || math.Abs(d-direction) > 1e-9 {
+			t.Errorf("expected %v° 10 got %v° %v", direction, d, s)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package igc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// xcsKeys are the initial MD5 states used by the XCSoar G record.
+var xcsKeys = [4][4]uint32{
+	{0x1C80A301, 0x9EB30b89, 0x39CB2Afe, 0x0D0FEA76},
+	{0x48327203, 0x3948ebea, 0x9a9b9c9e, 0xb3bed89a},
+	{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476},
+	{0xc8e899e8, 0x9321c28a, 0x438eba12, 0x8cbe0aee},
+}
+
+// xcsLineSize is the number of signature characters in each G record.
+const xcsLineSize = 16
+
+// xcsVerifier verifies G records written by XCSoar and compatible loggers.
+//
+// The signature is the concatenation of four MD5 digests of the signed
+// content, each starting from a different initial state (xcsKeys). Only
+// printable characters are part of the signed content, and commas, L records
+// from other manufacturers and H records added after the flight (HO, HP) are
+// excluded.
+type xcsVerifier struct{}
+
+func (v xcsVerifier) Verify(records []string, signature []string) error {
+	if !strings.EqualFold(strings.Join(signature, ""), strings.Join(xcsSign(records), "")) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// xcsSign returns the G records for the given records.
+func xcsSign(records []string) []string {
+	digests := make([]md5State, len(xcsKeys))
+	for i, k := range xcsKeys {
+		digests[i] = newMD5State(k)
+	}
+	for _, r := range records {
+		if !xcsSigned(r) {
+			continue
+		}
+		for i := 0; i < len(r); i++ {
+			if !xcsValidChar(r[i]) {
+				continue
+			}
+			for j := range digests {
+				digests[j].write(r[i])
+			}
+		}
+	}
+
+	sum := ""
+	for i := range digests {
+		sum = sum + digests[i].sum()
+	}
+	lines := []string{}
+	for i := 0; i < len(sum); i += xcsLineSize {
+		lines = append(lines, sum[i:i+xcsLineSize])
+	}
+	return lines
+}
+
+func xcsSigned(record string) bool {
+	switch record[0] {
+	case 'L':
+		return strings.HasPrefix(record[1:], "XCS")
+	case 'G':
+		return false
+	case 'H':
+		return len(record) < 2 || (record[1] != 'O' && record[1] != 'P')
+	default:
+		return true
+	}
+}
+
+func xcsValidChar(c byte) bool {
+	return c >= 0x20 && c <= 0x7E && c != ',' && c != '$' && c != '*' &&
+		c != '!' && c != '\\' && c != '^' && c != '~'
+}
+
+// md5Shifts are the per round shift amounts of MD5 (RFC 1321).
+var md5Shifts = [64]int{
+	7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22,
+	5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20,
+	4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23,
+	6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21,
+}
+
+// md5Table holds floor(abs(sin(i+1)) * 2^32), as defined in RFC 1321.
+var md5Table = func() [64]uint32 {
+	var t [64]uint32
+	for i := range t {
+		t[i] = uint32(math.Floor(math.Abs(math.Sin(float64(i+1))) * (1 << 32)))
+	}
+	return t
+}()
+
+// md5State is an MD5 digest with a configurable initial state.
+//
+// The standard library does not allow setting the initial state, which is
+// required by some G record algorithms.
+type md5State struct {
+	h      [4]uint32
+	block  []byte
+	length uint64
+}
+
+func newMD5State(key [4]uint32) md5State {
+	return md5State{h: key, block: make([]byte, 0, 64)}
+}
+
+func (m *md5State) write(c byte) {
+	m.block = append(m.block, c)
+	m.length++
+	if len(m.block) == 64 {
+		m.process(m.block)
+		m.block = m.block[:0]
+	}
+}
+
+func (m *md5State) process(block []byte) {
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(block[i*4:])
+	}
+	a, b, c, d := m.h[0], m.h[1], m.h[2], m.h[3]
+	for i := 0; i < 64; i++ {
+		var f uint32
+		var g int
+		switch i / 16 {
+		case 0:
+			f = (b & c) | (^b & d)
+			g = i
+		case 1:
+			f = (d & b) | (^d & c)
+			g = (5*i + 1) % 16
+		case 2:
+			f = b ^ c ^ d
+			g = (3*i + 5) % 16
+		default:
+			f = c ^ (b | ^d)
+			g = (7 * i) % 16
+		}
+		f = f + a + md5Table[i] + x[g]
+		a, d, c = d, c, b
+		b = b + bits.RotateLeft32(f, md5Shifts[i])
+	}
+	m.h[0] += a
+	m.h[1] += b
+	m.h[2] += c
+	m.h[3] += d
+}
+
+// sum returns the hex encoded digest, without changing the state.
+func (m *md5State) sum() string {
+	s := *m
+	s.block = append([]byte{}, m.block...)
+	length := s.length
+	s.write(0x80)
+	for len(s.block) != 56 {
+		s.write(0)
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], length*8)
+	for _, c := range size {
+		s.write(c)
+	}
+
+	var digest [16]byte
+	for i, v := range s.h {
+		binary.LittleEndian.PutUint32(digest[i*4:], v)
+	}
+	return hex.EncodeToString(digest[:])
+}
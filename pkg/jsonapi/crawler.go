@@ -0,0 +1,225 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ezgliding/goigc/pkg/crawl"
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func init() {
+	crawl.Register("jsonapi", func(opts crawl.SourceOptions) (crawl.Source, error) {
+		if opts.RecordDir != "" {
+			return nil, fmt.Errorf("jsonapi does not support recording fixtures")
+		}
+		a, err := New(opts.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Limiter != nil {
+			a.client.Transport = &crawl.Transport{Limiter: opts.Limiter}
+		}
+		return a, nil
+	})
+}
+
+// DateFormat is the format of dates in the API.
+const DateFormat = "2006-01-02"
+
+// RequestTimeout is the max time for a single request.
+const RequestTimeout = time.Minute
+
+// API implements a crawler for a flight portal with a JSON API.
+type API struct {
+	baseURL *url.URL
+	client  *http.Client
+}
+
+// New returns an API crawler for the portal at the given base url.
+func New(baseURL string) (*API, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("jsonapi requires a base url")
+	}
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url '%v'", baseURL)
+	}
+	return &API{baseURL: u, client: &http.Client{Timeout: RequestTimeout}}, nil
+}
+
+// id is a flight or file id, sent by portals as a string or a number.
+type id string
+
+func (i *id) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*i = id(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid id %v", string(b))
+	}
+	*i = id(n.String())
+	return nil
+}
+
+// flightSummary is an entry in the flight list of a day.
+type flightSummary struct {
+	ID id `json:"id"`
+}
+
+// flightDetail holds the details of a single flight.
+type flightDetail struct {
+	ID            id      `json:"id"`
+	Date          string  `json:"date"`
+	Pilot         string  `json:"pilot"`
+	Club          string  `json:"club"`
+	Takeoff       string  `json:"takeoff"`
+	Region        string  `json:"region"`
+	Country       string  `json:"country"`
+	Distance      float64 `json:"distance"`
+	Points        float64 `json:"points"`
+	Speed         float64 `json:"speed"`
+	Aircraft      string  `json:"aircraft"`
+	Type          string  `json:"type"`
+	Comment       string  `json:"comment"`
+	CompetitionID string  `json:"competition_id"`
+	IGCFile       struct {
+		ID  id     `json:"id"`
+		URL string `json:"url"`
+	} `json:"igc_file"`
+}
+
+// Crawl returns the flights between start and end, listing each day and
+// fetching the details of each flight.
+func (a *API) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("invalid start end date pair")
+	}
+	var flights []igc.Flight
+	current := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, time.UTC)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 12, 0, 0, 0, time.UTC)
+	for ; !current.After(end); current = current.AddDate(0, 0, 1) {
+		var summaries []flightSummary
+		list := a.resolve("flights?date=" + current.Format(DateFormat))
+		if err := a.getJSON(list, &summaries); err != nil {
+			return flights, err
+		}
+		for _, s := range summaries {
+			var d flightDetail
+			detail := a.resolve("flights/" + url.PathEscape(string(s.ID)))
+			if err := a.getJSON(detail, &d); err != nil {
+				return flights, err
+			}
+			f, err := a.flight(detail, d)
+			if err != nil {
+				return flights, fmt.Errorf("%v :: %v", detail, err)
+			}
+			flights = append(flights, f)
+		}
+	}
+	log.WithFields(log.Fields{
+		"start":       start,
+		"end":         end,
+		"num_flights": len(flights),
+	}).Trace("Finishing crawling flights")
+	return flights, nil
+}
+
+// flight maps the flight details to an igc.Flight.
+func (a *API) flight(detailURL string, d flightDetail) (igc.Flight, error) {
+	f := igc.Flight{
+		URL: detailURL, ID: string(d.ID), Pilot: d.Pilot, Club: d.Club,
+		Takeoff: d.Takeoff, Region: d.Region, Country: d.Country,
+		Distance: d.Distance, Points: d.Points, Speed: d.Speed,
+		Glider: d.Aircraft, Type: d.Type, Comments: d.Comment,
+		CompetitionID: d.CompetitionID, TrackID: string(d.IGCFile.ID),
+	}
+	if d.Date != "" {
+		date, err := time.Parse(DateFormat, d.Date)
+		if err != nil {
+			return f, err
+		}
+		f.Date = date
+	}
+	if d.IGCFile.URL != "" {
+		f.TrackURL = a.resolve(d.IGCFile.URL)
+	}
+	if f.TrackID == "" && f.TrackURL != "" {
+		f.TrackID = f.ID
+	}
+	if f.TrackID != "" {
+		// the id names the track file in the crawl directory
+		if err := crawl.CheckTrackID(f.TrackID); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// Get returns the content at the given url, usually a flight track.
+//
+// Failed requests return a crawl.HTTPError, including any Retry-After
+// requested by the server.
+func (a *API) Get(u string) ([]byte, error) {
+	log.WithFields(log.Fields{"url": u}).Trace("GET request")
+	resp, err := a.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, crawl.NewHTTPError(u, resp.StatusCode, resp.Header)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (a *API) getJSON(u string, v interface{}) error {
+	b, err := a.Get(u)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%v :: %v", u, err)
+	}
+	return nil
+}
+
+// resolve returns the url relative to the base url, with a leading slash
+// being relative to the base url too.
+func (a *API) resolve(ref string) string {
+	r, err := url.Parse(ref)
+	if err != nil || r.IsAbs() {
+		return ref
+	}
+	r.Path = strings.TrimPrefix(r.Path, "/")
+	return a.baseURL.ResolveReference(r).String()
+}
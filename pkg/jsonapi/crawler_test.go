@@ -0,0 +1,168 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package jsonapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/crawl"
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+const trackFile = "../../testdata/parse/parse-0-basic-flight.1.igc"
+
+// fixtureServer serves the API from testdata/jsonapi under /api, with all
+// igc files being the same track.
+func fixtureServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/")
+		var file string
+		switch {
+		case path == "flights":
+			file = "../../testdata/jsonapi/flights-" + r.URL.Query().Get("date") + ".json"
+		case strings.HasPrefix(path, "flights/"):
+			file = "../../testdata/jsonapi/flight-" + strings.TrimPrefix(path, "flights/") + ".json"
+		case strings.HasPrefix(path, "files/"):
+			file = trackFile
+		case path == "busy":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+}
+
+func TestCrawl(t *testing.T) {
+	server := fixtureServer(t)
+	defer server.Close()
+
+	a, err := New(server.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	flights, err := a.Crawl(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flights) != 2 {
+		t.Fatalf("expected 2 flights got %v", len(flights))
+	}
+	f := flights[0]
+	expected := igc.Flight{
+		URL: server.URL + "/api/flights/101", ID: "101", Pilot: "Jane Doe",
+		Club: "Club de Vol a Voile", Date: start, Takeoff: "Saint-Auban",
+		Region: "Provence", Country: "FR", Distance: 512.3, Points: 601.2,
+		Glider: "LS8", Type: "FAI triangle", TrackURL: server.URL + "/api/files/987.igc",
+		TrackID: "987", CompetitionID: "AB", Speed: 98.5, Comments: "Nice day",
+	}
+	if f != expected {
+		t.Errorf("expected\n%+v\ngot\n%+v", expected, f)
+	}
+	if flights[1].ID != "102" || flights[1].TrackURL != server.URL+"/api/files/988.igc" {
+		t.Errorf("expected flight 102 with relative track url got %+v", flights[1])
+	}
+}
+
+func TestCrawlErrors(t *testing.T) {
+	server := fixtureServer(t)
+	defer server.Close()
+
+	a, err := New(server.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no fixture for this day
+	day := time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC)
+	_, err = a.Crawl(day, day)
+	if e, ok := err.(*crawl.HTTPError); !ok || e.StatusCode != http.StatusNotFound {
+		t.Errorf("expected http 404 error got %v", err)
+	}
+	// a track id escaping the crawl directory
+	day = time.Date(2020, 5, 4, 0, 0, 0, 0, time.UTC)
+	if _, err = a.Crawl(day, day); err == nil || !strings.Contains(err.Error(), "invalid track id") {
+		t.Errorf("expected invalid track id error got %v", err)
+	}
+	_, err = a.Get(server.URL + "/api/busy")
+	if e, ok := err.(*crawl.HTTPError); !ok || e.RetryAfter != 30*time.Second {
+		t.Errorf("expected http 429 error with retry after got %v", err)
+	}
+	if _, err := New(""); err == nil {
+		t.Errorf("expected error without base url")
+	}
+	if _, err := New("localhost"); err == nil {
+		t.Errorf("expected error for base url without scheme")
+	}
+}
+
+func TestCrawlPipeline(t *testing.T) {
+	server := fixtureServer(t)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "goigc-jsonapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source, err := crawl.NewSource("jsonapi", crawl.SourceOptions{BaseURL: server.URL + "/api/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	opts := crawl.DefaultOptions()
+	opts.Rate = 0
+	if err := crawl.Run(source, start, start.AddDate(0, 0, 1), dir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ioutil.ReadFile(trackFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"987", "988"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "2020", "flights", id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("expected track %v to match the served igc file", id)
+		}
+		if _, err := igc.Parse(string(data)); err != nil {
+			t.Errorf("expected valid igc track %v :: %v", id, err)
+		}
+	}
+	m, err := crawl.LoadManifest(filepath.Join(dir, "2020", crawl.ManifestFile), 2020)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := m.Get("987"); !ok || e.Status != crawl.Done {
+		t.Errorf("expected track 987 done in manifest got %+v", e)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+/*
+Package jsonapi provides a crawler for flight portals with a JSON API, in the
+style of OLC or WeGlide.
+
+The portal is expected to provide the endpoints below under a base url, with
+flight and file ids being either strings or numbers.
+
+  GET /flights?date=2006-01-02
+    [{"id": 123, "date": "2006-01-02"}, ...]
+
+  GET /flights/{id}
+    {"id": 123, "date": "2006-01-02", "pilot": "...", "club": "...",
+     "takeoff": "...", "region": "...", "country": "FR",
+     "distance": 512.3, "points": 601.2, "speed": 98.5,
+     "aircraft": "LS8", "type": "FAI triangle", "comment": "...",
+     "competition_id": "AB",
+     "igc_file": {"id": 987, "url": "/files/987.igc"}}
+
+  GET {igc_file.url}
+    the flight track in IGC format
+
+Relative urls, even with a leading slash, are resolved against the base
+url. The crawler is registered as the jsonapi source in the crawl package.
+
+*/
+package jsonapi
@@ -20,25 +20,31 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ezgliding/goigc/pkg/crawl"
 	"github.com/ezgliding/goigc/pkg/igc"
 	"github.com/gocolly/colly"
 	log "github.com/sirupsen/logrus"
 )
 
-// DailyUrlPattern is the main page to list netcoupe flights.
-const DailyUrlPattern = "https://%v.netcoupe.net/Results/DailyResults.aspx"
+// BaseUrlPattern is the netcoupe site for a given archive, like archive2019
+// or www for the current year.
+const BaseUrlPattern = "https://%v.netcoupe.net"
 
-// FlightBaseUrl is the base path to fetch flight details from a flight ID.
-const FlightBaseUrlPattern = "https://%v.netcoupe.net/Results/FlightDetail.aspx?FlightID="
+// DailyPath is the main page to list netcoupe flights.
+const DailyPath = "/Results/DailyResults.aspx"
 
-// TrackBaseUrlPattern is the base path to download the flight track from a track ID.
-const TrackBaseUrlPattern = "https://%v.netcoupe.net/Download/DownloadIGC.aspx?FileID="
+// FlightPath is the path to fetch flight details from a flight ID.
+const FlightPath = "/Results/FlightDetail.aspx?FlightID="
+
+// TrackPath is the path to download the flight track from a track ID.
+const TrackPath = "/Download/DownloadIGC.aspx?FileID="
 
 // This is a constant map.
 var httpHeaders = map[string][]string{
@@ -46,12 +52,17 @@ var httpHeaders = map[string][]string{
 	"Cache-Control":             []string{"max-age=0"},
 	"Upgrade-Insecure-Requests": []string{"1"},
 	"DNT":                       []string{"1"},
-	"Origin":                    []string{"https://archive2019.netcoupe.net"},
 	"User-Agent":                []string{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Ubuntu Chromium/66.0.3359.181 Chrome/66.0.3359.181 Safari/537.36"},
 	"Accept":                    []string{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8"},
 	"Accept-Language":           []string{"en-US,en;q=0.9,de;q=0.8,fr;q=0.7,pt;q=0.6,es;q=0.5,it;q=0.4,ny;q=0.3"},
-	"Connection":                []string{"keep-alive"},
-	"Referer":                   []string{"https://archive2019.netcoupe.net/Results/DailyResults.aspx"}}
+	"Connection":                []string{"keep-alive"}}
+
+func init() {
+	crawl.Register("netcoupe", func(opts crawl.SourceOptions) (crawl.Source, error) {
+		return NewNetcoupeWithOptions(Options{Year: opts.Year, BaseURL: opts.BaseURL,
+			RecordDir: opts.RecordDir, Limiter: opts.Limiter})
+	})
+}
 
 // Netcoupe implements a crawler for http://netcoupe.net.
 type Netcoupe struct {
@@ -60,19 +71,53 @@ type Netcoupe struct {
 	baseUrl   string
 }
 
+// Options holds the settings of a Netcoupe crawler.
+//
+// BaseURL replaces the site of the Year archive, like a local fixture
+// server in tests. With RecordDir set all responses are saved there as
+// fixtures for a FixtureHandler. Limiter, if set, is waited on before each
+// request.
+type Options struct {
+	Year      int
+	BaseURL   string
+	RecordDir string
+	Limiter   *crawl.TokenBucket
+}
+
 func NewNetcoupeYear(year int) Netcoupe {
 	n := Netcoupe{}
 	n.year = year
-	n.baseUrl = "www"
+	n.baseUrl = fmt.Sprintf(BaseUrlPattern, "www")
 	n.collector = colly.NewCollector()
 	n.collector.AllowURLRevisit = true
 	n.collector.UserAgent = httpHeaders["User-Agent"][0]
 	if year != 0 {
-		n.baseUrl = fmt.Sprintf("archive%v", year)
+		n.baseUrl = fmt.Sprintf(BaseUrlPattern, fmt.Sprintf("archive%v", year))
 	}
 	return n
 }
 
+// NewNetcoupeWithOptions returns a Netcoupe crawler with the given options.
+func NewNetcoupeWithOptions(opts Options) (Netcoupe, error) {
+	n := NewNetcoupeYear(opts.Year)
+	if opts.BaseURL != "" {
+		n.baseUrl = strings.TrimSuffix(opts.BaseURL, "/")
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.RecordDir != "" {
+		r, err := NewRecorder(opts.RecordDir, transport)
+		if err != nil {
+			return n, err
+		}
+		transport = r
+	}
+	if opts.Limiter != nil {
+		transport = &crawl.Transport{Limiter: opts.Limiter, Base: transport}
+	}
+	n.collector.WithTransport(transport)
+	return n, nil
+}
+
 func NewNetcoupe() Netcoupe {
 	return NewNetcoupeYear(0)
 }
@@ -88,8 +133,20 @@ func NewNetcoupe() Netcoupe {
 // Responsable de la NetCoupe de l’association avec l'accord du pilote.
 // """
 // Which means that it's only worth to crawl for new flights back to 2 weeks max.
+//
+// It returns the flights found along with the first error, a crawl.HTTPError
+// for failed requests (like http 420 when crawling too fast).
 func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 	var flights []igc.Flight
+	var crawlErr error
+	onError := func(r *colly.Response, err error) {
+		log.WithFields(log.Fields{
+			"response": r,
+			"error":    err}).Error("Failed to visit url")
+		if crawlErr == nil {
+			crawlErr = httpError(r, err)
+		}
+	}
 
 	// Do not allow start > end
 	if end.Before(start) {
@@ -104,15 +161,7 @@ func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 			"url":     r.URL.String(),
 			"headers": r.Headers}).Trace("Visiting flight list")
 	})
-	c.OnError(func(r *colly.Response, err error) {
-		log.WithFields(log.Fields{
-			"response": r,
-			"error":    err}).Error("Failed to visit url")
-		// Poor handling of http 420
-		if strings.Contains(err.Error(), "ENHANCE_YOUR_CALM") {
-			time.Sleep(10 * time.Second)
-		}
-	})
+	c.OnError(onError)
 
 	d := n.newCollector()
 	d.OnRequest(func(r *colly.Request) {
@@ -120,13 +169,14 @@ func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 			"url":     r.URL.String(),
 			"headers": r.Headers}).Trace("Visiting flight details")
 	})
+	d.OnError(onError)
 
 	c.OnHTML("table tr td:nth-child(4) a[href]", func(e *colly.HTMLElement) {
 		id := r.FindStringSubmatch(e.Attr("href"))
 		log.WithFields(log.Fields{
 			"flight_id": id[1]}).Trace("Scheduling visit to flight details")
 		if len(id) == 2 {
-			// TODO(rochaporto): handle error
+			// http errors are handled in OnError
 			_ = d.Visit(fmt.Sprintf("%v%v", n.flightBaseUrl(), id[1]))
 		}
 
@@ -166,8 +216,11 @@ func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 
 	current := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, time.UTC)
 	end = time.Date(end.Year(), end.Month(), end.Day(), 12, 0, 0, 0, time.UTC)
-	for ; end.After(current.AddDate(0, 0, -1)); current = current.AddDate(0, 0, 1) {
-		data := n.sessionHeaders(c)
+	for ; end.After(current.AddDate(0, 0, -1)) && crawlErr == nil; current = current.AddDate(0, 0, 1) {
+		data, err := n.sessionHeaders(c)
+		if err != nil {
+			return flights, err
+		}
 		data["ddlDisplayRange"] = "0"
 		data["ddlDisplayDate"] = current.Format("02/01/2006")
 		data["rbgDisplayMode"] = "rbDisplayByDate"
@@ -182,10 +235,18 @@ func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 				data["__VIEWSTATEGENERATOR"] = e.Attr("value")
 			}
 		})
-		n.post(tmp, n.dailyUrl(), data)
+		tmp.OnError(onError)
+		if err := n.post(tmp, n.dailyUrl(), data); err != nil && crawlErr == nil {
+			crawlErr = err
+		}
+		if crawlErr != nil {
+			break
+		}
 		// Set header for single page results (by default it pages)
 		data["__EVENTTARGET"] = "dgDailyResults$ctl01$ctl01"
-		n.post(c, n.dailyUrl(), data)
+		if err := n.post(c, n.dailyUrl(), data); err != nil && crawlErr == nil {
+			crawlErr = err
+		}
 	}
 
 	log.WithFields(log.Fields{
@@ -194,11 +255,16 @@ func (n Netcoupe) Crawl(start time.Time, end time.Time) ([]igc.Flight, error) {
 		"flights":     flights,
 		"num_flights": len(flights),
 	}).Trace("Finishing crawling flights")
-	return flights, nil
+	return flights, crawlErr
 }
 
+// Get returns the content at the given url, usually a flight track.
+//
+// Failed requests return a crawl.HTTPError, including any Retry-After
+// requested by the server.
 func (n Netcoupe) Get(url string) ([]byte, error) {
 	var result []byte
+	var getErr error
 
 	t := n.newCollector()
 	t.OnRequest(func(r *colly.Request) {
@@ -209,16 +275,34 @@ func (n Netcoupe) Get(url string) ([]byte, error) {
 	t.OnResponse(func(r *colly.Response) {
 		result = r.Body
 	})
+	t.OnError(func(r *colly.Response, err error) {
+		getErr = httpError(r, err)
+	})
 	err := t.Visit(url)
-
+	if getErr != nil {
+		return nil, getErr
+	}
 	return result, err
 }
 
+// httpError returns a crawl.HTTPError for responses with an http error
+// status, or the given error otherwise.
+func httpError(r *colly.Response, err error) error {
+	if r == nil || r.StatusCode < 400 {
+		return err
+	}
+	var header http.Header
+	if r.Headers != nil {
+		header = *r.Headers
+	}
+	return crawl.NewHTTPError(r.Request.URL.String(), r.StatusCode, header)
+}
+
 func (n Netcoupe) newCollector() *colly.Collector {
 	return n.collector.Clone()
 }
 
-func (n Netcoupe) sessionHeaders(c *colly.Collector) map[string]string {
+func (n Netcoupe) sessionHeaders(c *colly.Collector) (map[string]string, error) {
 	headers := map[string]string{
 		"__EVENTARGUMENT": "",
 		"__LASTFOCUS":     "",
@@ -242,34 +326,50 @@ func (n Netcoupe) sessionHeaders(c *colly.Collector) map[string]string {
 			headers["__VIEWSTATEGENERATOR"] = e.Attr("value")
 		}
 	})
-	// TODO(rochaporto): handle error
-	_ = t.Request("GET", n.dailyUrl(), nil, nil, httpHeaders)
+	var sessionErr error
+	t.OnError(func(r *colly.Response, err error) {
+		sessionErr = httpError(r, err)
+	})
+	if err := t.Request("GET", n.dailyUrl(), nil, nil, n.headers()); err != nil && sessionErr == nil {
+		sessionErr = err
+	}
 
-	return headers
+	return headers, sessionErr
 }
 
-func (n Netcoupe) post(c *colly.Collector, url string, data map[string]string) {
+func (n Netcoupe) post(c *colly.Collector, url string, data map[string]string) error {
 	cookies := c.Cookies(url)
-	// TODO(rochaporto): handle error
-	_ = c.SetCookies(url, cookies)
-	dur, _ := time.ParseDuration("1m")
-	c.SetRequestTimeout(dur)
+	if err := c.SetCookies(url, cookies); err != nil {
+		return err
+	}
+	c.SetRequestTimeout(time.Minute)
 	log.WithFields(log.Fields{
 		"url": url}).Trace("POST request")
-	// TODO(rochaporto): handle error
-	_ = c.Request("POST", url, createFormReader(data), nil, httpHeaders)
+	return c.Request("POST", url, createFormReader(data), nil, n.headers())
+}
+
+// headers returns the http headers for requests to the daily results page.
+func (n Netcoupe) headers() http.Header {
+	headers := http.Header{
+		"Origin":  []string{n.baseUrl},
+		"Referer": []string{n.dailyUrl()},
+	}
+	for k, v := range httpHeaders {
+		headers[k] = v
+	}
+	return headers
 }
 
 func (n Netcoupe) dailyUrl() string {
-	return fmt.Sprintf(DailyUrlPattern, n.baseUrl)
+	return n.baseUrl + DailyPath
 }
 
 func (n Netcoupe) flightBaseUrl() string {
-	return fmt.Sprintf(FlightBaseUrlPattern, n.baseUrl)
+	return n.baseUrl + FlightPath
 }
 
 func (n Netcoupe) TrackBaseUrl() string {
-	return fmt.Sprintf(TrackBaseUrlPattern, n.baseUrl)
+	return n.baseUrl + TrackPath
 }
 
 func parseFloat(s string) float64 {
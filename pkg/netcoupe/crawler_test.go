@@ -17,75 +17,112 @@
 package netcoupe
 
 import (
-	"encoding/json"
-	"fmt"
 	"io/ioutil"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/ezgliding/goigc/pkg/crawl"
 	"github.com/ezgliding/goigc/pkg/igc"
 	log "github.com/sirupsen/logrus"
 )
 
+// fixtureDir holds synthetic pages written by hand after the layout of the
+// netcoupe site, replayed by FixtureHandler. Replace them with a real capture
+// when the site layout changes, recorded with:
+//   goigc crawl 2018-12-24 2018-12-25 /tmp/crawl --record DIR
+const fixtureDir = "../../testdata/netcoupe"
+
 func init() {
 	log.SetLevel(log.TraceLevel)
 }
 
+func newTestNetcoupe(t *testing.T, opts Options) (Netcoupe, *httptest.Server) {
+	server := httptest.NewServer(FixtureHandler(fixtureDir))
+	opts.BaseURL = server.URL
+	n, err := NewNetcoupeWithOptions(opts)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return n, server
+}
+
 func TestNetcoupeCrawler(t *testing.T) {
 	start := time.Date(2018, time.December, 24, 12, 0, 0, 0, time.UTC)
 	end := time.Date(2018, time.December, 25, 12, 0, 0, 0, time.UTC)
 
-	var n Netcoupe = NewNetcoupeYear(2018)
+	n, server := newTestNetcoupe(t, Options{Year: 2018})
+	defer server.Close()
 	flights, err := n.Crawl(start, end)
 	if err != nil {
-		t.Errorf("%v", err)
+		t.Fatalf("%v", err)
 	}
 
-	if len(flights) <= 0 {
-		t.Errorf("no flights returned")
+	date := time.Date(2018, time.December, 24, 0, 0, 0, 0, time.UTC)
+	expected := []igc.Flight{
+		{
+			URL: server.URL + FlightPath + "201801", ID: "201801", Pilot: "Jean DUPONT",
+			Club: "Aéro-Club du Vexin", Date: date, Takeoff: "Pontoise",
+			Region: "Ile-de-France", Country: "France", Distance: 312.45, Points: 402.12,
+			Glider: "Discus 2b", Type: "Triangle FAI", TrackURL: server.URL + TrackPath + "301801",
+			TrackID: "301801", Speed: 85.3, Comments: "Belle journée d'hiver",
+		},
+		{
+			URL: server.URL + FlightPath + "201802", ID: "201802", Pilot: "Marie MARTIN",
+			Club: "CVV Auvergne", Date: date, Takeoff: "Issoire",
+			Region: "Auvergne", Country: "France", Distance: 155.1, Points: 188.3,
+			Glider: "LS 4", Type: "Aller-retour", TrackURL: server.URL + TrackPath + "301802",
+			TrackID: "301802", CompetitionURL: "Coupe de Noël", Speed: 72.65,
+			Comments: "Onde sous le vent",
+		},
+	}
+	if len(flights) != len(expected) {
+		t.Fatalf("expected %v flights got %v", len(expected), len(flights))
+	}
+	for i, f := range flights {
+		// parsed with 32 bit precision
+		f.Distance, f.Points, f.Speed = round(f.Distance), round(f.Points), round(f.Speed)
+		if !reflect.DeepEqual(f, expected[i]) {
+			t.Errorf("expected\n%+v\ngot\n%+v", expected[i], f)
+		}
 	}
-
-	jsonFlights, _ := json.MarshalIndent(flights, "", "   ")
-	fmt.Printf("%v\n", string(jsonFlights))
 }
 
 func TestNetcoupeCrawlerDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcoupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
 
-	year := 2019
-	start := time.Date(year, 12, 31, 12, 0, 0, 0, time.UTC)
-	end := time.Date(year, 12, 31, 12, 0, 0, 0, time.UTC)
+	n, server := newTestNetcoupe(t, Options{Year: 2018})
+	defer server.Close()
+	start := time.Date(2018, time.December, 24, 0, 0, 0, 0, time.UTC)
+	opts := crawl.DefaultOptions()
+	opts.Rate = 0
+	if err := crawl.Run(n, start, start.AddDate(0, 0, 1), dir, opts); err != nil {
+		t.Fatal(err)
+	}
 
-	var n Netcoupe = NewNetcoupeYear(year)
-	current := start
-	for ; end.After(current.AddDate(0, 0, -1)); current = current.AddDate(0, 0, 1) {
-		var flights []igc.Flight
-		dbFile := fmt.Sprintf("db/%v/%v.json", year, current.Format("02-01-2006"))
-		if _, err := os.Stat(dbFile); os.IsNotExist(err) {
-			flights, err = n.Crawl(current, current)
-			if err != nil {
-				t.Errorf("%v", err)
-			}
-			jsonFlights, _ := json.MarshalIndent(flights, "", "   ")
-			// TODO(rochaporto): handle error
-			_ = ioutil.WriteFile(dbFile, jsonFlights, 0644)
-		} else {
-			b, _ := ioutil.ReadFile(dbFile)
-			err = json.Unmarshal(b, &flights)
-			if err != nil {
-				fmt.Printf("error parsing %v %v\n", dbFile, err)
-				continue
-			}
+	for _, id := range []string{"301801", "301802"} {
+		expected, err := ioutil.ReadFile(filepath.Join(fixtureDir, "track-"+id+".igc"))
+		if err != nil {
+			t.Fatal(err)
 		}
-
-		for _, f := range flights {
-			flightFile := fmt.Sprintf("db/%v/flights/%v", year, f.TrackID)
-			if _, err := os.Stat(flightFile); os.IsNotExist(err) {
-				url := fmt.Sprintf("%v%v", n.TrackBaseUrl(), f.TrackID)
-				data, _ := n.Get(url)
-				// TODO(rochaporto): handle error
-				_ = ioutil.WriteFile(flightFile, data, 0644)
-			}
+		result, err := ioutil.ReadFile(filepath.Join(dir, "2018", "flights", id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != string(expected) {
+			t.Errorf("track %v differs from the fixture", id)
 		}
 	}
 }
+
+func round(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
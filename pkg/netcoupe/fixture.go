@@ -0,0 +1,245 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package netcoupe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixtures are named after the request they answer, as follows:
+//
+//   daily-YYYY-MM-DD-session.html GET of the daily results page
+//   daily-YYYY-MM-DD-select.html  postback selecting the date
+//   daily-YYYY-MM-DD.html         postback showing all results of the date
+//   flight-ID.html                flight details
+//   track-ID.igc                  flight track
+//
+// Each day is crawled in a new session, opened with a GET of the daily
+// results page which has no date. It is named after the date selected with
+// its __VIEWSTATE, so that the sessions of different days are all kept.
+//
+// Requests not matching any of the above are not recorded.
+
+var viewStateRegexp = regexp.MustCompile(`name="__VIEWSTATE"[^>]*value="([^"]*)"`)
+
+// sessionFixture is the name of the daily results page GET until the date
+// selected in its session is known.
+const sessionFixture = "daily-session.html"
+
+// Recorder is an http.RoundTripper saving the body of all successful
+// responses in a directory, as fixtures to be replayed by FixtureHandler.
+//
+// The session pages are kept in memory, keyed by their __VIEWSTATE, until
+// the postback selecting the date.
+type Recorder struct {
+	dir       string
+	transport http.RoundTripper
+	sessions  map[string][]byte
+	mu        sync.Mutex
+}
+
+// NewRecorder returns a Recorder saving fixtures in dir, creating it if
+// needed, and sending the requests with the given transport.
+func NewRecorder(dir string, transport http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, transport: transport, sessions: make(map[string][]byte)}, nil
+}
+
+// RoundTrip sends the request and saves the response body, decompressed,
+// under its fixture name.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	form := url.Values{}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		form, _ = url.ParseQuery(string(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	name := fixtureName(req.Method, req.URL, form)
+	if name == "" || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body, err = ioutil.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+	if name == sessionFixture {
+		r.mu.Lock()
+		r.sessions[viewState(body)] = body
+		r.mu.Unlock()
+		return resp, nil
+	}
+	if form.Get("__EVENTTARGET") == "ddlDisplayDate" {
+		r.mu.Lock()
+		session, ok := r.sessions[form.Get("__VIEWSTATE")]
+		delete(r.sessions, form.Get("__VIEWSTATE"))
+		r.mu.Unlock()
+		if ok {
+			path := filepath.Join(r.dir, strings.TrimSuffix(name, "-select.html")+"-session.html")
+			if err := writeFixture(path, session); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, writeFixture(filepath.Join(r.dir, name), body)
+}
+
+// viewState returns the __VIEWSTATE in the given page, empty if none.
+func viewState(page []byte) string {
+	if state := viewStateRegexp.FindSubmatch(page); state != nil {
+		return string(state[1])
+	}
+	return ""
+}
+
+// writeFixture writes the file atomically, as concurrent requests may save
+// the same fixture.
+func writeFixture(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FixtureHandler returns an http.Handler replaying the fixtures in dir,
+// like those saved by a Recorder.
+//
+// The GET of the daily results page is answered with the first session page.
+// The ASP.NET postback flow is checked as well: the __VIEWSTATE posted to
+// select a date must be the one in a session page, and the one posted to
+// show its results the one in the date selection page. Other values fail
+// with http 400, and missing fixtures with http 404.
+func FixtureHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := fixtureName(r.Method, r.URL, r.PostForm)
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		sessions, err := filepath.Glob(filepath.Join(dir, "daily-*-session.html"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if name == sessionFixture {
+			if len(sessions) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			name = filepath.Base(sessions[0])
+		}
+		if r.Method == "POST" {
+			previous := []string{strings.TrimSuffix(name, ".html") + "-select.html"}
+			if r.PostForm.Get("__EVENTTARGET") == "ddlDisplayDate" {
+				previous = sessions
+			}
+			valid := false
+			for _, p := range previous {
+				b, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(p)))
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+				if state := viewState(b); state != "" && state == r.PostForm.Get("__VIEWSTATE") {
+					valid = true
+				}
+			}
+			if !valid {
+				http.Error(w, "invalid viewstate", http.StatusBadRequest)
+				return
+			}
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+}
+
+// fixtureName returns the name of the fixture for the given request, or
+// empty if it should not be recorded.
+func fixtureName(method string, u *url.URL, form url.Values) string {
+	path := func(p string) bool {
+		return strings.HasSuffix(u.Path, strings.SplitN(p, "?", 2)[0])
+	}
+	switch {
+	case path(DailyPath) && method == "GET":
+		return sessionFixture
+	case path(DailyPath) && method == "POST":
+		date, err := time.Parse("02/01/2006", form.Get("ddlDisplayDate"))
+		if err != nil {
+			return ""
+		}
+		if form.Get("__EVENTTARGET") == "ddlDisplayDate" {
+			return "daily-" + date.Format("2006-01-02") + "-select.html"
+		}
+		return "daily-" + date.Format("2006-01-02") + ".html"
+	case path(FlightPath) && u.Query().Get("FlightID") != "":
+		return "flight-" + filepath.Base(u.Query().Get("FlightID")) + ".html"
+	case path(TrackPath) && u.Query().Get("FileID") != "":
+		return "track-" + filepath.Base(u.Query().Get("FileID")) + ".igc"
+	}
+	return ""
+}
@@ -0,0 +1,279 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package netcoupe
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+var fixtureNameTests = []struct {
+	t        string
+	method   string
+	url      string
+	form     url.Values
+	expected string
+}{
+	{
+		"daily", "GET", "https://archive2018.netcoupe.net/Results/DailyResults.aspx", nil,
+		"daily-session.html",
+	},
+	{
+		"daily-select", "POST", "https://archive2018.netcoupe.net/Results/DailyResults.aspx",
+		url.Values{"__EVENTTARGET": {"ddlDisplayDate"}, "ddlDisplayDate": {"24/12/2018"}},
+		"daily-2018-12-24-select.html",
+	},
+	{
+		"daily-results", "POST", "https://archive2018.netcoupe.net/Results/DailyResults.aspx",
+		url.Values{"__EVENTTARGET": {"dgDailyResults$ctl01$ctl01"}, "ddlDisplayDate": {"24/12/2018"}},
+		"daily-2018-12-24.html",
+	},
+	{
+		"daily-invalid-date", "POST", "https://archive2018.netcoupe.net/Results/DailyResults.aspx",
+		url.Values{"ddlDisplayDate": {"2018-12-24"}}, "",
+	},
+	{
+		"flight", "GET", "http://127.0.0.1/base/Results/FlightDetail.aspx?FlightID=201801", nil,
+		"flight-201801.html",
+	},
+	{
+		"track", "GET", "https://archive2018.netcoupe.net/Download/DownloadIGC.aspx?FileID=301801", nil,
+		"track-301801.igc",
+	},
+	{
+		"track-no-id", "GET", "https://archive2018.netcoupe.net/Download/DownloadIGC.aspx", nil, "",
+	},
+	{
+		"unknown", "GET", "https://archive2018.netcoupe.net/Default.aspx", nil, "",
+	},
+}
+
+func TestFixtureName(t *testing.T) {
+	for _, test := range fixtureNameTests {
+		t.Run(test.t, func(t *testing.T) {
+			u, err := url.Parse(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := fixtureName(test.method, u, test.form)
+			if result != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestFixtureHandler(t *testing.T) {
+	state := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(fixtureDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(viewStateRegexp.FindSubmatch(b)[1])
+	}
+	tests := []struct {
+		t        string
+		target   string
+		state    string
+		expected int
+	}{
+		{"select", "ddlDisplayDate", state("daily-2018-12-24-session.html"), http.StatusOK},
+		{"select-invalid-state", "ddlDisplayDate", state("daily-2018-12-24-select.html"), http.StatusBadRequest},
+		{"results", "dgDailyResults$ctl01$ctl01", state("daily-2018-12-24-select.html"), http.StatusOK},
+		{"results-invalid-state", "dgDailyResults$ctl01$ctl01", state("daily-2018-12-24-session.html"), http.StatusBadRequest},
+		{"results-no-state", "dgDailyResults$ctl01$ctl01", "", http.StatusBadRequest},
+	}
+	handler := FixtureHandler(fixtureDir)
+	for _, test := range tests {
+		t.Run(test.t, func(t *testing.T) {
+			form := url.Values{"__EVENTTARGET": {test.target}, "ddlDisplayDate": {"24/12/2018"}}
+			if test.state != "" {
+				form.Set("__VIEWSTATE", test.state)
+			}
+			req := httptest.NewRequest("POST", DailyPath, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != test.expected {
+				t.Errorf("expected status %v got %v", test.expected, w.Code)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", FlightPath+"999999", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %v for a missing fixture got %v", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcoupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// compress responses like the netcoupe site does
+	handler := FixtureHandler(fixtureDir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		w.Header().Set("Content-Type", http.DetectContentType(rec.Body.Bytes()))
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(rec.Code)
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write(rec.Body.Bytes())
+		gz.Close()
+	}))
+	defer server.Close()
+
+	n, err := NewNetcoupeWithOptions(Options{Year: 2018, BaseURL: server.URL + "/", RecordDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2018, time.December, 24, 12, 0, 0, 0, time.UTC)
+	flights, err := n.Crawl(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range flights {
+		if _, err := n.Get(f.TrackURL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != len(files) {
+		t.Errorf("expected %v recorded fixtures got %v", len(files), len(recorded))
+	}
+	for _, f := range files {
+		expected, err := ioutil.ReadFile(filepath.Join(fixtureDir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		if string(result) != string(expected) {
+			t.Errorf("recorded %v differs from the fixture", f.Name())
+		}
+	}
+}
+
+func TestRecorderSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcoupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// like the netcoupe site, each session page has a new viewstate which
+	// must be posted to select the date
+	original := viewState(mustReadFile(t, filepath.Join(fixtureDir, "daily-2018-12-24-session.html")))
+	var mu sync.Mutex
+	issued := make(map[string]bool)
+	handler := FixtureHandler(fixtureDir)
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch name := fixtureName(r.Method, r.URL, r.PostForm); {
+		case name == sessionFixture:
+			state := fmt.Sprintf("session-%d", len(issued)+1)
+			issued[state] = true
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			_, _ = w.Write([]byte(strings.Replace(rec.Body.String(), original, state, 1)))
+			return
+		case r.PostForm.Get("__EVENTTARGET") == "ddlDisplayDate":
+			if !issued[r.PostForm.Get("__VIEWSTATE")] {
+				http.Error(w, "invalid viewstate", http.StatusBadRequest)
+				return
+			}
+			r.PostForm.Set("__VIEWSTATE", original)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer live.Close()
+
+	start := time.Date(2018, time.December, 24, 12, 0, 0, 0, time.UTC)
+	run := func(url string, record string) []igc.Flight {
+		n, err := NewNetcoupeWithOptions(Options{Year: 2018, BaseURL: url, RecordDir: record})
+		if err != nil {
+			t.Fatal(err)
+		}
+		flights, err := n.Crawl(start, start.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flights
+	}
+	recorded := run(live.URL, dir)
+
+	// one session page per day
+	for i, day := range []string{"2018-12-24", "2018-12-25"} {
+		b := mustReadFile(t, filepath.Join(dir, "daily-"+day+"-session.html"))
+		if state := viewState(b); state != fmt.Sprintf("session-%d", i+1) {
+			t.Errorf("expected session %v for %v got %v", i+1, day, state)
+		}
+	}
+
+	replay := httptest.NewServer(FixtureHandler(dir))
+	defer replay.Close()
+	replayed := run(replay.URL, "")
+	if len(replayed) != len(recorded) || len(replayed) != 2 {
+		t.Fatalf("expected %v flights replayed got %v", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		if replayed[i].ID != recorded[i].ID || replayed[i].Date != recorded[i].Date {
+			t.Errorf("expected flight %+v got %+v", recorded[i], replayed[i])
+		}
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
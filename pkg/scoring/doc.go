@@ -0,0 +1,30 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+Package scoring provides rule sets to score flights in online competitions.
+
+Available rules include the OnLine Contest (OLC) classic, FAI and plus
+rankings, and the Netcoupe. Each of them can be combined with a glider
+handicap (index).
+
+Rules can be used directly to find the best scoring Task in a Track, or via
+ScoreFunc as an igc.Score with the optimizers scoring whole tasks, like the
+brute force and triangle ones. The DP optimizer scores each leg on its own,
+and refuses them.
+
+*/
+package scoring
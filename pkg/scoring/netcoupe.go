@@ -0,0 +1,104 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package scoring
+
+import (
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// Netcoupe scores flights by distance, with a coefficient depending on the
+// type of course.
+//
+// Free distance goes through up to MaxTurnpoints, while triangles must close
+// within MaxGap kms. Triangles where every leg has at least
+// igc.FAIMinLegRatio of the total get FAITriangleFactor, other (flat)
+// triangles get FlatTriangleFactor.
+type Netcoupe struct {
+	MaxTurnpoints      int
+	MaxGap             float64
+	DistanceFactor     float64
+	FlatTriangleFactor float64
+	FAITriangleFactor  float64
+}
+
+// NewNetcoupe returns the Netcoupe rules with the default coefficients.
+func NewNetcoupe() *Netcoupe {
+	return &Netcoupe{
+		MaxTurnpoints:      3,
+		MaxGap:             3,
+		DistanceFactor:     1.0,
+		FlatTriangleFactor: 1.2,
+		FAITriangleFactor:  1.4,
+	}
+}
+
+// Name returns netcoupe.
+func (n *Netcoupe) Name() string {
+	return "netcoupe"
+}
+
+// Score returns the points for the task, as a triangle if it has three
+// turnpoints and closes within MaxGap or as free distance otherwise.
+func (n *Netcoupe) Score(task igc.Task) Result {
+	r := Result{Rules: n.Name(), Task: task}
+	if len(task.Turnpoints) == 3 && task.ClosingGap() <= n.MaxGap {
+		r.Legs = triangle(task)
+		r.Type, r.Factor = "flat triangle", n.FlatTriangleFactor
+		if isFAI(r.Legs) {
+			r.Type, r.Factor = "fai triangle", n.FAITriangleFactor
+		}
+		return total(r)
+	}
+	if len(task.Turnpoints) > n.MaxTurnpoints {
+		r.Type = "invalid"
+		return r
+	}
+	r.Legs = legs(path(task), []float64{1})
+	r.Type, r.Factor = "free distance", n.DistanceFactor
+	return total(r)
+}
+
+// Optimize returns the best Result in the track, out of free distance and
+// triangles.
+func (n *Netcoupe) Optimize(track igc.Track) (Result, error) {
+	simple, err := simplify(track)
+	if err != nil {
+		return Result{}, err
+	}
+	var best Result
+	candidate := func(task igc.Task) {
+		if r := n.Score(task); r.Points > best.Points || best.Rules == "" {
+			best = r
+		}
+	}
+
+	opt := igc.NewDPOptimizer()
+	for tp := 1; tp <= n.MaxTurnpoints && tp <= igc.MaxDPTurnpoints; tp++ {
+		task, err := opt.Optimize(simple, tp, igc.Distance)
+		if err != nil {
+			if tp == 1 {
+				return Result{}, err
+			}
+			break
+		}
+		candidate(task)
+	}
+	tri := igc.NewTriangleOptimizerWithOptions(igc.TriangleOptions{MaxGap: n.MaxGap})
+	if task, err := tri.Optimize(simple, 3, ScoreFunc(n)); err == nil {
+		candidate(task)
+	}
+	return best, nil
+}
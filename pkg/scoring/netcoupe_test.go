@@ -0,0 +1,66 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package scoring
+
+import (
+	"testing"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func TestNetcoupeScore(t *testing.T) {
+	open := faiTask
+	open.Finish = igc.NewPointFromLatLng(46, 7)
+	tooMany := igc.Task{Start: faiTask.Start, Finish: open.Finish,
+		Turnpoints: append(append([]igc.Point{}, faiTask.Turnpoints...), open.Finish)}
+
+	tests := []struct {
+		name   string
+		task   igc.Task
+		kind   string
+		factor float64
+	}{
+		{name: "fai", task: faiTask, kind: "fai triangle", factor: 1.4},
+		{name: "flat", task: flatTask, kind: "flat triangle", factor: 1.2},
+		{name: "open", task: open, kind: "free distance", factor: 1.0},
+		{name: "too-many-turnpoints", task: tooMany, kind: "invalid", factor: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNetcoupe().Score(test.task)
+			if r.Type != test.kind {
+				t.Errorf("expected %v got %v", test.kind, r.Type)
+			}
+			distance := test.task.Distance()
+			if test.kind != "free distance" {
+				distance = igc.Triangle(test.task)
+			}
+			if !near(distance*test.factor, r.Points) {
+				t.Errorf("expected %v got %v", distance*test.factor, r.Points)
+			}
+		})
+	}
+}
+
+func TestNetcoupeOptimize(t *testing.T) {
+	r, err := NewNetcoupe().Optimize(parse(t, "optimize-short-flight-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Type != "flat triangle" || !near(50.697858347748856, r.Points) {
+		t.Errorf("expected flat triangle with %v got %v with %v", 50.697858347748856, r.Type, r.Points)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package scoring
+
+import (
+	"fmt"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// OLCMaxGapPercent is the max closing gap of an OLC triangle, as a percentage
+// of the triangle length.
+const OLCMaxGapPercent = 20
+
+// OLCPlusFAIFactor is the factor applied to the FAI triangle points when
+// adding them to the classic points in the OLC plus ranking.
+const OLCPlusFAIFactor = 0.3
+
+// OLCClassic scores free distance with a start, up to 5 turnpoints and a
+// finish.
+//
+// Each leg gets the corresponding factor in Factors, which by default
+// devalues the last two legs (80% and 60%).
+type OLCClassic struct {
+	Factors []float64
+}
+
+// NewOLCClassic returns the OLC classic rules with the default leg factors.
+func NewOLCClassic() *OLCClassic {
+	return &OLCClassic{Factors: []float64{1, 1, 1, 1, 0.8, 0.6}}
+}
+
+// Name returns olc-classic.
+func (o *OLCClassic) Name() string {
+	return "olc-classic"
+}
+
+// Score returns the classic points for the task, zero if it has more than 5
+// turnpoints.
+func (o *OLCClassic) Score(task igc.Task) Result {
+	r := Result{Rules: o.Name(), Type: "free distance", Task: task, Factor: 1}
+	if len(task.Turnpoints) > len(o.Factors)-1 {
+		r.Type = "invalid"
+		return r
+	}
+	r.Legs = legs(path(task), o.Factors)
+	return total(r)
+}
+
+// Optimize returns the best classic Result in the track.
+//
+// Candidates for each number of turnpoints are found with the DP optimizer on
+// distance, and then scored with the leg factors. The result is exact for
+// the distance, but might miss a better scoring task with the same points in
+// a different order.
+func (o *OLCClassic) Optimize(track igc.Track) (Result, error) {
+	simple, err := simplify(track)
+	if err != nil {
+		return Result{}, err
+	}
+	opt := igc.NewDPOptimizer()
+	var best Result
+	for tp := 1; tp <= len(o.Factors)-1 && tp <= igc.MaxDPTurnpoints; tp++ {
+		task, err := opt.Optimize(simple, tp, igc.Distance)
+		if err != nil {
+			if tp == 1 {
+				return Result{}, err
+			}
+			break
+		}
+		if r := o.Score(task); r.Points > best.Points || best.Rules == "" {
+			best = r
+		}
+	}
+	return best, nil
+}
+
+// OLCFAI scores FAI triangles, with the closing gap deducted from the length.
+type OLCFAI struct {
+	MaxGapPercent float64
+}
+
+// NewOLCFAI returns the OLC FAI triangle rules.
+func NewOLCFAI() *OLCFAI {
+	return &OLCFAI{MaxGapPercent: OLCMaxGapPercent}
+}
+
+// Name returns olc-fai.
+func (o *OLCFAI) Name() string {
+	return "olc-fai"
+}
+
+// Score returns the triangle points for the task, zero if the turnpoints are
+// not an FAI triangle or the closing gap is too big.
+func (o *OLCFAI) Score(task igc.Task) Result {
+	r := Result{Rules: o.Name(), Type: "fai triangle", Task: task, Factor: 1}
+	if len(task.Turnpoints) != 3 {
+		r.Type = "invalid"
+		return r
+	}
+	r.Legs = triangle(task)
+	r.Penalty = task.ClosingGap()
+	r = total(r)
+	if !isFAI(r.Legs) || r.Penalty > (r.Distance+r.Penalty)*o.MaxGapPercent/100 {
+		r.Type = "invalid"
+		r.Points = 0
+	}
+	return r
+}
+
+// Optimize returns the best FAI triangle Result in the track.
+func (o *OLCFAI) Optimize(track igc.Track) (Result, error) {
+	simple, err := simplify(track)
+	if err != nil {
+		return Result{}, err
+	}
+	opt := igc.NewTriangleOptimizerWithOptions(
+		igc.TriangleOptions{FAI: true, MaxGapPercent: o.MaxGapPercent})
+	task, err := opt.Optimize(simple, 3, ScoreFunc(o))
+	if err != nil {
+		return Result{}, err
+	}
+	return o.Score(task), nil
+}
+
+// OLCPlus adds the classic points and a share of the FAI triangle points.
+type OLCPlus struct {
+	Classic   *OLCClassic
+	FAI       *OLCFAI
+	FAIFactor float64
+}
+
+// NewOLCPlus returns the OLC plus rules.
+func NewOLCPlus() *OLCPlus {
+	return &OLCPlus{Classic: NewOLCClassic(), FAI: NewOLCFAI(), FAIFactor: OLCPlusFAIFactor}
+}
+
+// Name returns olc-plus.
+func (o *OLCPlus) Name() string {
+	return "olc-plus"
+}
+
+// Score returns the classic points for the task.
+//
+// The plus score combines two different tasks, so it is only available
+// from Optimize.
+func (o *OLCPlus) Score(task igc.Task) Result {
+	r := o.Classic.Score(task)
+	r.Rules = o.Name()
+	return r
+}
+
+// Optimize returns the sum of the best classic and FAI triangle Results in
+// the track, each of them available in Parts.
+//
+// Flights with no FAI triangle get only the classic points.
+func (o *OLCPlus) Optimize(track igc.Track) (Result, error) {
+	classic, err := o.Classic.Optimize(track)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to optimize classic :: %v", err)
+	}
+	r := Result{Rules: o.Name(), Type: "plus", Task: classic.Task, Factor: 1,
+		Distance: classic.Distance, Points: classic.Points, Parts: []Result{classic}}
+	fai, err := o.FAI.Optimize(track)
+	if err != nil {
+		return r, nil
+	}
+	fai.Factor = o.FAIFactor
+	fai.Points *= o.FAIFactor
+	r.Points += fai.Points
+	r.Parts = append(r.Parts, fai)
+	return r, nil
+}
@@ -0,0 +1,121 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package scoring
+
+import (
+	"testing"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func TestOLCClassicScore(t *testing.T) {
+	p := []igc.Point{}
+	for i := 0; i < 7; i++ {
+		p = append(p, igc.NewPointFromLatLng(45, 5+float64(i)*0.1))
+	}
+	task := igc.Task{Start: p[0], Turnpoints: p[1:6], Finish: p[6]}
+	r := NewOLCClassic().Score(task)
+	if len(r.Legs) != 6 {
+		t.Fatalf("expected 6 legs got %v", len(r.Legs))
+	}
+	expected := 0.0
+	for i, f := range []float64{1, 1, 1, 1, 0.8, 0.6} {
+		if r.Legs[i].Factor != f {
+			t.Errorf("leg %v :: expected factor %v got %v", i, f, r.Legs[i].Factor)
+		}
+		expected += r.Legs[i].Distance * f
+	}
+	if !near(expected, r.Points) || !near(task.Distance(), r.Distance) {
+		t.Errorf("expected %v/%v got %v/%v", expected, task.Distance(), r.Points, r.Distance)
+	}
+
+	task.Turnpoints = append(task.Turnpoints, p[0])
+	if r := NewOLCClassic().Score(task); r.Points != 0 || r.Type != "invalid" {
+		t.Errorf("expected invalid task with 6 turnpoints got %v", r.Points)
+	}
+}
+
+func TestOLCFAIScore(t *testing.T) {
+	r := NewOLCFAI().Score(faiTask)
+	if r.Type != "fai triangle" || len(r.Legs) != 3 {
+		t.Fatalf("expected fai triangle got %v with %v legs", r.Type, len(r.Legs))
+	}
+	expected := igc.Triangle(faiTask) - faiTask.ClosingGap()
+	if !near(expected, r.Points) || r.Penalty != faiTask.ClosingGap() {
+		t.Errorf("expected %v got %v", expected, r.Points)
+	}
+
+	if r := NewOLCFAI().Score(flatTask); r.Points != 0 {
+		t.Errorf("expected no points for flat triangle got %v", r.Points)
+	}
+	open := faiTask
+	open.Finish = igc.NewPointFromLatLng(46, 7)
+	if r := NewOLCFAI().Score(open); r.Points != 0 {
+		t.Errorf("expected no points for open triangle got %v", r.Points)
+	}
+}
+
+var olcTests = []struct {
+	name     string
+	rules    Rules
+	points   float64
+	distance float64
+}{
+	{name: "optimize-short-flight-1", rules: NewOLCClassic(), points: 46.842393699299215, distance: 47.846859270673946},
+	{name: "optimize-short-flight-1", rules: NewOLCFAI(), points: 21.58372640834416, distance: 21.58372640834416},
+	{name: "optimize-short-flight-1", rules: NewOLCPlus(), points: 53.31751162180246, distance: 47.846859270673946},
+	{name: "optimize-long-flight-1", rules: NewOLCClassic(), points: 541.9953422154922, distance: 543.4034032554478},
+	{name: "optimize-long-flight-1", rules: NewOLCFAI(), points: 427.30033565008546, distance: 427.30033565008546},
+}
+
+func TestOLCOptimize(t *testing.T) {
+	for _, test := range olcTests {
+		t.Run(test.name+"/"+test.rules.Name(), func(t *testing.T) {
+			r, err := test.rules.Optimize(parse(t, test.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(test.points, r.Points) || !near(test.distance, r.Distance) {
+				t.Errorf("expected %v/%v got %v/%v", test.points, test.distance, r.Points, r.Distance)
+			}
+		})
+	}
+}
+
+func TestOLCPlusOptimize(t *testing.T) {
+	track := parse(t, "optimize-short-flight-1")
+	r, err := NewOLCPlus().Optimize(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Parts) != 2 {
+		t.Fatalf("expected classic and fai parts got %v", len(r.Parts))
+	}
+	expected := r.Parts[0].Points + r.Parts[1].Points
+	if !near(expected, r.Points) || r.Parts[1].Factor != OLCPlusFAIFactor {
+		t.Errorf("expected %v got %v", expected, r.Points)
+	}
+
+	// too short for an fai triangle
+	track.Points = track.Points[:3]
+	r, err = NewOLCPlus().Optimize(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Parts) != 1 || r.Points != r.Parts[0].Points {
+		t.Errorf("expected only classic points got %v", r.Points)
+	}
+}
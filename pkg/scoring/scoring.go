@@ -0,0 +1,231 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTolerance is the tolerance used to simplify a Track before looking
+// for the best scoring Task.
+const DefaultTolerance = 0.0001
+
+// Leg holds the score of a single leg of a Task.
+//
+// Distance is in kms, and Points is the Distance with the leg Factor applied.
+type Leg struct {
+	Start    igc.Point
+	End      igc.Point
+	Distance float64
+	Factor   float64
+	Points   float64
+}
+
+// Result holds the score of a Task, with a breakdown per leg.
+//
+// Distance is the sum of all leg distances minus the Penalty (like the
+// closing gap of a triangle), in kms. Points is the final score after all
+// factors and the handicap are applied. Parts holds the results combined in
+// this one, when the rules sum multiple tasks.
+type Result struct {
+	Rules    string
+	Type     string
+	Task     igc.Task
+	Legs     []Leg
+	Penalty  float64
+	Distance float64
+	Factor   float64
+	Handicap float64
+	Points   float64
+	Parts    []Result
+}
+
+// Encode returns the Result in the given format, one of json or yaml.
+func (r Result) Encode(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(r, "", "  ")
+	case "yaml":
+		return yaml.Marshal(r)
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+// Rules define how a flight is scored in a given competition.
+//
+// Score returns the Result for the given Task, with zero points if the Task
+// is not valid under these rules. Optimize looks for the best scoring Task
+// in the given Track.
+type Rules interface {
+	Name() string
+	Score(task igc.Task) Result
+	Optimize(track igc.Track) (Result, error)
+}
+
+// ScoreFunc returns an igc.Score with the points of the Task for the given
+// Rules, to be passed to an igc.Optimizer scoring whole tasks (like the brute
+// force and triangle optimizers).
+//
+// Rules apply factors and shape checks to the whole Task, so the Score is not
+// additive (see igc.Additive) and the DP optimizer refuses it.
+func ScoreFunc(r Rules) igc.Score {
+	return func(task igc.Task) float64 {
+		return r.Score(task).Points
+	}
+}
+
+var rules = map[string]func() Rules{
+	"olc-classic": func() Rules { return NewOLCClassic() },
+	"olc-fai":     func() Rules { return NewOLCFAI() },
+	"olc-plus":    func() Rules { return NewOLCPlus() },
+	"netcoupe":    func() Rules { return NewNetcoupe() },
+}
+
+// Get returns the Rules with the given name.
+func Get(name string) (Rules, error) {
+	r, ok := rules[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rules '%v', available :: %v", name, Names())
+	}
+	return r(), nil
+}
+
+// Names returns the names of all available Rules, sorted.
+func Names() []string {
+	names := make([]string, 0, len(rules))
+	for n := range rules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewHandicap returns Rules applying the given glider index to r.
+//
+// All points are multiplied by 100/index, so that gliders with an index
+// below 100 get more points than their distance.
+func NewHandicap(r Rules, index float64) Rules {
+	return &handicap{rules: r, index: index}
+}
+
+type handicap struct {
+	rules Rules
+	index float64
+}
+
+func (h *handicap) Name() string {
+	return h.rules.Name()
+}
+
+func (h *handicap) Score(task igc.Task) Result {
+	return h.apply(h.rules.Score(task))
+}
+
+func (h *handicap) Optimize(track igc.Track) (Result, error) {
+	r, err := h.rules.Optimize(track)
+	if err != nil {
+		return r, err
+	}
+	return h.apply(r), nil
+}
+
+func (h *handicap) apply(r Result) Result {
+	if h.index <= 0 {
+		return r
+	}
+	f := 100 / h.index
+	r.Handicap = h.index
+	r.Points *= f
+	r.Legs = append([]Leg{}, r.Legs...)
+	for i := range r.Legs {
+		r.Legs[i].Points *= f
+	}
+	r.Parts = append([]Result{}, r.Parts...)
+	for i := range r.Parts {
+		r.Parts[i] = h.apply(r.Parts[i])
+	}
+	return r
+}
+
+// legs returns the legs between consecutive points, with the given factors.
+//
+// Legs past the number of factors get the last factor.
+func legs(points []igc.Point, factors []float64) []Leg {
+	result := make([]Leg, 0, len(points))
+	for i := 0; i < len(points)-1; i++ {
+		f := factors[len(factors)-1]
+		if i < len(factors) {
+			f = factors[i]
+		}
+		d := points[i].Distance(points[i+1])
+		result = append(result, Leg{Start: points[i], End: points[i+1],
+			Distance: d, Factor: f, Points: d * f})
+	}
+	return result
+}
+
+// path returns the points in the task, from Start to Finish.
+func path(task igc.Task) []igc.Point {
+	p := []igc.Point{task.Start}
+	p = append(p, task.Turnpoints...)
+	return append(p, task.Finish)
+}
+
+// triangle returns the legs of the closed course through the task turnpoints.
+func triangle(task igc.Task) []Leg {
+	p := append([]igc.Point{}, task.Turnpoints...)
+	return legs(append(p, task.Turnpoints[0]), []float64{1})
+}
+
+// isFAI returns true if all legs are at least igc.FAIMinLegRatio of the total.
+func isFAI(l []Leg) bool {
+	total := 0.0
+	for _, v := range l {
+		total += v.Distance
+	}
+	for _, v := range l {
+		if v.Distance < igc.FAIMinLegRatio*total {
+			return false
+		}
+	}
+	return total > 0
+}
+
+// total fills in the Result Distance and Points from the legs and factor.
+func total(r Result) Result {
+	d, p := 0.0, 0.0
+	for _, l := range r.Legs {
+		d += l.Distance
+		p += l.Points
+	}
+	r.Distance = d - r.Penalty
+	r.Points = (p - r.Penalty) * r.Factor
+	if r.Points < 0 {
+		r.Points = 0
+	}
+	return r
+}
+
+func simplify(track igc.Track) (igc.Track, error) {
+	return track.Simplify(DefaultTolerance)
+}
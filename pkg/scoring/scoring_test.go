@@ -0,0 +1,148 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package scoring
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+	"gopkg.in/yaml.v3"
+)
+
+const errorMargin float64 = 0.02
+
+func parse(t testing.TB, name string) igc.Track {
+	track, err := igc.ParseLocation("../../testdata/optimize/" + name + ".igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return track
+}
+
+func near(expected float64, result float64) bool {
+	return math.Abs(expected-result) <= math.Abs(expected)*errorMargin
+}
+
+// faiTask is a closed FAI triangle of about 300 kms.
+var faiTask = igc.Task{
+	Start: igc.NewPointFromLatLng(45, 5),
+	Turnpoints: []igc.Point{
+		igc.NewPointFromLatLng(45, 5),
+		igc.NewPointFromLatLng(45, 6.27),
+		igc.NewPointFromLatLng(45.9, 5.635),
+	},
+	Finish: igc.NewPointFromLatLng(45.005, 5),
+}
+
+// flatTask is a closed flat triangle, with one leg much shorter than the others.
+var flatTask = igc.Task{
+	Start: igc.NewPointFromLatLng(45, 5),
+	Turnpoints: []igc.Point{
+		igc.NewPointFromLatLng(45, 5),
+		igc.NewPointFromLatLng(45, 7),
+		igc.NewPointFromLatLng(45.1, 6),
+	},
+	Finish: igc.NewPointFromLatLng(45, 5),
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range Names() {
+		r, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Name() != name {
+			t.Errorf("expected %v got %v", name, r.Name())
+		}
+	}
+	if _, err := Get("unknown"); err == nil {
+		t.Errorf("expected error for unknown rules")
+	}
+}
+
+func TestScoreFunc(t *testing.T) {
+	track := parse(t, "optimize-short-flight-1")
+	track, err := track.Simplify(DefaultTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewOLCClassic()
+	task, err := igc.NewBruteForceOptimizer(false).Optimize(track, 2, ScoreFunc(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := r.Score(task).Points; points != ScoreFunc(r)(task) || points == 0 {
+		t.Errorf("expected %v got %v", points, ScoreFunc(r)(task))
+	}
+}
+
+func TestHandicap(t *testing.T) {
+	base := NewNetcoupe().Score(faiTask)
+	result := NewHandicap(NewNetcoupe(), 125).Score(faiTask)
+	if result.Handicap != 125 {
+		t.Errorf("expected handicap 125 got %v", result.Handicap)
+	}
+	if !near(base.Points*0.8, result.Points) {
+		t.Errorf("expected %v got %v", base.Points*0.8, result.Points)
+	}
+	for i := range result.Legs {
+		if !near(base.Legs[i].Points*0.8, result.Legs[i].Points) {
+			t.Errorf("leg %v :: expected %v got %v", i, base.Legs[i].Points*0.8, result.Legs[i].Points)
+		}
+	}
+	if base.Legs[0].Points == result.Legs[0].Points {
+		t.Errorf("expected handicap not to change the original result")
+	}
+
+	plus, err := NewHandicap(NewOLCPlus(), 50).Optimize(parse(t, "optimize-short-flight-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0.0
+	for _, p := range plus.Parts {
+		sum += p.Points
+	}
+	if !near(plus.Points, sum) {
+		t.Errorf("expected parts to sum %v got %v", plus.Points, sum)
+	}
+}
+
+func TestResultEncode(t *testing.T) {
+	r := NewOLCClassic().Score(faiTask)
+	result, err := r.Encode("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Points != r.Points || len(decoded.Legs) != len(r.Legs) {
+		t.Errorf("expected %v got %v", r, decoded)
+	}
+	result, err = r.Encode("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(result, &map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Encode("unknown"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,449 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package waypoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// Style is the type of a waypoint, as defined in the CUP format.
+type Style int
+
+// Waypoint styles defined in the CUP format.
+const (
+	Unknown       Style = 0
+	Normal        Style = 1
+	AirfieldGrass Style = 2
+	Outlanding    Style = 3
+	GlidingField  Style = 4
+	AirfieldSolid Style = 5
+	MountainPass  Style = 6
+	MountainTop   Style = 7
+	Transmitter   Style = 8
+	VOR           Style = 9
+	NDB           Style = 10
+	CoolingTower  Style = 11
+	Dam           Style = 12
+	Tunnel        Style = 13
+	Bridge        Style = 14
+	PowerPlant    Style = 15
+	Castle        Style = 16
+	Intersection  Style = 17
+)
+
+// IsLandable returns true for airfields and outlanding fields.
+func (s Style) IsLandable() bool {
+	return s >= AirfieldGrass && s <= AirfieldSolid
+}
+
+// tasksMarker separates the waypoints from the tasks in a CUP file.
+const tasksMarker = "-----Related Tasks-----"
+
+// cupHeader is the header line written to CUP files.
+var cupHeader = []string{"name", "code", "country", "lat", "lon", "elev",
+	"style", "rwdir", "rwlen", "freq", "desc"}
+
+// Waypoint holds a single waypoint entry.
+//
+// Lat and Lng are in decimal degrees, Elevation and RunwayLength in meters
+// and RunwayDirection in degrees (-1 if not set).
+type Waypoint struct {
+	Name            string
+	Code            string
+	Country         string
+	Lat             float64
+	Lng             float64
+	Elevation       float64
+	Style           Style
+	RunwayDirection int
+	RunwayLength    float64
+	Frequency       string
+	Description     string
+}
+
+// Point returns the igc.Point for the waypoint.
+//
+// The Description of the point is the waypoint name and the GNSSAltitude
+// its elevation.
+func (w *Waypoint) Point() igc.Point {
+	p := igc.NewPointFromLatLng(w.Lat, w.Lng)
+	p.Description = w.Name
+	p.GNSSAltitude = int64(math.Round(w.Elevation))
+	return p
+}
+
+// ObsZone holds the observation zone of a task point, as in the CUP format.
+//
+// Radiuses (R1, R2) are in meters and angles (A1, A2, A12) in degrees, with
+// A1 and A2 being half the sector angle.
+type ObsZone struct {
+	Index int
+	Style int
+	R1    float64
+	A1    float64
+	R2    float64
+	A2    float64
+	A12   float64
+	Line  bool
+}
+
+// Task holds a task from the related tasks section.
+//
+// Waypoints holds the names of all task points, including the takeoff as
+// the first and the landing as the last. Options has the task options
+// (like NoStart or TaskTime) as key values.
+type Task struct {
+	Name      string
+	Waypoints []string
+	Options   map[string]string
+	Zones     []ObsZone
+}
+
+// File holds all waypoints and tasks in a CUP file.
+type File struct {
+	Waypoints []Waypoint
+	Tasks     []Task
+}
+
+// Find returns the waypoint with the given name.
+func (f *File) Find(name string) (Waypoint, bool) {
+	for _, w := range f.Waypoints {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Waypoint{}, false
+}
+
+// ParseLocation returns a File with the contents of the given location.
+func ParseLocation(location string) (File, error) {
+	r, err := os.Open(location)
+	if err != nil {
+		return File{}, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// Parse returns a File with the contents of the given reader in CUP format.
+func Parse(r io.Reader) (File, error) {
+	var f File
+	var columns map[string]int
+	tasks := false
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, tasksMarker) {
+			tasks = true
+			continue
+		}
+		record, err := splitRecord(line)
+		if err != nil {
+			return f, fmt.Errorf("line %d :: %v", number, err)
+		}
+		switch {
+		case tasks:
+			err = f.parseTaskLine(record)
+		case columns == nil:
+			columns = headerColumns(record)
+		default:
+			var w Waypoint
+			w, err = parseWaypoint(record, columns)
+			f.Waypoints = append(f.Waypoints, w)
+		}
+		if err != nil {
+			return f, fmt.Errorf("line %d :: %v", number, err)
+		}
+	}
+	return f, scanner.Err()
+}
+
+func splitRecord(line string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.Read()
+}
+
+func headerColumns(record []string) map[string]int {
+	columns := make(map[string]int)
+	for i, c := range record {
+		columns[strings.ToLower(strings.TrimSpace(c))] = i
+	}
+	return columns
+}
+
+func parseWaypoint(record []string, columns map[string]int) (Waypoint, error) {
+	get := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	w := Waypoint{Name: get("name"), Code: get("code"), Country: get("country"),
+		Frequency: get("freq"), Description: get("desc"), RunwayDirection: -1}
+
+	var err error
+	if w.Lat, err = parseCoordinate(get("lat"), 2, "NS"); err != nil {
+		return w, fmt.Errorf("invalid latitude :: %v", err)
+	}
+	if w.Lng, err = parseCoordinate(get("lon"), 3, "EW"); err != nil {
+		return w, fmt.Errorf("invalid longitude :: %v", err)
+	}
+	if w.Elevation, err = parseDistance(get("elev")); err != nil {
+		return w, fmt.Errorf("invalid elevation :: %v", err)
+	}
+	if s := get("style"); s != "" {
+		style, err := strconv.Atoi(s)
+		if err != nil {
+			return w, fmt.Errorf("invalid style :: %v", err)
+		}
+		w.Style = Style(style)
+	}
+	if s := get("rwdir"); s != "" {
+		if w.RunwayDirection, err = strconv.Atoi(s); err != nil {
+			return w, fmt.Errorf("invalid runway direction :: %v", err)
+		}
+	}
+	if w.RunwayLength, err = parseDistance(get("rwlen")); err != nil {
+		return w, fmt.Errorf("invalid runway length :: %v", err)
+	}
+	return w, nil
+}
+
+// parseCoordinate parses a latitude (DDMM.mmmN) or longitude (DDDMM.mmmE).
+func parseCoordinate(value string, digits int, hemispheres string) (float64, error) {
+	if len(value) < digits+2 {
+		return 0, fmt.Errorf("too short '%v'", value)
+	}
+	h := value[len(value)-1]
+	if h != hemispheres[0] && h != hemispheres[1] {
+		return 0, fmt.Errorf("invalid hemisphere '%v'", value)
+	}
+	d, err := strconv.Atoi(value[:digits])
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.ParseFloat(value[digits:len(value)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	result := float64(d) + m/60
+	if h == hemispheres[1] {
+		result = -result
+	}
+	return result, nil
+}
+
+// units holds the conversion to meters of the supported distance units.
+var units = []struct {
+	suffix string
+	factor float64
+}{
+	{"km", 1000}, {"nm", 1852}, {"ml", 1609.344}, {"ft", 0.3048}, {"m", 1},
+}
+
+// parseDistance returns the value in meters of the given distance.
+//
+// Supported units are m, km, ft, nm and ml, with meters being the default.
+func parseDistance(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	factor := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value = strings.TrimSuffix(value, u.suffix)
+			factor = u.factor
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * factor, nil
+}
+
+func (f *File) parseTaskLine(record []string) error {
+	key := strings.SplitN(record[0], "=", 2)[0]
+	switch key {
+	case "Options":
+		if len(f.Tasks) == 0 {
+			return fmt.Errorf("options before any task")
+		}
+		t := &f.Tasks[len(f.Tasks)-1]
+		for _, o := range record[1:] {
+			kv := strings.SplitN(o, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid option '%v'", o)
+			}
+			t.Options[kv[0]] = kv[1]
+		}
+	case "ObsZone":
+		if len(f.Tasks) == 0 {
+			return fmt.Errorf("observation zone before any task")
+		}
+		z, err := parseObsZone(record)
+		if err != nil {
+			return err
+		}
+		t := &f.Tasks[len(f.Tasks)-1]
+		t.Zones = append(t.Zones, z)
+	case "Point", "STARTS":
+		// inline waypoints and multiple starts are not supported
+	default:
+		f.Tasks = append(f.Tasks, Task{Name: record[0], Waypoints: record[1:],
+			Options: make(map[string]string)})
+	}
+	return nil
+}
+
+func parseObsZone(record []string) (ObsZone, error) {
+	z := ObsZone{}
+	for _, v := range record {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return z, fmt.Errorf("invalid observation zone field '%v'", v)
+		}
+		var err error
+		switch kv[0] {
+		case "ObsZone":
+			z.Index, err = strconv.Atoi(kv[1])
+		case "Style":
+			z.Style, err = strconv.Atoi(kv[1])
+		case "R1":
+			z.R1, err = parseDistance(kv[1])
+		case "A1":
+			z.A1, err = strconv.ParseFloat(kv[1], 64)
+		case "R2":
+			z.R2, err = parseDistance(kv[1])
+		case "A2":
+			z.A2, err = strconv.ParseFloat(kv[1], 64)
+		case "A12":
+			z.A12, err = strconv.ParseFloat(kv[1], 64)
+		case "Line":
+			z.Line = kv[1] == "1"
+		}
+		if err != nil {
+			return z, fmt.Errorf("invalid observation zone field '%v' :: %v", v, err)
+		}
+	}
+	return z, nil
+}
+
+// encodeCUP returns the File in CUP format.
+func (f *File) encodeCUP() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	w.UseCRLF = true
+	if err := w.Write(cupHeader); err != nil {
+		return []byte{}, err
+	}
+	for _, wp := range f.Waypoints {
+		rwdir := ""
+		if wp.RunwayDirection >= 0 {
+			rwdir = fmt.Sprintf("%03d", wp.RunwayDirection)
+		}
+		if err := w.Write([]string{wp.Name, wp.Code, wp.Country,
+			formatCoordinate(wp.Lat, 2, "NS"), formatCoordinate(wp.Lng, 3, "EW"),
+			formatDistance(wp.Elevation), strconv.Itoa(int(wp.Style)), rwdir,
+			formatDistance(wp.RunwayLength), wp.Frequency, wp.Description}); err != nil {
+			return []byte{}, err
+		}
+	}
+	w.Flush()
+	if len(f.Tasks) == 0 {
+		return buf.Bytes(), w.Error()
+	}
+
+	buf.WriteString(tasksMarker + "\r\n")
+	for _, t := range f.Tasks {
+		if err := w.Write(append([]string{t.Name}, t.Waypoints...)); err != nil {
+			return []byte{}, err
+		}
+		w.Flush()
+		if len(t.Options) > 0 {
+			keys := make([]string, 0, len(t.Options))
+			for k := range t.Options {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			options := []string{"Options"}
+			for _, k := range keys {
+				options = append(options, k+"="+t.Options[k])
+			}
+			buf.WriteString(strings.Join(options, ",") + "\r\n")
+		}
+		for _, z := range t.Zones {
+			buf.WriteString(z.String() + "\r\n")
+		}
+	}
+	return buf.Bytes(), w.Error()
+}
+
+// String returns the observation zone as a CUP ObsZone line.
+func (z ObsZone) String() string {
+	s := fmt.Sprintf("ObsZone=%d,Style=%d,R1=%vm,A1=%v", z.Index, z.Style,
+		strconv.FormatFloat(z.R1, 'f', -1, 64), strconv.FormatFloat(z.A1, 'f', -1, 64))
+	if z.R2 != 0 {
+		s += fmt.Sprintf(",R2=%vm,A2=%v", strconv.FormatFloat(z.R2, 'f', -1, 64),
+			strconv.FormatFloat(z.A2, 'f', -1, 64))
+	}
+	if z.A12 != 0 {
+		s += fmt.Sprintf(",A12=%v", strconv.FormatFloat(z.A12, 'f', -1, 64))
+	}
+	if z.Line {
+		s += ",Line=1"
+	}
+	return s
+}
+
+func formatCoordinate(v float64, digits int, hemispheres string) string {
+	h := hemispheres[0]
+	if v < 0 {
+		h = hemispheres[1]
+		v = -v
+	}
+	thousandths := int(math.Round(v * 60000))
+	d, m := thousandths/60000, thousandths%60000
+	return fmt.Sprintf("%0*d%02d.%03d%c", digits, d, m/1000, m%1000, h)
+}
+
+func formatDistance(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + "m"
+}
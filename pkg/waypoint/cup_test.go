@@ -0,0 +1,162 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package waypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden test data")
+
+// parseAll parses the file and converts all its tasks.
+func parseAll(location string) (File, error) {
+	f, err := ParseLocation(location)
+	if err != nil {
+		return f, err
+	}
+	for _, t := range f.Tasks {
+		if _, _, err := f.Task(t); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func TestParse(t *testing.T) {
+	// testdata/waypoint file name format is testname.[1|0].cup
+	match, err := filepath.Glob("../../testdata/waypoint/waypoint-*.cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range match {
+		t.Run(in, func(t *testing.T) {
+			parts := strings.Split(in, ".")
+			ok, _ := strconv.ParseBool(parts[len(parts)-2])
+
+			result, err := parseAll(in)
+			if err != nil && !ok {
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			} else if !ok {
+				t.Fatalf("expected error parsing %v", in)
+			}
+			resultJSON, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := fmt.Sprintf("%v.golden", in)
+			// update golden if flag is passed
+			if *update {
+				if err = ioutil.WriteFile(out, resultJSON, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expectedJSON, err := ioutil.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(resultJSON) != string(expectedJSON) {
+				t.Errorf("expected\n%+v\ngot\n%+v", string(expectedJSON), string(resultJSON))
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	f, err := ParseLocation("../../testdata/waypoint/waypoint-valid.1.cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, ok := f.Find("Peyrins")
+	if !ok {
+		t.Fatal("expected waypoint Peyrins")
+	}
+	if math.Abs(w.Lat-45.0925) > 1e-6 || math.Abs(w.Lng-5.048050) > 1e-6 {
+		t.Errorf("expected 45.0925 5.04805 got %v %v", w.Lat, w.Lng)
+	}
+	if math.Abs(w.Elevation-274.32) > 1e-6 || w.RunwayLength != 1200 || w.RunwayDirection != 10 {
+		t.Errorf("expected 274.32m with runway 010 of 1200m got %v %v %v",
+			w.Elevation, w.RunwayDirection, w.RunwayLength)
+	}
+	if w.Style != AirfieldSolid || !w.Style.IsLandable() || w.Frequency != "123.500" {
+		t.Errorf("expected landable style with frequency got %v %v", w.Style, w.Frequency)
+	}
+	if len(f.Tasks) != 2 || f.Tasks[0].Options["TaskTime"] != "03:00:00" || len(f.Tasks[0].Zones) != 5 {
+		t.Errorf("expected 2 tasks with options and 5 zones got %+v", f.Tasks)
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	tests := map[string]float64{
+		"": 0, "100": 100, "100m": 100, "1.5km": 1500, "1000ft": 304.8, "1nm": 1852, "1ml": 1609.344,
+	}
+	for in, expected := range tests {
+		v, err := parseDistance(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(v-expected) > 1e-9 {
+			t.Errorf("%v :: expected %v got %v", in, expected, v)
+		}
+	}
+	if _, err := parseDistance("12xx"); err == nil {
+		t.Errorf("expected error for invalid distance")
+	}
+}
+
+func TestEncodeCUP(t *testing.T) {
+	f, err := ParseLocation("../../testdata/waypoint/waypoint-valid.1.cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := f.Encode("cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Parse(bytes.NewReader(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Waypoints) != len(f.Waypoints) {
+		t.Fatalf("expected %v waypoints got %v", len(f.Waypoints), len(decoded.Waypoints))
+	}
+	for i, w := range f.Waypoints {
+		d := decoded.Waypoints[i]
+		if math.Abs(w.Lat-d.Lat) > 1e-5 || math.Abs(w.Lng-d.Lng) > 1e-5 ||
+			math.Abs(w.Elevation-d.Elevation) > 0.1 {
+			t.Errorf("expected %+v got %+v", w, d)
+		}
+		d.Lat, d.Lng, d.Elevation = w.Lat, w.Lng, w.Elevation
+		if !reflect.DeepEqual(w, d) {
+			t.Errorf("expected %+v got %+v", w, d)
+		}
+	}
+	if !reflect.DeepEqual(f.Tasks, decoded.Tasks) {
+		t.Errorf("expected %+v got %+v", f.Tasks, decoded.Tasks)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+/*
+Package waypoint provides means to parse and write waypoint files.
+
+The supported format is the SeeYou CUP format, including the waypoint table
+and the related tasks section with observation zones:
+
+http://download.naviter.com/docs/CUP-file-format-description.pdf
+
+Tasks can be converted to an igc.Task along with the observation zones of
+each task point, ready to check flights against them.
+
+*/
+package waypoint
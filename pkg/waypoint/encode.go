@@ -0,0 +1,104 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package waypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	kml "github.com/twpayne/go-kml"
+	"gopkg.in/yaml.v3"
+)
+
+// Encode returns the File in the given format.
+//
+// Supported formats are cup, json, yaml, geojson and kml. The geojson and
+// kml formats include only the waypoints.
+func (f *File) Encode(format string) ([]byte, error) {
+	switch format {
+	case "cup":
+		return f.encodeCUP()
+	case "json":
+		return json.MarshalIndent(f, "", "  ")
+	case "yaml":
+		return yaml.Marshal(f)
+	case "geojson":
+		return f.encodeGeoJSON()
+	case "kml":
+		return f.encodeKML()
+	default:
+		return []byte{}, fmt.Errorf("unsupported format '%v'", format)
+	}
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+func (f *File) encodeGeoJSON() ([]byte, error) {
+	features := make([]geoJSONFeature, len(f.Waypoints))
+	for i, w := range f.Waypoints {
+		features[i] = geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: []float64{w.Lng, w.Lat, w.Elevation},
+			},
+			Properties: map[string]interface{}{
+				"name":        w.Name,
+				"code":        w.Code,
+				"country":     w.Country,
+				"elevation":   w.Elevation,
+				"style":       w.Style,
+				"description": w.Description,
+			},
+		}
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}, "", "  ")
+}
+
+func (f *File) encodeKML() ([]byte, error) {
+	d := kml.Document()
+	for _, w := range f.Waypoints {
+		desc := fmt.Sprintf("Code: %v<br/>Elevation: %.0fm<br/>Style: %d<br/>%v",
+			w.Code, w.Elevation, w.Style, w.Description)
+		d.Add(
+			kml.Placemark(
+				kml.Name(w.Name),
+				kml.Description(desc),
+				kml.Point(
+					kml.Coordinates(kml.Coordinate{Lon: w.Lng, Lat: w.Lat, Alt: w.Elevation}),
+				),
+			))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := kml.KML(d).WriteIndent(buf, "", "  "); err != nil {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package waypoint
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	f, err := ParseLocation("../../testdata/waypoint/waypoint-valid.1.cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := f.Encode("geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(result, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != len(f.Waypoints) {
+		t.Fatalf("expected collection with %v features got %v", len(f.Waypoints), string(result))
+	}
+	first := collection.Features[0]
+	if first.Properties["name"] != "Bouchere" || first.Geometry.Coordinates[0] != f.Waypoints[0].Lng {
+		t.Errorf("expected first waypoint got %+v", first)
+	}
+
+	result, err = f.Encode("kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(result), "<Placemark>"); n != len(f.Waypoints) {
+		t.Errorf("expected %v placemarks got %v", len(f.Waypoints), n)
+	}
+
+	if _, err := f.Encode("unknown"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
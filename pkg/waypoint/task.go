@@ -0,0 +1,87 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package waypoint
+
+import (
+	"fmt"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+// DefaultZone is the observation zone of task points without an ObsZone.
+var DefaultZone = igc.NewCylinderZone(0.5)
+
+// Zone returns the igc.Zone matching the observation zone.
+//
+// Lines have a length of twice R1, and zones with A1 of 180 degrees are
+// cylinders. A sector with a cylinder (R2 with A2 of 180) is a keyhole, any
+// other zone a sector. The zone orientation (Style) is not kept, with sectors
+// being always symmetrical.
+func (z ObsZone) Zone() igc.Zone {
+	r1, r2 := z.R1/1000, z.R2/1000
+	switch {
+	case z.Line:
+		return igc.NewLineZone(2 * r1)
+	case z.A1 >= 180:
+		return igc.NewCylinderZone(r1)
+	case r2 > 0 && z.A2 >= 180:
+		return igc.Zone{Type: igc.KeyholeZone, Radius: r1, InnerRadius: r2, Angle: 2 * z.A1}
+	default:
+		return igc.Zone{Type: igc.SectorZone, Radius: r1, Angle: 2 * z.A1}
+	}
+}
+
+// Task returns the igc.Task for the given task, along with the observation
+// zones of each of its Start, Turnpoints and Finish.
+//
+// Task points are looked up by name in the File waypoints. Points with no
+// observation zone get DefaultZone.
+func (f *File) Task(t Task) (igc.Task, []igc.Zone, error) {
+	if len(t.Waypoints) < 4 {
+		return igc.Task{}, nil, fmt.Errorf("task %v has %v points, min 4 required",
+			t.Name, len(t.Waypoints))
+	}
+	points := make([]igc.Point, len(t.Waypoints))
+	for i, name := range t.Waypoints {
+		w, ok := f.Find(name)
+		if !ok {
+			return igc.Task{}, nil, fmt.Errorf("task %v :: unknown waypoint '%v'", t.Name, name)
+		}
+		points[i] = w.Point()
+	}
+
+	last := len(points) - 1
+	task := igc.Task{
+		Description: t.Name,
+		Takeoff:     points[0],
+		Start:       points[1],
+		Turnpoints:  points[2 : last-1],
+		Finish:      points[last-1],
+		Landing:     points[last],
+	}
+	zones := make([]igc.Zone, last-1)
+	for i := range zones {
+		zones[i] = DefaultZone
+	}
+	for _, z := range t.Zones {
+		if z.Index < 0 || z.Index >= len(zones) {
+			return igc.Task{}, nil, fmt.Errorf("task %v :: invalid observation zone index %v",
+				t.Name, z.Index)
+		}
+		zones[z.Index] = z.Zone()
+	}
+	return task, zones, nil
+}
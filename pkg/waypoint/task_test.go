@@ -0,0 +1,86 @@
+// Copyright The ezgliding Authors.
+//
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package waypoint
+
+import (
+	"testing"
+
+	"github.com/ezgliding/goigc/pkg/igc"
+)
+
+func TestObsZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     ObsZone
+		expected igc.Zone
+	}{
+		{name: "line", zone: ObsZone{R1: 5000, A1: 180, Line: true}, expected: igc.NewLineZone(10)},
+		{name: "cylinder", zone: ObsZone{R1: 500, A1: 180}, expected: igc.NewCylinderZone(0.5)},
+		{name: "keyhole", zone: ObsZone{R1: 10000, A1: 45, R2: 500, A2: 180}, expected: igc.NewKeyholeZone()},
+		{name: "sector", zone: ObsZone{R1: 20000, A1: 45}, expected: igc.Zone{Type: igc.SectorZone, Radius: 20, Angle: 90}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if z := test.zone.Zone(); z != test.expected {
+				t.Errorf("expected %+v got %+v", test.expected, z)
+			}
+		})
+	}
+}
+
+func TestTask(t *testing.T) {
+	f, err := ParseLocation("../../testdata/waypoint/waypoint-valid.1.cup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, zones, err := f.Task(f.Tasks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "Alps 500" || task.Start.Description != "Bouchere" ||
+		task.Finish.Description != "Peyrins" || len(task.Turnpoints) != 3 {
+		t.Errorf("unexpected task %+v", task)
+	}
+	expected := []igc.ZoneType{igc.LineZone, igc.KeyholeZone, igc.CylinderZone, igc.SectorZone, igc.CylinderZone}
+	if len(zones) != len(expected) {
+		t.Fatalf("expected %v zones got %v", len(expected), len(zones))
+	}
+	for i, z := range zones {
+		if z.Type != expected[i] {
+			t.Errorf("zone %v :: expected %v got %v", i, expected[i], z.Type)
+		}
+	}
+
+	// task with no observation zones
+	_, zones, err = f.Task(f.Tasks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, z := range zones {
+		if z != DefaultZone {
+			t.Errorf("zone %v :: expected default zone got %+v", i, z)
+		}
+	}
+
+	if _, _, err := f.Task(Task{Name: "short", Waypoints: []string{"Vinon", "Vinon", "Vinon"}}); err == nil {
+		t.Errorf("expected error for task too short")
+	}
+	broken := f.Tasks[0]
+	broken.Zones = []ObsZone{{Index: 7}}
+	if _, _, err := f.Task(broken); err == nil {
+		t.Errorf("expected error for invalid zone index")
+	}
+}